@@ -0,0 +1,113 @@
+package glog
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// recurredKey is the attribute carrying how many records an alert cooldown suppressed.
+const recurredKey = "recurred"
+
+// alertEntry is an alert in its cooldown window.
+type alertEntry struct {
+	first *bufferedRecord // the record that started the cooldown
+	seq   uint64          // orders summaries written by flush
+	count int             // identical records suppressed so far
+	timer *time.Timer
+}
+
+// alertState throttles identical records at or above a level: the first one is written at once,
+// identical ones (same level and message) are suppressed for the cooldown, and when it ends a copy of
+// the first record with a recurred=N attribute is written if any were suppressed.
+type alertState struct {
+	mu         sync.Mutex
+	level      slog.Leveler
+	cooldown   time.Duration
+	entries    map[dedupKey]*alertEntry
+	seq        uint64
+	suppressed *atomic.Int64 // counts suppressed records
+}
+
+func newAlertState(level slog.Leveler, cooldown time.Duration, suppressed *atomic.Int64) *alertState {
+	return &alertState{
+		level:      level,
+		cooldown:   cooldown,
+		entries:    make(map[dedupKey]*alertEntry),
+		suppressed: suppressed,
+	}
+}
+
+// applies reports whether records at level are throttled.
+func (a *alertState) applies(level slog.Level) bool {
+	return level >= a.level.Level()
+}
+
+// allow reports whether r should be written, starting a cooldown for it if so; otherwise it
+// counts r as suppressed.
+func (a *alertState) allow(ctx context.Context, handler slog.Handler, r slog.Record) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key := dedupKey{level: r.Level, message: r.Message}
+	if e, ok := a.entries[key]; ok {
+		e.count++
+		a.suppressed.Add(1)
+		return false
+	}
+
+	a.seq++
+	e := &alertEntry{first: &bufferedRecord{handler: handler, ctx: ctx, record: r.Clone()}, seq: a.seq}
+	e.timer = time.AfterFunc(a.cooldown, func() { a.expire(key, e) })
+	a.entries[key] = e
+	return true
+}
+
+// expire ends the cooldown of e, writing its summary.
+func (a *alertState) expire(key dedupKey, e *alertEntry) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.entries[key] != e {
+		return
+	}
+	delete(a.entries, key)
+	_ = e.summarize() // no caller to report to
+}
+
+// flush ends every cooldown now, writing the summaries in the order the cooldowns started.
+func (a *alertState) flush() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	entries := make([]*alertEntry, 0, len(a.entries))
+	for key, e := range a.entries {
+		e.timer.Stop()
+		entries = append(entries, e)
+		delete(a.entries, key)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].seq < entries[j].seq })
+
+	var errs []error
+	for _, e := range entries {
+		if err := e.summarize(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// summarize writes the first record again with the suppressed count, if any were suppressed.
+func (e *alertEntry) summarize() error {
+	if e.count == 0 {
+		return nil
+	}
+	r := e.first.record.Clone()
+	r.Time = time.Now()
+	r.AddAttrs(slog.Int(recurredKey, e.count))
+	return e.first.handler.Handle(e.first.ctx, r)
+}
@@ -0,0 +1,81 @@
+package glog
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandler_AlertCooldown(t *testing.T) {
+	var buf syncBuffer
+
+	cooldown := 100 * time.Millisecond
+	handler := NewHandler(&Options{
+		Writer:        &buf,
+		Format:        FormatLine,
+		Level:         slog.LevelInfo,
+		AlertCooldown: cooldown,
+	})
+	logger := slog.New(handler)
+
+	for range 5 {
+		logger.Error("db unreachable", slog.String("host", "db1"))
+	}
+	logger.Error("cache unreachable")
+	for range 2 {
+		logger.Warn("slow query") // below AlertLevel, never suppressed
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected the first alert, the other alert and both warnings, got %d lines:\n%s", len(lines), buf.String())
+	}
+	if !strings.Contains(lines[0], "ERROR: db unreachable") || strings.Contains(lines[0], recurredKey) {
+		t.Errorf("expected the first occurrence written at once, got: %s", lines[0])
+	}
+
+	time.Sleep(3 * cooldown)
+	lines = strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("expected one summary after the cooldown, got %d lines:\n%s", len(lines), buf.String())
+	}
+	if !strings.Contains(lines[4], "ERROR: db unreachable") || !strings.Contains(lines[4], `"recurred":4`) ||
+		!strings.Contains(lines[4], `"host":"db1"`) {
+		t.Errorf("expected a summary of 4 suppressed records, got: %s", lines[4])
+	}
+
+	// the cooldown has ended, so the next occurrence is written at once again
+	logger.Error("db unreachable")
+	if err := handler.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	lines = strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 6 || strings.Contains(lines[5], recurredKey) {
+		t.Errorf("expected a new occurrence and no summary on Close, got:\n%s", buf.String())
+	}
+}
+
+func TestHandler_AlertCooldownClose(t *testing.T) {
+	var buf syncBuffer
+
+	handler := NewHandler(&Options{
+		Writer:        &buf,
+		Format:        FormatLine,
+		Level:         slog.LevelInfo,
+		AlertCooldown: time.Hour,
+		AlertLevel:    slog.LevelWarn,
+	})
+	logger := slog.New(handler)
+	for range 3 {
+		logger.Warn("disk almost full")
+	}
+	if err := handler.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], `"recurred":2`) {
+		t.Errorf("expected the pending summary written on Close, got:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,65 @@
+package glog
+
+import "log/slog"
+
+// levelGated is an attribute value kept only on records at or below level.
+type levelGated struct {
+	level slog.Level
+	value slog.Value
+}
+
+// LogValue returns the wrapped value; handlers other than Handler log it unconditionally.
+func (g levelGated) LogValue() slog.Value {
+	return g.value
+}
+
+// AtLevel marks a as verbose-only: Handler keeps it on records at or below level and drops it from
+// more severe ones, so heavy context can be attached cheaply at every call site:
+//
+//	logger.Info("request", glog.AtLevel(slog.LevelDebug, slog.String("body", body)))
+//
+// writes the body only when the same call is made at debug level. The check applies to attributes
+// passed with the record; attributes added with Logger.With are always kept.
+func AtLevel(level slog.Level, a slog.Attr) slog.Attr {
+	return slog.Any(a.Key, levelGated{level: level, value: a.Value})
+}
+
+// gateAttrs returns r with AtLevel attributes resolved for its level: unwrapped when kept, removed
+// otherwise. r is returned as is when it has none.
+func gateAttrs(r slog.Record) slog.Record {
+	if r.NumAttrs() == 0 {
+		return r
+	}
+	gated := false
+	r.Attrs(func(a slog.Attr) bool {
+		_, gated = levelGatedValue(a.Value)
+		return !gated
+	})
+	if !gated {
+		return r
+	}
+
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		if g, ok := levelGatedValue(a.Value); ok {
+			if r.Level > g.level {
+				return true
+			}
+			a.Value = g.value
+		}
+		attrs = append(attrs, a)
+		return true
+	})
+	out := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	out.AddAttrs(attrs...)
+	return out
+}
+
+// levelGatedValue reports whether v was made by AtLevel.
+func levelGatedValue(v slog.Value) (levelGated, bool) {
+	if v.Kind() != slog.KindLogValuer {
+		return levelGated{}, false
+	}
+	g, ok := v.LogValuer().(levelGated)
+	return g, ok
+}
@@ -0,0 +1,39 @@
+package glog
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestAtLevel(t *testing.T) {
+	var buf bytes.Buffer
+
+	handler := NewHandler(&Options{Writer: &buf, Format: FormatJSON, Level: slog.LevelDebug})
+	logger := slog.New(handler)
+	body := AtLevel(slog.LevelDebug, slog.String("body", "{...}"))
+
+	logger.Debug("request", body, slog.Int("status", 200))
+	logger.Info("request", body, slog.Int("status", 200))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d: %s", len(lines), buf.String())
+	}
+	if !strings.Contains(lines[0], `"body":"{...}","status":200`) {
+		t.Errorf("expected the attribute kept at debug, got: %s", lines[0])
+	}
+	if strings.Contains(lines[1], "body") || !strings.Contains(lines[1], `"status":200`) {
+		t.Errorf("expected only the gated attribute dropped at info, got: %s", lines[1])
+	}
+}
+
+func TestAtLevel_OtherHandlers(t *testing.T) {
+	var buf bytes.Buffer
+
+	slog.New(slog.NewJSONHandler(&buf, nil)).Info("request", AtLevel(slog.LevelDebug, slog.String("body", "{...}")))
+	if !strings.Contains(buf.String(), `"body":"{...}"`) {
+		t.Errorf("expected other handlers to log the plain value, got: %s", buf.String())
+	}
+}
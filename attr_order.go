@@ -0,0 +1,42 @@
+package glog
+
+import (
+	"log/slog"
+	"sort"
+)
+
+// attrRank maps each key of order to its position; the first occurrence of a duplicate wins.
+func attrRank(order []string) map[string]int {
+	rank := make(map[string]int, len(order))
+	for i, key := range order {
+		if _, ok := rank[key]; !ok {
+			rank[key] = i
+		}
+	}
+	return rank
+}
+
+// orderAttrs returns a copy of r whose attributes are rebuilt so that keys in rank come first,
+// by rank, followed by all other attributes in their original order.
+func orderAttrs(r slog.Record, rank map[string]int) slog.Record {
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+
+	last := len(rank)
+	position := func(a slog.Attr) int {
+		if i, ok := rank[a.Key]; ok {
+			return i
+		}
+		return last
+	}
+	sort.SliceStable(attrs, func(i, j int) bool {
+		return position(attrs[i]) < position(attrs[j])
+	})
+
+	out := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	out.AddAttrs(attrs...)
+	return out
+}
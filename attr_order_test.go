@@ -0,0 +1,58 @@
+package glog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandler_AttrOrder(t *testing.T) {
+	var buf bytes.Buffer
+
+	handler := NewHandler(&Options{
+		Writer:         &buf,
+		Format:         FormatJSON,
+		Level:          slog.LevelInfo,
+		TraceExtractor: DefaultTraceExtractor,
+		AttrOrder:      []string{"trace_id", "span_id", "user"},
+	})
+	defer handler.Close()
+	logger := slog.New(handler)
+
+	ctx := context.WithValue(context.Background(), "trace_id", "t1")
+	ctx = context.WithValue(ctx, "span_id", "s1")
+
+	logger.InfoContext(ctx, "first", slog.String("user", "u1"), slog.Int("n", 1))
+	logger.InfoContext(ctx, "second", slog.Int("n", 2), slog.String("user", "u2"))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d: %s", len(lines), buf.String())
+	}
+	wants := []string{
+		`"msg":"first","trace_id":"t1","span_id":"s1","user":"u1","n":1}`,
+		`"msg":"second","trace_id":"t1","span_id":"s1","user":"u2","n":2}`,
+	}
+	for i, want := range wants {
+		if !strings.HasSuffix(lines[i], want) {
+			t.Errorf("line %d: expected suffix %s, got: %s", i, want, lines[i])
+		}
+	}
+}
+
+func TestOrderAttrs_KeepsUnlistedOrder(t *testing.T) {
+	r := slog.NewRecord(time.Time{}, slog.LevelInfo, "msg", 0)
+	r.AddAttrs(slog.Int("c", 3), slog.Int("a", 1), slog.Int("first", 0), slog.Int("b", 2))
+
+	var keys []string
+	orderAttrs(r, attrRank([]string{"first"})).Attrs(func(a slog.Attr) bool {
+		keys = append(keys, a.Key)
+		return true
+	})
+	if got := strings.Join(keys, ","); got != "first,c,a,b" {
+		t.Errorf("expected first,c,a,b, got %s", got)
+	}
+}
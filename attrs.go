@@ -0,0 +1,17 @@
+package glog
+
+import "log/slog"
+
+// Attrs returns a group attribute named name holding attrs, for attributes built at run time:
+//
+//	meta := []slog.Attr{slog.String("region", region)}
+//	if user != "" {
+//		meta = append(meta, slog.String("user", user))
+//	}
+//	logger.Info("request", glog.Attrs("meta", meta...))
+//
+// JSON and text output nest the attributes under name; FormatLine writes them as "meta.region", ...
+// An empty name inlines them, and an empty slice adds nothing.
+func Attrs(name string, attrs ...slog.Attr) slog.Attr {
+	return slog.Attr{Key: name, Value: slog.GroupValue(attrs...)}
+}
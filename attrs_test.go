@@ -0,0 +1,33 @@
+package glog
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestAttrs(t *testing.T) {
+	var meta []slog.Attr
+	for i := 0; i < 3; i++ {
+		meta = append(meta, slog.Int(fmt.Sprintf("k%d", i), i))
+	}
+
+	for format, want := range map[FormatType]string{
+		FormatJSON: `"meta":{"k0":0,"k1":1,"k2":2}`,
+		FormatLine: `{"meta.k0":0,"meta.k1":1,"meta.k2":2}`,
+	} {
+		var buf bytes.Buffer
+		handler := NewHandler(&Options{Writer: &buf, Format: format, Level: slog.LevelInfo})
+		slog.New(handler).Info("dynamic", Attrs("meta", meta...), Attrs("empty"))
+
+		out := buf.String()
+		if !strings.Contains(out, want) {
+			t.Errorf("format %d: expected %s, got: %s", format, want, out)
+		}
+		if strings.Contains(out, "empty") {
+			t.Errorf("format %d: expected an empty group to add nothing, got: %s", format, out)
+		}
+	}
+}
@@ -1,9 +1,15 @@
 package glog
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -251,3 +257,169 @@ func BenchmarkZap_FileJSON(b *testing.B) {
 		}
 	})
 }
+
+// BenchmarkGlog_KeyedAttrs benchmarks ordinary key/value logging as a baseline for BenchmarkGlog_Schema.
+func BenchmarkGlog_KeyedAttrs(b *testing.B) {
+	handler := NewHandler(&Options{Writer: io.Discard, Format: FormatJSON})
+	defer handler.Close()
+	logger := slog.New(handler)
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.InfoContext(ctx, benchmarkMessage,
+			"iteration", i,
+			"key1", "value1",
+			"key2", "value2",
+			"key3", 123,
+			"key4", true,
+		)
+	}
+}
+
+// BenchmarkGlog_Schema benchmarks positional logging against a pre-registered schema.
+func BenchmarkGlog_Schema(b *testing.B) {
+	handler := NewHandler(&Options{Writer: io.Discard, Format: FormatJSON})
+	defer handler.Close()
+	schema, err := NewSchema(
+		SchemaField{Key: "iteration", Kind: slog.KindInt64},
+		SchemaField{Key: "key1", Kind: slog.KindString},
+		SchemaField{Key: "key2", Kind: slog.KindString},
+		SchemaField{Key: "key3", Kind: slog.KindInt64},
+		SchemaField{Key: "key4", Kind: slog.KindBool},
+	)
+	if err != nil {
+		b.Fatal(err)
+	}
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = handler.LogSchema(ctx, schema, slog.LevelInfo, benchmarkMessage, i, "value1", "value2", 123, true)
+	}
+}
+
+// BenchmarkGlog_JSONTrace benchmarks the JSON path with trace injection on a record whose
+// inline attribute slots are already full, so the injected fields overflow them.
+func BenchmarkGlog_JSONTrace(b *testing.B) {
+	handler := NewHandler(&Options{
+		Writer:          io.Discard,
+		Format:          FormatJSON,
+		TraceExtractor:  DefaultTraceExtractor,
+		IncludeRawLevel: true,
+	})
+	defer handler.Close()
+	logger := slog.New(handler)
+	ctx := SetSpanID(SetTraceID(context.Background(), "4bf92f3577b34da6a3ce929d0e0e4736"), "00f067aa0ba902b7")
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.InfoContext(ctx, benchmarkMessage,
+			"iteration", i,
+			"key1", "value1",
+			"key2", "value2",
+			"key3", 123,
+			"key4", true,
+		)
+	}
+}
+
+// BenchmarkFileWriter_ConcurrentLatency benchmarks concurrent FileWriter writes and reports the
+// 99th-percentile latency of a single Write next to the throughput, for several MaxBatchWrites caps.
+func BenchmarkFileWriter_ConcurrentLatency(b *testing.B) {
+	for _, tc := range []struct{ flush, maxBatch int }{
+		{0, 1}, {0, DefaultMaxBatchWrites}, {0, 256},
+		{1, 1}, {1, DefaultMaxBatchWrites}, {1, 256},
+	} {
+		b.Run(fmt.Sprintf("flush=%ds/batch=%d", tc.flush, tc.maxBatch), func(b *testing.B) {
+			fw := NewFileWriterWithOptions(filepath.Join(b.TempDir(), "latency-2006-01-02-15.log"), FileWriterOptions{
+				FlushInterval:  tc.flush,
+				MaxBatchWrites: tc.maxBatch,
+			})
+			defer fw.Close()
+			line := []byte(benchmarkMessage + "\n")
+
+			var mu sync.Mutex
+			latencies := make([]time.Duration, 0, b.N)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				local := make([]time.Duration, 0, 1024)
+				for pb.Next() {
+					start := time.Now()
+					if _, err := fw.Write(line); err != nil {
+						b.Error(err)
+						return
+					}
+					local = append(local, time.Since(start))
+				}
+				mu.Lock()
+				latencies = append(latencies, local...)
+				mu.Unlock()
+			})
+			b.StopTimer()
+
+			if len(latencies) > 0 {
+				slices.Sort(latencies)
+				b.ReportMetric(float64(latencies[len(latencies)*99/100].Nanoseconds()), "p99-ns/write")
+			}
+		})
+	}
+}
+
+// BenchmarkGlog_SampledEnrichment benchmarks a Sampler keeping 1 in 100 records in front of an
+// AttrExtractor, with the sampling decision before and after enrichment.
+func BenchmarkGlog_SampledEnrichment(b *testing.B) {
+	for _, after := range []bool{false, true} {
+		b.Run(fmt.Sprintf("after=%t", after), func(b *testing.B) {
+			var n, enriched atomic.Int64
+			handler := NewHandler(&Options{
+				Writer: io.Discard,
+				Format: FormatJSON,
+				Level:  slog.LevelInfo,
+				AttrExtractor: func(context.Context) []slog.Attr {
+					enriched.Add(1)
+					return []slog.Attr{slog.String("tenant", "acme"), slog.String("region", "eu")}
+				},
+				Sampler:               func(context.Context, slog.Record) bool { return n.Add(1)%100 == 0 },
+				SampleAfterEnrichment: after,
+			})
+			logger := slog.New(handler)
+			ctx := context.Background()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				logger.InfoContext(ctx, benchmarkMessage, "iteration", i)
+			}
+			b.StopTimer()
+			b.ReportMetric(float64(enriched.Load())/float64(b.N), "enrichments/op")
+		})
+	}
+}
+
+// BenchmarkFileWriter_SizeCheck benchmarks buffered FileWriter writes with MaxSize checked on every
+// write and by the background loop.
+func BenchmarkFileWriter_SizeCheck(b *testing.B) {
+	for _, background := range []bool{false, true} {
+		b.Run(fmt.Sprintf("background=%t", background), func(b *testing.B) {
+			opts := FileWriterOptions{FlushInterval: 1, MaxSize: 64 << 20}
+			if background {
+				opts.SizeCheckInterval = time.Second
+			}
+			fw := NewFileWriterWithOptions(filepath.Join(b.TempDir(), "size.log"), opts)
+			defer fw.Close()
+			line := []byte(benchmarkMessage + "\n")
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := fw.Write(line); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
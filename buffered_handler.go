@@ -0,0 +1,231 @@
+package glog
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	defaultBufferedMaxRecords    = 100
+	defaultBufferedFlushInterval = time.Second
+)
+
+// BufferedOptions configures a BufferedHandler.
+type BufferedOptions struct {
+	// MaxRecords flushes the buffer once it holds this many records; 0 means no count threshold.
+	MaxRecords int
+	// FlushInterval flushes the buffer periodically; 0 means no time threshold.
+	FlushInterval time.Duration
+}
+
+// bufferedRecord is a record held by a BufferedHandler together with the handler and context it was logged with.
+type bufferedRecord struct {
+	handler slog.Handler
+	ctx     context.Context
+	record  slog.Record
+}
+
+// recordBuffer is the state shared by a BufferedHandler and the handlers derived from it.
+type recordBuffer struct {
+	mu         sync.Mutex
+	flushMu    sync.Mutex // serializes flushes so batches are delivered in order
+	records    []bufferedRecord
+	maxRecords int
+	closing    bool // Close has started; records are still queued until it has flushed them all
+	closed     bool // Close has flushed every record
+	closer     io.Closer
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// BufferedHandler wraps a slog.Handler and delivers records to it in batches: when MaxRecords
+// records are pending, every FlushInterval, on Flush, or on Close, whichever comes first.
+// It is safe for concurrent use; handlers derived via WithAttrs/WithGroup share the same buffer.
+//
+// It buffers records, not encoded bytes: the wrapped handler enriches and encodes each record only
+// when it is flushed, on the flushing goroutine, with the context the record was logged with. Context
+// values (trace IDs, AttrExtractor fields) are therefore those of the logging call, but the context
+// may have been cancelled by then, and time-dependent enrichment such as IncludeDeadline reflects the
+// flush. The record's own time is kept. To batch encoded output instead, buffer at the writer (e.g.
+// FileWriterOptions.FlushInterval).
+type BufferedHandler struct {
+	handler slog.Handler
+	buf     *recordBuffer
+}
+
+// NewBufferedHandler creates a BufferedHandler around h. If opts is nil, it flushes every
+// 100 records or every second.
+func NewBufferedHandler(h slog.Handler, opts *BufferedOptions) *BufferedHandler {
+	if opts == nil {
+		opts = &BufferedOptions{
+			MaxRecords:    defaultBufferedMaxRecords,
+			FlushInterval: defaultBufferedFlushInterval,
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	buf := &recordBuffer{
+		maxRecords: opts.MaxRecords,
+		cancel:     cancel,
+		done:       make(chan struct{}),
+	}
+	if closer, ok := h.(io.Closer); ok {
+		buf.closer = closer
+	}
+
+	go buf.flushLoop(ctx, opts.FlushInterval)
+
+	return &BufferedHandler{
+		handler: h,
+		buf:     buf,
+	}
+}
+
+// Enabled reports whether the wrapped handler is enabled for the given level.
+func (h *BufferedHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+// Handle queues the record; it is written to the wrapped handler on the next flush. Records
+// logged while Close is flushing are queued too, and written by Close in order. After Close,
+// records are passed straight through, or dropped if Close closed the wrapped handler.
+func (h *BufferedHandler) Handle(ctx context.Context, r slog.Record) error {
+	b := h.buf
+
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		if b.closer != nil {
+			return nil
+		}
+		return h.handler.Handle(ctx, r)
+	}
+	b.records = append(b.records, bufferedRecord{
+		handler: h.handler,
+		ctx:     ctx,
+		record:  r.Clone(),
+	})
+	full := b.maxRecords > 0 && len(b.records) >= b.maxRecords
+	b.mu.Unlock()
+
+	if full {
+		return b.flush()
+	}
+	return nil
+}
+
+// WithAttrs returns a new BufferedHandler sharing this handler's buffer.
+func (h *BufferedHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &BufferedHandler{
+		handler: h.handler.WithAttrs(attrs),
+		buf:     h.buf,
+	}
+}
+
+// WithGroup returns a new BufferedHandler sharing this handler's buffer.
+func (h *BufferedHandler) WithGroup(name string) slog.Handler {
+	return &BufferedHandler{
+		handler: h.handler.WithGroup(name),
+		buf:     h.buf,
+	}
+}
+
+// Flush writes all pending records to the wrapped handler.
+func (h *BufferedHandler) Flush() error {
+	return h.buf.flush()
+}
+
+// Close stops the flush loop, writes all pending records, and closes the wrapped handler
+// if it implements io.Closer.
+func (h *BufferedHandler) Close() error {
+	b := h.buf
+
+	b.mu.Lock()
+	if b.closing {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closing = true
+	b.mu.Unlock()
+
+	b.cancel()
+	<-b.done
+
+	err := b.drain()
+	if b.closer != nil {
+		err = errors.Join(err, b.closer.Close())
+	}
+	return err
+}
+
+// flushLoop flushes the buffer every interval until ctx is cancelled.
+func (b *recordBuffer) flushLoop(ctx context.Context, interval time.Duration) {
+	defer close(b.done)
+
+	if interval <= 0 {
+		<-ctx.Done()
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = b.flush() // errors are dropped; call Flush to observe them
+		}
+	}
+}
+
+// drain flushes until no record is pending and marks the buffer closed under the same lock, so a
+// record logged meanwhile is either flushed here, in order, or handled as logged after Close.
+func (b *recordBuffer) drain() error {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+
+	var errs []error
+	for {
+		b.mu.Lock()
+		records := b.records
+		b.records = nil
+		if len(records) == 0 {
+			b.closed = true
+			b.mu.Unlock()
+			return errors.Join(errs...)
+		}
+		b.mu.Unlock()
+
+		for _, br := range records {
+			if err := br.handler.Handle(br.ctx, br.record); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+}
+
+// flush hands all pending records to their handlers in the order they were logged.
+func (b *recordBuffer) flush() error {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+
+	b.mu.Lock()
+	records := b.records
+	b.records = nil
+	b.mu.Unlock()
+
+	var errs []error
+	for _, br := range records {
+		if err := br.handler.Handle(br.ctx, br.record); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
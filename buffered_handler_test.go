@@ -0,0 +1,247 @@
+package glog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent use by a background flusher and the test.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func (b *syncBuffer) lines() []string {
+	out := strings.TrimSpace(b.String())
+	if out == "" {
+		return nil
+	}
+	return strings.Split(out, "\n")
+}
+
+func TestBufferedHandler_FlushByCount(t *testing.T) {
+	var buf syncBuffer
+
+	h := NewBufferedHandler(NewLineHandler(&buf, nil), &BufferedOptions{MaxRecords: 3})
+	defer h.Close()
+	logger := slog.New(h)
+
+	logger.Info("one")
+	logger.Info("two")
+	if got := buf.lines(); len(got) != 0 {
+		t.Fatalf("expected no output before threshold, got %v", got)
+	}
+
+	logger.Info("three")
+	got := buf.lines()
+	if len(got) != 3 {
+		t.Fatalf("expected 3 lines after threshold, got %d: %v", len(got), got)
+	}
+	for i, msg := range []string{"one", "two", "three"} {
+		if !strings.Contains(got[i], "INFO: "+msg) {
+			t.Errorf("line %d: expected %q, got %q", i, msg, got[i])
+		}
+	}
+}
+
+func TestBufferedHandler_FlushByTime(t *testing.T) {
+	var buf syncBuffer
+
+	h := NewBufferedHandler(NewLineHandler(&buf, nil), &BufferedOptions{
+		MaxRecords:    100,
+		FlushInterval: 50 * time.Millisecond,
+	})
+	defer h.Close()
+	logger := slog.New(h)
+
+	logger.Info("timed")
+	if got := buf.lines(); len(got) != 0 {
+		t.Fatalf("expected no output before interval, got %v", got)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(buf.lines()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !strings.Contains(buf.String(), "INFO: timed") {
+		t.Fatalf("expected record flushed by interval, got: %s", buf.String())
+	}
+}
+
+func TestBufferedHandler_FlushAndClose(t *testing.T) {
+	var buf syncBuffer
+
+	h := NewBufferedHandler(NewLineHandler(&buf, nil), &BufferedOptions{})
+	logger := slog.New(h).With(slog.String("app", "demo"))
+
+	logger.Info("first")
+	if err := h.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if got := buf.lines(); len(got) != 1 || !strings.Contains(got[0], `"app":"demo"`) {
+		t.Fatalf("expected one flushed line with app attr, got %v", got)
+	}
+
+	logger.Info("second")
+	logger.Info("last")
+	if err := h.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	got := buf.lines()
+	if len(got) != 3 || !strings.Contains(got[2], "INFO: last") {
+		t.Fatalf("expected all records after Close, got %v", got)
+	}
+
+	// after Close records pass straight through
+	logger.Info("after close")
+	if !strings.Contains(buf.String(), "INFO: after close") {
+		t.Fatalf("expected record written after Close, got: %s", buf.String())
+	}
+}
+
+func TestBufferedHandler_ClosesWrappedHandler(t *testing.T) {
+	var buf syncBuffer
+
+	inner := NewHandler(&Options{Writer: &buf, Format: FormatJSON})
+	h := NewBufferedHandler(inner, nil)
+	slog.New(h).Info("wrapped")
+
+	if err := h.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"msg":"wrapped"`) {
+		t.Fatalf("expected JSON record after Close, got: %s", buf.String())
+	}
+}
+
+// ctxHandler records the context error each record is handled with.
+type ctxHandler struct {
+	slog.Handler
+	mu   sync.Mutex
+	errs []error
+}
+
+func (h *ctxHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.mu.Lock()
+	h.errs = append(h.errs, ctx.Err())
+	h.mu.Unlock()
+	return h.Handler.Handle(ctx, r)
+}
+
+func TestBufferedHandler_DeferredEnrichment(t *testing.T) {
+	var buf syncBuffer
+
+	inner := &ctxHandler{Handler: NewHandler(&Options{
+		Writer:         &buf,
+		Format:         FormatLine,
+		Level:          slog.LevelInfo,
+		TraceExtractor: DefaultTraceExtractor,
+	})}
+	h := NewBufferedHandler(inner, &BufferedOptions{})
+	defer h.Close()
+
+	ctx, cancel := context.WithCancel(SetTraceID(context.Background(), "req-1"))
+	slog.New(h).InfoContext(ctx, "handled")
+	cancel()
+	if len(inner.errs) != 0 {
+		t.Fatal("expected the record held, not handled, before the flush")
+	}
+
+	if err := h.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	// enrichment runs at the flush, with the logging call's context as it is by then
+	if len(inner.errs) != 1 || !errors.Is(inner.errs[0], context.Canceled) {
+		t.Errorf("expected the wrapped handler to see the cancelled logging context, got %v", inner.errs)
+	}
+	if !strings.Contains(buf.String(), `INFO: handled {"trace_id":"req-1"}`) {
+		t.Errorf("expected the context's trace ID, got: %s", buf.String())
+	}
+}
+
+// closeTrackingHandler records messages and counts those handled after Close.
+type closeTrackingHandler struct {
+	slog.Handler
+	mu         sync.Mutex
+	msgs       []string
+	closed     bool
+	afterClose int
+}
+
+func (h *closeTrackingHandler) Handle(_ context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		h.afterClose++
+	}
+	h.msgs = append(h.msgs, r.Message)
+	return nil
+}
+
+func (h *closeTrackingHandler) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	return nil
+}
+
+func TestBufferedHandler_ConcurrentClose(t *testing.T) {
+	inner := &closeTrackingHandler{Handler: slog.NewTextHandler(io.Discard, nil)}
+	h := NewBufferedHandler(inner, &BufferedOptions{MaxRecords: 16})
+	logger := slog.New(h)
+
+	const loggers, perLogger = 8, 5000
+	var wg sync.WaitGroup
+	for g := range loggers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range perLogger {
+				logger.Info(fmt.Sprintf("%d-%d", g, i))
+			}
+		}()
+	}
+	time.Sleep(time.Millisecond)
+	if err := h.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	wg.Wait()
+
+	inner.mu.Lock()
+	defer inner.mu.Unlock()
+	if inner.afterClose != 0 {
+		t.Errorf("expected no record to reach the closed handler, got %d", inner.afterClose)
+	}
+	// each goroutine's records arrive in order, and only the ones logged after Close are dropped
+	next := make([]int, loggers)
+	for _, msg := range inner.msgs {
+		var g, i int
+		if _, err := fmt.Sscanf(msg, "%d-%d", &g, &i); err != nil {
+			t.Fatalf("unexpected message %q", msg)
+		}
+		if i != next[g] {
+			t.Fatalf("goroutine %d: expected record %d, got %d", g, next[g], i)
+		}
+		next[g]++
+	}
+}
@@ -0,0 +1,39 @@
+package glog
+
+import (
+	"log/slog"
+	"math"
+	"strconv"
+)
+
+// ByteSize is a number of bytes. FormatLine output, including the ConsoleLevel mirror, writes it in
+// binary units (e.g. "1.0GiB"); JSON and text output write the raw number, so it stays machine-readable.
+type ByteSize int64
+
+// Bytes returns an attribute for a byte count n, rendered per format as described for ByteSize:
+//
+//	logger.Info("uploaded", glog.Bytes("size", 1<<30)) // line: {"size":"1.0GiB"}, JSON: "size":1073741824
+func Bytes(key string, n int64) slog.Attr {
+	return slog.Any(key, ByteSize(n))
+}
+
+// LogValue returns the raw number of bytes.
+func (b ByteSize) LogValue() slog.Value {
+	return slog.Int64Value(int64(b))
+}
+
+// String formats b in binary units with one decimal ("1.5KiB", "1.0GiB"); below 1KiB it is a
+// plain byte count ("512B").
+func (b ByteSize) String() string {
+	if b > -1024 && b < 1024 {
+		return strconv.FormatInt(int64(b), 10) + "B"
+	}
+	const units = "KMGTPE"
+	v := float64(b) / 1024
+	i := 0
+	for math.Abs(v) >= 1024 && i < len(units)-1 {
+		v /= 1024
+		i++
+	}
+	return strconv.FormatFloat(v, 'f', 1, 64) + units[i:i+1] + "iB"
+}
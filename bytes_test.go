@@ -0,0 +1,51 @@
+package glog
+
+import (
+	"bytes"
+	"log/slog"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestByteSize_String(t *testing.T) {
+	cases := map[ByteSize]string{
+		0:             "0B",
+		512:           "512B",
+		1023:          "1023B",
+		1024:          "1.0KiB",
+		1536:          "1.5KiB",
+		10 << 20:      "10.0MiB",
+		1 << 30:       "1.0GiB",
+		-2048:         "-2.0KiB",
+		math.MaxInt64: "8.0EiB",
+	}
+	for size, want := range cases {
+		if got := size.String(); got != want {
+			t.Errorf("ByteSize(%d): expected %q, got %q", int64(size), want, got)
+		}
+	}
+}
+
+func TestBytes(t *testing.T) {
+	cases := map[FormatType]string{
+		FormatLine: `"size":"1.0GiB"`,
+		FormatJSON: `"size":1073741824`,
+		FormatText: `size=1073741824`,
+	}
+	for format, want := range cases {
+		var buf bytes.Buffer
+		logger := slog.New(NewHandler(&Options{Writer: &buf, Format: format, Level: slog.LevelInfo}))
+		logger.Info("uploaded", Bytes("size", 1<<30))
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("format %d: expected %s, got: %s", format, want, buf.String())
+		}
+	}
+
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&Options{Writer: &buf, Format: FormatLine, Level: slog.LevelInfo}))
+	logger.With(Bytes("limit", 1536)).Info("quota", slog.Group("disk", Bytes("free", 512)))
+	if !strings.Contains(buf.String(), `"limit":"1.5KiB","disk.free":"512B"`) {
+		t.Errorf("expected bound and grouped sizes human-readable, got: %s", buf.String())
+	}
+}
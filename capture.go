@@ -0,0 +1,50 @@
+package glog
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+)
+
+// Capture runs fn with the slog default logger writing into a buffer instead of its usual output,
+// and returns the lines logged meanwhile, in the default line format at info level and above,
+// together with fn's error. The previous default is restored when fn returns, even if it panics.
+//
+// The redirect is process-wide: records any goroutine logs through the default logger while fn runs
+// are captured, including goroutines fn did not start, and records logged after fn returns are not.
+// Wait for goroutines fn starts before returning from it to capture their output.
+func Capture(fn func() error) ([]string, error) {
+	var buf lockedBuffer
+	opts := defaultOptions()
+	opts.Writer = &buf
+
+	restore := PushDefault(opts)
+	err := func() error {
+		defer restore()
+		return fn()
+	}()
+
+	out := strings.TrimSuffix(buf.String(), "\n")
+	if out == "" {
+		return nil, err
+	}
+	return strings.Split(out, "\n"), err
+}
+
+// lockedBuffer is a bytes.Buffer safe for concurrent use.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
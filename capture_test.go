@@ -0,0 +1,49 @@
+package glog
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCapture(t *testing.T) {
+	var outside bytes.Buffer
+	defer PushDefault(&Options{Writer: &outside, Level: slog.LevelInfo})()
+
+	slog.Info("before")
+	failed := errors.New("failed")
+	lines, err := Capture(func() error {
+		slog.Info("inside", "step", 1)
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slog.Warn("from goroutine")
+		}()
+		wg.Wait()
+		slog.Debug("below info")
+		return failed
+	})
+	slog.Info("after")
+
+	if err != failed {
+		t.Errorf("expected fn's error, got %v", err)
+	}
+	if len(lines) != 2 || !strings.Contains(lines[0], `INFO: inside {"step":1}`) || !strings.Contains(lines[1], "WARN: from goroutine") {
+		t.Errorf("expected the two records logged inside, got %q", lines)
+	}
+	out := outside.String()
+	if !strings.Contains(out, "before") || !strings.Contains(out, "after") || strings.Contains(out, "inside") {
+		t.Errorf("expected only the records outside Capture in the default output, got: %s", out)
+	}
+}
+
+func TestCapture_Nothing(t *testing.T) {
+	lines, err := Capture(func() error { return nil })
+	if lines != nil || err != nil {
+		t.Errorf("expected no lines and no error, got %q, %v", lines, err)
+	}
+}
@@ -0,0 +1,277 @@
+package cbor
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// errBreak is returned by value when it reads the break code ending an indefinite-length item.
+var errBreak = errors.New("cbor: unexpected break")
+
+// Decoder reads CBOR records, such as those written by Handler, from a stream.
+type Decoder struct {
+	r *bufio.Reader
+}
+
+// NewDecoder returns a Decoder reading from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r)}
+}
+
+// Decode reads the next record. Values decode to string, int64 (uint64 when too large), float64,
+// bool, nil, []byte, time.Time (tag 1), []any and map[string]any (groups). It returns io.EOF when
+// the stream ends cleanly.
+func (d *Decoder) Decode() (map[string]any, error) {
+	v, err := d.value()
+	if err != nil {
+		return nil, err
+	}
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("cbor: expected a map, got %T", v)
+	}
+	return m, nil
+}
+
+// value reads one data item.
+func (d *Decoder) value() (any, error) {
+	head, err := d.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if head == breakCode {
+		return nil, errBreak
+	}
+	major, info := head>>5, head&0x1f
+
+	if major == majorSimple {
+		return d.simple(info)
+	}
+	if info == 31 {
+		return d.indefinite(major)
+	}
+	n, err := d.arg(info)
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case majorUint:
+		if n > math.MaxInt64 {
+			return n, nil
+		}
+		return int64(n), nil
+	case majorNegInt:
+		if n > math.MaxInt64 {
+			return nil, errors.New("cbor: negative integer out of range")
+		}
+		return -1 - int64(n), nil
+	case majorBytes, majorText:
+		p := make([]byte, n)
+		if _, err := io.ReadFull(d.r, p); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		if major == majorText {
+			return string(p), nil
+		}
+		return p, nil
+	case majorArray:
+		arr := make([]any, 0, n)
+		for range n {
+			v, err := d.item()
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		return arr, nil
+	case majorMap:
+		m := make(map[string]any, n)
+		for range n {
+			if err := d.entry(m); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	default: // majorTag
+		v, err := d.item()
+		if err != nil {
+			return nil, err
+		}
+		if n == tagEpochTime {
+			return epochTime(v)
+		}
+		return v, nil
+	}
+}
+
+// item reads a data item nested in another one, where EOF is always unexpected.
+func (d *Decoder) item() (any, error) {
+	v, err := d.value()
+	return v, unexpectedEOF(err)
+}
+
+// entry reads one map entry into m.
+func (d *Decoder) entry(m map[string]any) error {
+	k, err := d.item()
+	if err != nil {
+		return err
+	}
+	v, err := d.item()
+	if err != nil {
+		return err
+	}
+	key, ok := k.(string)
+	if !ok {
+		key = fmt.Sprint(k)
+	}
+	m[key] = v
+	return nil
+}
+
+// indefinite reads the rest of an indefinite-length item of the given major type.
+func (d *Decoder) indefinite(major byte) (any, error) {
+	switch major {
+	case majorBytes, majorText:
+		var p []byte
+		for {
+			chunk, err := d.item()
+			if err == errBreak {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			switch c := chunk.(type) {
+			case []byte:
+				p = append(p, c...)
+			case string:
+				p = append(p, c...)
+			default:
+				return nil, fmt.Errorf("cbor: invalid chunk %T in an indefinite-length string", chunk)
+			}
+		}
+		if major == majorText {
+			return string(p), nil
+		}
+		return p, nil
+	case majorArray:
+		var arr []any
+		for {
+			v, err := d.item()
+			if err == errBreak {
+				return arr, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+	case majorMap:
+		m := make(map[string]any)
+		for {
+			err := d.entry(m)
+			if err == errBreak {
+				return m, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return nil, fmt.Errorf("cbor: invalid indefinite length for major type %d", major)
+}
+
+// simple reads a major type 7 item: false, true, null, undefined or a float.
+func (d *Decoder) simple(info byte) (any, error) {
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23:
+		return nil, nil
+	case 25:
+		n, err := d.arg(info)
+		return halfToFloat(uint16(n)), err
+	case 26:
+		n, err := d.arg(info)
+		return float64(math.Float32frombits(uint32(n))), err
+	case 27:
+		n, err := d.arg(info)
+		return math.Float64frombits(n), err
+	}
+	return nil, fmt.Errorf("cbor: unsupported simple value %d", info)
+}
+
+// arg reads the argument encoded by the additional information of a head.
+func (d *Decoder) arg(info byte) (uint64, error) {
+	var size int
+	switch {
+	case info < 24:
+		return uint64(info), nil
+	case info == 24:
+		size = 1
+	case info == 25:
+		size = 2
+	case info == 26:
+		size = 4
+	case info == 27:
+		size = 8
+	default:
+		return 0, fmt.Errorf("cbor: invalid additional information %d", info)
+	}
+	var p [8]byte
+	if _, err := io.ReadFull(d.r, p[8-size:]); err != nil {
+		return 0, unexpectedEOF(err)
+	}
+	return binary.BigEndian.Uint64(p[:]), nil
+}
+
+// epochTime converts the content of a tag 1 item to a time.
+func epochTime(v any) (time.Time, error) {
+	switch s := v.(type) {
+	case int64:
+		return time.Unix(s, 0), nil
+	case float64:
+		sec, frac := math.Modf(s)
+		return time.Unix(int64(sec), int64(math.Round(frac*1e9))), nil
+	}
+	return time.Time{}, fmt.Errorf("cbor: invalid epoch time %T", v)
+}
+
+// halfToFloat converts an IEEE 754 half-precision float.
+func halfToFloat(h uint16) float64 {
+	exp := int(h>>10) & 0x1f
+	mant := float64(h & 0x3ff)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(mant, -24)
+	case 31:
+		if mant == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		f = -f
+	}
+	return f
+}
+
+// unexpectedEOF turns io.EOF in the middle of an item into io.ErrUnexpectedEOF.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,127 @@
+// Package cbor provides a slog.Handler that writes each record as a CBOR (RFC 8949) map, a compact
+// binary alternative to JSON for bandwidth-constrained sinks, and a Decoder to read the records back.
+//
+// The handler mirrors slog's JSON handler: built-in keys are time, level, msg and source, groups
+// become nested maps, and ReplaceAttr is applied the same way. CBOR items are self-delimiting, so
+// records can be written back to back to any io.Writer, such as a glog FileWriter:
+//
+//	h := cbor.NewHandler(glog.NewFileWriter("/var/log/app-2006-01-02.cbor", 7), nil)
+package cbor
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"sort"
+	"time"
+)
+
+// CBOR major types.
+const (
+	majorUint   = 0
+	majorNegInt = 1
+	majorBytes  = 2
+	majorText   = 3
+	majorArray  = 4
+	majorMap    = 5
+	majorTag    = 6
+	majorSimple = 7
+)
+
+const (
+	beginIndefiniteMap = majorMap<<5 | 31
+	breakCode          = 0xff
+	simpleFalse        = majorSimple<<5 | 20
+	simpleTrue         = majorSimple<<5 | 21
+	simpleNull         = majorSimple<<5 | 22
+	float64Head        = majorSimple<<5 | 27
+	tagEpochTime       = 1 // tag for a number of seconds since the Unix epoch
+)
+
+// appendHead appends an item head: the major type and its argument in the shortest form.
+func appendHead(b []byte, major byte, n uint64) []byte {
+	m := major << 5
+	switch {
+	case n < 24:
+		return append(b, m|byte(n))
+	case n <= math.MaxUint8:
+		return append(b, m|24, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, m|25), uint16(n))
+	case n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, m|26), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, m|27), n)
+	}
+}
+
+func appendInt(b []byte, i int64) []byte {
+	if i >= 0 {
+		return appendHead(b, majorUint, uint64(i))
+	}
+	return appendHead(b, majorNegInt, uint64(-1-i))
+}
+
+func appendText(b []byte, s string) []byte {
+	return append(appendHead(b, majorText, uint64(len(s))), s...)
+}
+
+func appendBytes(b []byte, p []byte) []byte {
+	return append(appendHead(b, majorBytes, uint64(len(p))), p...)
+}
+
+func appendFloat(b []byte, f float64) []byte {
+	return binary.BigEndian.AppendUint64(append(b, float64Head), math.Float64bits(f))
+}
+
+func appendBool(b []byte, v bool) []byte {
+	if v {
+		return append(b, simpleTrue)
+	}
+	return append(b, simpleFalse)
+}
+
+// appendTime appends t as an epoch-based date/time: tag 1 with fractional seconds, which keeps
+// sub-microsecond precision for current dates.
+func appendTime(b []byte, t time.Time) []byte {
+	b = appendHead(b, majorTag, tagEpochTime)
+	return appendFloat(b, float64(t.UnixNano())/1e9)
+}
+
+// appendJSONValue appends a value decoded from JSON (with json.Number numbers) in CBOR form.
+// Map keys are sorted so output is deterministic.
+func appendJSONValue(b []byte, v any) []byte {
+	switch v := v.(type) {
+	case nil:
+		return append(b, simpleNull)
+	case bool:
+		return appendBool(b, v)
+	case string:
+		return appendText(b, v)
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return appendInt(b, i)
+		}
+		f, _ := v.Float64()
+		return appendFloat(b, f)
+	case []any:
+		b = appendHead(b, majorArray, uint64(len(v)))
+		for _, e := range v {
+			b = appendJSONValue(b, e)
+		}
+		return b
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		b = appendHead(b, majorMap, uint64(len(v)))
+		for _, k := range keys {
+			b = appendText(b, k)
+			b = appendJSONValue(b, v[k])
+		}
+		return b
+	}
+	return append(b, simpleNull) // not produced by encoding/json
+}
@@ -0,0 +1,222 @@
+package cbor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Handler is a slog.Handler that writes each record as one CBOR map.
+type Handler struct {
+	w    io.Writer
+	mu   *sync.Mutex // shared with derived handlers; guards writes to w
+	opts slog.HandlerOptions
+	goas []groupOrAttrs // WithGroup and WithAttrs calls, in order
+}
+
+// groupOrAttrs is one WithGroup (group set) or WithAttrs (attrs set) call.
+type groupOrAttrs struct {
+	group string
+	attrs []slog.Attr
+}
+
+// NewHandler creates a Handler writing to w. opts may be nil.
+func NewHandler(w io.Writer, opts *slog.HandlerOptions) *Handler {
+	h := &Handler{w: w, mu: &sync.Mutex{}}
+	if opts != nil {
+		h.opts = *opts
+	}
+	return h
+}
+
+// Enabled reports whether the given level is enabled.
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+	return level >= minLevel
+}
+
+// Handle writes r as a CBOR map.
+func (h *Handler) Handle(_ context.Context, r slog.Record) error {
+	b := make([]byte, 0, 256)
+	b = append(b, beginIndefiniteMap)
+
+	if !r.Time.IsZero() {
+		b = h.appendBuiltin(b, slog.Time(slog.TimeKey, r.Time))
+	}
+	b = h.appendBuiltin(b, slog.Any(slog.LevelKey, r.Level))
+	b = h.appendBuiltin(b, slog.String(slog.MessageKey, r.Message))
+	if h.opts.AddSource && r.PC != 0 {
+		frames := runtime.CallersFrames([]uintptr{r.PC})
+		f, _ := frames.Next()
+		b = h.appendBuiltin(b, slog.Any(slog.SourceKey, &slog.Source{Function: f.Function, File: f.File, Line: f.Line}))
+	}
+
+	goas := h.goas
+	if r.NumAttrs() == 0 {
+		// like slog's built-in handlers, omit groups that would be empty
+		for len(goas) > 0 && goas[len(goas)-1].group != "" {
+			goas = goas[:len(goas)-1]
+		}
+	}
+	var groups []string
+	for _, goa := range goas {
+		if goa.group != "" {
+			b = appendText(b, goa.group)
+			b = append(b, beginIndefiniteMap)
+			groups = append(groups, goa.group)
+			continue
+		}
+		for _, a := range goa.attrs {
+			b = h.appendAttr(b, a, groups)
+		}
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		b = h.appendAttr(b, a, groups)
+		return true
+	})
+	for range groups {
+		b = append(b, breakCode)
+	}
+	b = append(b, breakCode)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(b)
+	return err
+}
+
+// appendBuiltin appends a built-in attribute after ReplaceAttr.
+func (h *Handler) appendBuiltin(b []byte, a slog.Attr) []byte {
+	if h.opts.ReplaceAttr != nil {
+		a = h.opts.ReplaceAttr(nil, a)
+		a.Value = a.Value.Resolve()
+	}
+	if a.Key == "" {
+		return b
+	}
+	b = appendText(b, a.Key)
+	return appendValue(b, a.Value)
+}
+
+// appendAttr appends a, with groups open, following slog's rules: LogValuers are resolved,
+// ReplaceAttr is applied to non-group attributes, empty-keyed attributes are dropped, empty groups
+// are omitted, and groups with an empty key are inlined.
+func (h *Handler) appendAttr(b []byte, a slog.Attr, groups []string) []byte {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() != slog.KindGroup && h.opts.ReplaceAttr != nil {
+		a = h.opts.ReplaceAttr(groups, a)
+		a.Value = a.Value.Resolve()
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		attrs := a.Value.Group()
+		if len(attrs) == 0 {
+			return b
+		}
+		if a.Key != "" {
+			b = appendText(b, a.Key)
+			b = append(b, beginIndefiniteMap)
+			groups = append(groups[:len(groups):len(groups)], a.Key)
+		}
+		for _, ga := range attrs {
+			b = h.appendAttr(b, ga, groups)
+		}
+		if a.Key != "" {
+			b = append(b, breakCode)
+		}
+		return b
+	}
+
+	if a.Key == "" {
+		return b
+	}
+	b = appendText(b, a.Key)
+	return appendValue(b, a.Value)
+}
+
+// appendValue appends a resolved, non-group value.
+func appendValue(b []byte, v slog.Value) []byte {
+	switch v.Kind() {
+	case slog.KindString:
+		return appendText(b, v.String())
+	case slog.KindInt64:
+		return appendInt(b, v.Int64())
+	case slog.KindUint64:
+		return appendHead(b, majorUint, v.Uint64())
+	case slog.KindFloat64:
+		return appendFloat(b, v.Float64())
+	case slog.KindBool:
+		return appendBool(b, v.Bool())
+	case slog.KindDuration:
+		// nanoseconds, as slog's JSON handler writes them
+		return appendInt(b, int64(v.Duration()))
+	case slog.KindTime:
+		return appendTime(b, v.Time())
+	}
+	return appendAny(b, v.Any())
+}
+
+// appendAny appends an arbitrary value the way slog's JSON handler would encode it.
+func appendAny(b []byte, v any) []byte {
+	switch x := v.(type) {
+	case nil:
+		return append(b, simpleNull)
+	case slog.Level:
+		return appendText(b, x.String())
+	case error:
+		return appendText(b, x.Error())
+	case []byte:
+		return appendBytes(b, x)
+	case time.Time:
+		return appendTime(b, x)
+	case *slog.Source:
+		b = appendHead(b, majorMap, 3)
+		b = appendText(appendText(b, "function"), x.Function)
+		b = appendText(appendText(b, "file"), x.File)
+		return appendInt(appendText(b, "line"), int64(x.Line))
+	}
+
+	// everything else goes through encoding/json, so its structure matches the JSON handler
+	data, err := json.Marshal(v)
+	if err != nil {
+		return appendText(b, fmt.Sprintf("!ERROR:%v", err))
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var generic any
+	if err := dec.Decode(&generic); err != nil {
+		return appendText(b, fmt.Sprintf("!ERROR:%v", err))
+	}
+	return appendJSONValue(b, generic)
+}
+
+// WithAttrs returns a new Handler with the given attributes.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	return h.withGroupOrAttrs(groupOrAttrs{attrs: attrs})
+}
+
+// WithGroup returns a new Handler that nests later attributes in a group.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return h.withGroupOrAttrs(groupOrAttrs{group: name})
+}
+
+func (h *Handler) withGroupOrAttrs(goa groupOrAttrs) *Handler {
+	c := *h
+	c.goas = append(h.goas[:len(h.goas):len(h.goas)], goa)
+	return &c
+}
@@ -0,0 +1,140 @@
+package cbor
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"testing/slogtest"
+	"time"
+)
+
+func decodeAll(t *testing.T, data []byte) []map[string]any {
+	t.Helper()
+	dec := NewDecoder(bytes.NewReader(data))
+	var records []map[string]any
+	for {
+		m, err := dec.Decode()
+		if err == io.EOF {
+			return records
+		}
+		if err != nil {
+			t.Fatalf("Decode failed: %v", err)
+		}
+		records = append(records, m)
+	}
+}
+
+func TestHandler_RoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+
+	logger := slog.New(NewHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	at := time.Date(2024, 5, 1, 12, 30, 0, 123456000, time.UTC)
+	logger.With(slog.String("app", "edge")).WithGroup("req").Debug("sent",
+		slog.Int("status", -404),
+		slog.Uint64("bytes", 1<<40),
+		slog.Float64("ratio", 0.25),
+		slog.Bool("ok", false),
+		slog.Duration("took", 1500*time.Millisecond),
+		slog.Time("at", at),
+		slog.Any("err", errors.New("boom")),
+		slog.Any("raw", []byte{0xde, 0xad}),
+		slog.Any("tags", []string{"a", "b"}),
+		slog.Group("peer", slog.String("ip", "10.0.0.1"), slog.Int("port", 443)),
+	)
+	logger.Info("second")
+
+	records := decodeAll(t, buf.Bytes())
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+	rec := records[0]
+	if rec["level"] != "DEBUG" || rec["msg"] != "sent" || rec["app"] != "edge" {
+		t.Errorf("unexpected built-in fields: %v", rec)
+	}
+	if ts, ok := rec["time"].(time.Time); !ok || time.Since(ts) > time.Minute {
+		t.Errorf("expected a recent time, got %v", rec["time"])
+	}
+
+	req, ok := rec["req"].(map[string]any)
+	if !ok {
+		t.Fatalf("expected a req group, got %v", rec)
+	}
+	want := map[string]any{
+		"status": int64(-404),
+		"bytes":  int64(1 << 40),
+		"ratio":  0.25,
+		"ok":     false,
+		"took":   int64(1500 * time.Millisecond),
+		"err":    "boom",
+	}
+	for k, v := range want {
+		if req[k] != v {
+			t.Errorf("req.%s: expected %v (%T), got %v (%T)", k, v, v, req[k], req[k])
+		}
+	}
+	if got, _ := req["at"].(time.Time); got.Sub(at).Abs() > time.Microsecond {
+		t.Errorf("expected %v, got %v", at, req["at"])
+	}
+	if raw, _ := req["raw"].([]byte); !bytes.Equal(raw, []byte{0xde, 0xad}) {
+		t.Errorf("expected a byte string, got %v", req["raw"])
+	}
+	if tags, _ := req["tags"].([]any); len(tags) != 2 || tags[0] != "a" || tags[1] != "b" {
+		t.Errorf("expected the tags array, got %v", req["tags"])
+	}
+	if peer, _ := req["peer"].(map[string]any); peer["ip"] != "10.0.0.1" || peer["port"] != int64(443) {
+		t.Errorf("expected the nested peer group, got %v", req["peer"])
+	}
+
+	if _, ok := records[1]["req"]; ok {
+		t.Errorf("expected no group on a handler without it, got %v", records[1])
+	}
+}
+
+func TestHandler_ReplaceAttr(t *testing.T) {
+	var buf bytes.Buffer
+
+	logger := slog.New(NewHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey || a.Key == "secret" {
+				return slog.Attr{}
+			}
+			if len(groups) > 0 && a.Key == "id" {
+				a.Key = strings.Join(groups, "_") + "_id"
+			}
+			return a
+		},
+	}))
+	logger.WithGroup("user").Info("login", slog.String("id", "u1"), slog.String("secret", "x"))
+
+	rec := decodeAll(t, buf.Bytes())[0]
+	if _, ok := rec["time"]; ok {
+		t.Errorf("expected time removed, got %v", rec)
+	}
+	user, _ := rec["user"].(map[string]any)
+	if len(user) != 1 || user["user_id"] != "u1" {
+		t.Errorf("expected only the renamed id in the group, got %v", rec["user"])
+	}
+}
+
+func TestHandler_Slogtest(t *testing.T) {
+	var buf bytes.Buffer
+	err := slogtest.TestHandler(NewHandler(&buf, nil), func() []map[string]any {
+		return decodeAll(t, buf.Bytes())
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestDecoder_Truncated(t *testing.T) {
+	var buf bytes.Buffer
+	slog.New(NewHandler(&buf, nil)).Info("hello")
+
+	data := buf.Bytes()
+	if _, err := NewDecoder(bytes.NewReader(data[:len(data)-3])).Decode(); err != io.ErrUnexpectedEOF {
+		t.Errorf("expected io.ErrUnexpectedEOF for a truncated record, got %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package glog
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// checkpointPrefix starts the key of each elapsed-since-checkpoint field.
+const checkpointPrefix = "elapsed_since_"
+
+// checkpointContextKey is the context key used by Checkpoint.
+type checkpointContextKey struct{}
+
+// checkpoint is one named point in time; checkpoints stored in a context form a list, newest first.
+type checkpoint struct {
+	name string
+	at   time.Time
+	prev *checkpoint
+}
+
+// Checkpoint returns a context recording the current time under name, for timing the steps of an
+// operation: records logged with the returned context (or one derived from it) can carry the time
+// elapsed since it, with ElapsedSince or Options.IncludeCheckpoints. Setting a name again moves that
+// checkpoint to the current time.
+func Checkpoint(ctx context.Context, name string) context.Context {
+	prev, _ := ctx.Value(checkpointContextKey{}).(*checkpoint)
+	return context.WithValue(ctx, checkpointContextKey{}, &checkpoint{name: name, at: time.Now(), prev: prev})
+}
+
+// ElapsedSince returns an "elapsed_since_<name>" duration attribute with the time since the checkpoint
+// name was set on ctx, or an empty attribute (which slog drops) if ctx has none by that name.
+//
+//	ctx = glog.Checkpoint(ctx, "db")
+//	rows, err := query(ctx)
+//	logger.InfoContext(ctx, "queried", glog.ElapsedSince(ctx, "db"))
+func ElapsedSince(ctx context.Context, name string) slog.Attr {
+	for c, _ := ctx.Value(checkpointContextKey{}).(*checkpoint); c != nil; c = c.prev {
+		if c.name == name {
+			return slog.Duration(checkpointPrefix+name, time.Since(c.at))
+		}
+	}
+	return slog.Attr{}
+}
+
+// checkpointAttrs returns an elapsed-since field for each checkpoint on ctx, as of at, oldest first.
+// A name set more than once counts from its latest checkpoint.
+func checkpointAttrs(ctx context.Context, at time.Time) []slog.Attr {
+	var attrs []slog.Attr
+	seen := map[string]bool{}
+	for c, _ := ctx.Value(checkpointContextKey{}).(*checkpoint); c != nil; c = c.prev {
+		if seen[c.name] {
+			continue
+		}
+		seen[c.name] = true
+		attrs = append(attrs, slog.Duration(checkpointPrefix+c.name, at.Sub(c.at)))
+	}
+	// the list is newest first
+	for i, j := 0, len(attrs)-1; i < j; i, j = i+1, j-1 {
+		attrs[i], attrs[j] = attrs[j], attrs[i]
+	}
+	return attrs
+}
@@ -0,0 +1,73 @@
+package glog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandler_IncludeCheckpoints(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&Options{
+		Writer:             &buf,
+		Format:             FormatJSON,
+		Level:              slog.LevelInfo,
+		IncludeCheckpoints: true,
+	}))
+
+	ctx := Checkpoint(context.Background(), "request")
+	time.Sleep(5 * time.Millisecond)
+	ctx = Checkpoint(ctx, "db")
+	logger.InfoContext(ctx, "queried")
+	logger.Info("no checkpoints")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got: %s", buf.String())
+	}
+	// oldest checkpoint first
+	first := strings.Index(lines[0], `"elapsed_since_request":`)
+	second := strings.Index(lines[0], `"elapsed_since_db":`)
+	if first < 0 || second < first {
+		t.Fatalf("expected elapsed_since_request before elapsed_since_db, got: %s", lines[0])
+	}
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	request, _ := entry["elapsed_since_request"].(float64)
+	db, _ := entry["elapsed_since_db"].(float64)
+	if db < 0 || request-db < float64(5*time.Millisecond) {
+		t.Errorf("expected the request step to include the 5ms before db, got request=%v db=%v", request, db)
+	}
+	if strings.Contains(lines[1], "elapsed_since_") {
+		t.Errorf("expected no fields without checkpoints, got: %s", lines[1])
+	}
+}
+
+func TestElapsedSince(t *testing.T) {
+	ctx := Checkpoint(context.Background(), "db")
+	ctx = Checkpoint(ctx, "cache")
+	time.Sleep(time.Millisecond)
+
+	attr := ElapsedSince(ctx, "db")
+	if attr.Key != "elapsed_since_db" || attr.Value.Kind() != slog.KindDuration || attr.Value.Duration() < time.Millisecond {
+		t.Errorf("expected elapsed_since_db of at least 1ms, got %v", attr)
+	}
+	if attr := ElapsedSince(ctx, "missing"); !attr.Equal(slog.Attr{}) {
+		t.Errorf("expected an empty attribute for an unknown checkpoint, got %v", attr)
+	}
+
+	// setting a name again restarts it
+	ctx = Checkpoint(ctx, "db")
+	if again := ElapsedSince(ctx, "db"); again.Value.Duration() >= attr.Value.Duration() {
+		t.Errorf("expected the reset checkpoint to count from its latest time, got %v", again)
+	}
+	if attrs := checkpointAttrs(ctx, time.Now()); len(attrs) != 2 || attrs[0].Key != "elapsed_since_cache" || attrs[1].Key != "elapsed_since_db" {
+		t.Errorf("expected one field per name, oldest first, got %v", attrs)
+	}
+}
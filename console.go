@@ -0,0 +1,42 @@
+package glog
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// mirrorHandler passes each record to primary and, independently, to mirror, each only when enabled
+// for the record's level. ConsoleLevel uses it to copy warnings and errors to the console.
+type mirrorHandler struct {
+	primary slog.Handler
+	mirror  slog.Handler
+}
+
+// Enabled reports whether either handler is enabled.
+func (h *mirrorHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.primary.Enabled(ctx, level) || h.mirror.Enabled(ctx, level)
+}
+
+// Handle passes r to each enabled handler; a failing mirror does not keep r from primary. Records
+// dumped by a debug ring go to primary only.
+func (h *mirrorHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	if h.primary.Enabled(ctx, r.Level) || isDebugDump(ctx) {
+		errs = append(errs, h.primary.Handle(ctx, r))
+	}
+	if h.mirror.Enabled(ctx, r.Level) {
+		errs = append(errs, h.mirror.Handle(ctx, r.Clone()))
+	}
+	return errors.Join(errs...)
+}
+
+// WithAttrs returns a new mirrorHandler with attrs added to both handlers.
+func (h *mirrorHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &mirrorHandler{primary: h.primary.WithAttrs(attrs), mirror: h.mirror.WithAttrs(attrs)}
+}
+
+// WithGroup returns a new mirrorHandler with the group opened in both handlers.
+func (h *mirrorHandler) WithGroup(name string) slog.Handler {
+	return &mirrorHandler{primary: h.primary.WithGroup(name), mirror: h.mirror.WithGroup(name)}
+}
@@ -0,0 +1,65 @@
+package glog
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestHandler_ConsoleLevel(t *testing.T) {
+	var file, console bytes.Buffer
+
+	handler := NewHandler(&Options{
+		Writer:        &file,
+		Format:        FormatJSON,
+		Level:         slog.LevelInfo,
+		ConsoleLevel:  slog.LevelWarn,
+		ConsoleWriter: &console,
+	})
+	logger := slog.New(handler).With(slog.String("app", "demo"))
+	logger.Info("started")
+	logger.Error("failed", slog.Int("code", 500))
+
+	fileOut := file.String()
+	if !strings.Contains(fileOut, `"msg":"started"`) || !strings.Contains(fileOut, `"msg":"failed"`) {
+		t.Errorf("expected every record in the file in JSON, got: %s", fileOut)
+	}
+	consoleOut := strings.TrimSpace(console.String())
+	if strings.Contains(consoleOut, "started") {
+		t.Errorf("expected info kept off the console, got: %s", consoleOut)
+	}
+	if !strings.Contains(consoleOut, `ERROR: failed {"app":"demo","code":500}`) {
+		t.Errorf("expected the error on the console in line format, got: %s", consoleOut)
+	}
+}
+
+func TestHandler_ConsoleLevelDestinations(t *testing.T) {
+	var file, audit, console bytes.Buffer
+
+	handler := NewHandler(&Options{
+		Writer:        &file,
+		Writers:       map[string]io.Writer{"audit": &audit},
+		Format:        FormatJSON,
+		Level:         slog.LevelInfo,
+		ConsoleLevel:  slog.LevelWarn,
+		ConsoleWriter: &console,
+	})
+	logger := slog.New(handler).With(slog.String("app", "demo"))
+	ctx := WithDestination(context.Background(), "audit")
+	logger.InfoContext(ctx, "login")
+	logger.ErrorContext(ctx, "denied", slog.String("user", "u-1"))
+
+	if file.Len() != 0 || !strings.Contains(audit.String(), `"msg":"denied"`) {
+		t.Errorf("expected the records in the audit writer only, got file %q and audit %q", file.String(), audit.String())
+	}
+	consoleOut := strings.TrimSpace(console.String())
+	if strings.Contains(consoleOut, "login") {
+		t.Errorf("expected info kept off the console, got: %s", consoleOut)
+	}
+	if !strings.Contains(consoleOut, `ERROR: denied {"app":"demo","user":"u-1"}`) {
+		t.Errorf("expected the destination's error on the console, got: %s", consoleOut)
+	}
+}
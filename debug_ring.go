@@ -0,0 +1,89 @@
+package glog
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+)
+
+// defaultDebugRingSize is the ring size WithDebugRing uses for a size of 0 or less.
+const defaultDebugRingSize = 256
+
+// debugRingContextKey is the context key used by WithDebugRing.
+type debugRingContextKey struct{}
+
+// debugDumpContextKey marks the context of records written by a debug ring dump.
+type debugDumpContextKey struct{}
+
+// debugRing holds the latest records of one request that were below the handler's level.
+type debugRing struct {
+	mu      sync.Mutex
+	records []bufferedRecord // grows to size, then is overwritten in place from head
+	head    int              // index of the oldest record once records is full
+	size    int
+}
+
+// WithDebugRing returns a context carrying a ring for the latest size records (256 if size <= 0)
+// logged with it, or a context derived from it, below the handler's level. Such records are enriched
+// as usual but held instead of written; an error record logged with the context, or FlushDebug, writes
+// them first, so a failed request comes with its full debug context while successful requests, whose
+// ring is simply dropped with the context, cost no output.
+//
+//	ctx = glog.WithDebugRing(r.Context(), 0)
+//	logger.DebugContext(ctx, "cache miss", "key", key) // held
+//	logger.ErrorContext(ctx, "query failed", "err", err) // writes the held records, then this one
+//
+// Dumped records keep their level and time and go to the primary writer (or their WithDestination
+// writer), not to the ConsoleLevel mirror or Secondary output. Only Handler supports the ring.
+func WithDebugRing(ctx context.Context, size int) context.Context {
+	if size <= 0 {
+		size = defaultDebugRingSize
+	}
+	return context.WithValue(ctx, debugRingContextKey{}, &debugRing{size: size})
+}
+
+// FlushDebug writes the records held by the debug ring of ctx and empties it, e.g. when a request
+// ends in a failure that was not logged as an error. It does nothing if ctx has no ring.
+func FlushDebug(ctx context.Context) error {
+	ring, _ := ctx.Value(debugRingContextKey{}).(*debugRing)
+	if ring == nil {
+		return nil
+	}
+	return ring.flush()
+}
+
+// add holds r, dropping the oldest record when the ring is full.
+func (b *debugRing) add(handler slog.Handler, ctx context.Context, r slog.Record) {
+	br := bufferedRecord{handler: handler, ctx: ctx, record: r.Clone()}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.records) < b.size {
+		b.records = append(b.records, br)
+		return
+	}
+	b.records[b.head] = br
+	b.head = (b.head + 1) % b.size
+}
+
+// flush writes the held records, oldest first, with the handlers they were logged through.
+func (b *debugRing) flush() error {
+	b.mu.Lock()
+	records := append(b.records[b.head:len(b.records):len(b.records)], b.records[:b.head]...)
+	b.records, b.head = nil, 0
+	b.mu.Unlock()
+
+	var errs []error
+	for _, br := range records {
+		ctx := context.WithValue(br.ctx, debugDumpContextKey{}, true)
+		errs = append(errs, br.handler.Handle(ctx, br.record))
+	}
+	return errors.Join(errs...)
+}
+
+// isDebugDump reports whether ctx belongs to a record written by a debug ring dump, which is written
+// even though its level is below the handler's.
+func isDebugDump(ctx context.Context) bool {
+	dump, _ := ctx.Value(debugDumpContextKey{}).(bool)
+	return dump
+}
@@ -0,0 +1,102 @@
+package glog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandler_DebugRing(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&Options{
+		Writer:         &buf,
+		Format:         FormatLine,
+		Level:          slog.LevelInfo,
+		TraceExtractor: DefaultTraceExtractor,
+	}))
+
+	// a successful request: its debug records are discarded with the context
+	ok := WithDebugRing(SetTraceID(context.Background(), "ok-1"), 0)
+	logger.DebugContext(ok, "cache hit")
+	logger.InfoContext(ok, "served")
+
+	// a failed request: the error dumps the held debug records first
+	failed := WithDebugRing(SetTraceID(context.Background(), "fail-1"), 0)
+	logger.DebugContext(failed, "cache miss", "key", "k1")
+	logger.With("db", "main").DebugContext(failed, "query")
+	logger.InfoContext(failed, "retrying")
+	logger.ErrorContext(failed, "query failed")
+	logger.ErrorContext(failed, "gave up") // nothing left to dump
+
+	// no ring: debug records are filtered as usual
+	logger.Debug("plain debug")
+
+	want := []string{
+		`INFO: served {"trace_id":"ok-1"}`,
+		`INFO: retrying {"trace_id":"fail-1"}`,
+		`DEBUG: cache miss {"key":"k1","trace_id":"fail-1"}`,
+		`DEBUG: query {"db":"main","trace_id":"fail-1"}`,
+		`ERROR: query failed {"trace_id":"fail-1"}`,
+		`ERROR: gave up {"trace_id":"fail-1"}`,
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("expected %d lines, got:\n%s", len(want), buf.String())
+	}
+	for i, line := range lines {
+		if !strings.HasSuffix(line, "] "+want[i]) {
+			t.Errorf("line %d: expected %s, got %s", i, want[i], line)
+		}
+	}
+}
+
+func TestHandler_DebugRingFlushAndSize(t *testing.T) {
+	var buf, console bytes.Buffer
+	logger := slog.New(NewHandler(&Options{
+		Writer:        &buf,
+		Format:        FormatJSON,
+		Level:         slog.LevelInfo,
+		ConsoleLevel:  slog.LevelWarn,
+		ConsoleWriter: &console,
+	}))
+
+	ctx := WithDebugRing(context.Background(), 2)
+	for _, msg := range []string{"d1", "d2", "d3"} {
+		logger.DebugContext(ctx, msg)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("expected the debug records held, got: %s", buf.String())
+	}
+	if err := FlushDebug(ctx); err != nil {
+		t.Fatalf("FlushDebug failed: %v", err)
+	}
+
+	out := buf.String()
+	if strings.Contains(out, `"msg":"d1"`) || !strings.Contains(out, `"msg":"d2"`) || !strings.Contains(out, `"msg":"d3"`) {
+		t.Errorf("expected the latest 2 records dumped, got: %s", out)
+	}
+	if console.Len() != 0 {
+		t.Errorf("expected dumps to skip the console mirror, got: %s", console.String())
+	}
+	if err := FlushDebug(context.Background()); err != nil {
+		t.Errorf("expected FlushDebug without a ring to do nothing, got %v", err)
+	}
+}
+
+func TestDebugRing_Order(t *testing.T) {
+	mem := NewMemoryHandler(nil)
+	ring := &debugRing{size: 3}
+	for _, msg := range []string{"d1", "d2", "d3", "d4", "d5"} {
+		ring.add(mem, context.Background(), slog.NewRecord(time.Time{}, slog.LevelDebug, msg, 0))
+	}
+	if err := ring.flush(); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+	if got, want := messages(mem), []string{"d3", "d4", "d5"}; !slices.Equal(got, want) {
+		t.Errorf("expected the latest records oldest first %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,135 @@
+package glog
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// repeatedKey is the attribute carrying how many times a collapsed record was logged.
+const repeatedKey = "repeated"
+
+// dedupKey identifies records that are considered duplicates of each other.
+type dedupKey struct {
+	level   slog.Level
+	message string
+	attrs   string // values of the DedupKeys attributes, in order; empty without DedupKeys
+}
+
+// dedupState collapses consecutive records with the same level, message and DedupKeys values logged
+// within a window.
+// The first record of a streak is held; it is written with a repeated=N attribute (when N > 1)
+// once a different record arrives, the window elapses, or flush is called.
+type dedupState struct {
+	mu      sync.Mutex
+	window  time.Duration
+	keys    []string        // DedupKeys
+	pending *bufferedRecord // first record of the current streak; nil when idle
+	key     dedupKey
+	start   time.Time
+	count   int
+	gen     uint64 // incremented per streak so a stale timer does not end a newer streak
+	timer   *time.Timer
+	dropped *atomic.Int64 // counts records collapsed into a streak
+}
+
+func newDedupState(window time.Duration, keys []string, dropped *atomic.Int64) *dedupState {
+	return &dedupState{window: window, keys: keys, dropped: dropped}
+}
+
+// keyOf returns the dedupKey of r.
+func (d *dedupState) keyOf(r slog.Record) dedupKey {
+	key := dedupKey{level: r.Level, message: r.Message}
+	if len(d.keys) == 0 {
+		return key
+	}
+	values := make([]string, len(d.keys))
+	found := make([]bool, len(d.keys))
+	r.Attrs(func(a slog.Attr) bool {
+		for i, k := range d.keys {
+			if a.Key == k {
+				values[i], found[i] = a.Value.Resolve().String(), true
+			}
+		}
+		return true
+	})
+	var b strings.Builder
+	for i, v := range values {
+		// a missing attribute differs from one with an empty value
+		if found[i] {
+			b.WriteByte('=')
+		}
+		b.WriteString(v)
+		b.WriteByte(0)
+	}
+	key.attrs = b.String()
+	return key
+}
+
+// handle either counts r into the current streak or ends the streak and starts a new one with r.
+func (d *dedupState) handle(ctx context.Context, handler slog.Handler, r slog.Record) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	key := d.keyOf(r)
+	now := time.Now()
+	if d.pending != nil && key == d.key && now.Sub(d.start) < d.window {
+		d.count++
+		d.dropped.Add(1)
+		return nil
+	}
+
+	err := d.emitLocked()
+
+	d.gen++
+	gen := d.gen
+	d.pending = &bufferedRecord{handler: handler, ctx: ctx, record: r.Clone()}
+	d.key = key
+	d.start = now
+	d.count = 1
+	d.timer = time.AfterFunc(d.window, func() { d.expire(gen) })
+	return err
+}
+
+// expire ends the streak started as generation gen once its window has elapsed.
+func (d *dedupState) expire(gen uint64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if gen != d.gen {
+		return
+	}
+	_ = d.emitLocked() // no caller to report to
+}
+
+// flush writes the held record, if any.
+func (d *dedupState) flush() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.emitLocked()
+}
+
+// emitLocked writes the held record with its repeat count and resets the streak. Caller must hold d.mu.
+// Writing under the lock keeps collapsed records in the order their streaks started.
+func (d *dedupState) emitLocked() error {
+	if d.pending == nil {
+		return nil
+	}
+	if d.timer != nil {
+		d.timer.Stop()
+		d.timer = nil
+	}
+
+	p := d.pending
+	r := p.record
+	if d.count > 1 {
+		r.AddAttrs(slog.Int(repeatedKey, d.count))
+	}
+	d.pending = nil
+	d.count = 0
+	return p.handler.Handle(p.ctx, r)
+}
@@ -0,0 +1,137 @@
+package glog
+
+import (
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandler_DedupWindow_CollapsesRepeats(t *testing.T) {
+	var buf syncBuffer
+
+	handler := NewHandler(&Options{
+		Writer:      &buf,
+		Format:      FormatJSON,
+		DedupWindow: time.Minute,
+	})
+	logger := slog.New(handler)
+
+	for i := 0; i < 50; i++ {
+		logger.Warn("retrying connection")
+	}
+	if got := buf.lines(); len(got) != 0 {
+		t.Fatalf("expected streak to be held, got %v", got)
+	}
+
+	if err := handler.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	lines := buf.lines()
+	if len(lines) != 1 {
+		t.Fatalf("expected a single collapsed record, got %d: %v", len(lines), lines)
+	}
+	var entry map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	if entry["msg"] != "retrying connection" {
+		t.Errorf("unexpected msg: %v", entry["msg"])
+	}
+	if entry["repeated"] != float64(50) {
+		t.Errorf("expected repeated=50, got %v", entry["repeated"])
+	}
+}
+
+func TestHandler_DedupWindow_StreakEnds(t *testing.T) {
+	var buf syncBuffer
+
+	handler := NewHandler(&Options{
+		Writer:      &buf,
+		Format:      FormatLine,
+		DedupWindow: time.Minute,
+	})
+	logger := slog.New(handler)
+
+	logger.Info("a")
+	logger.Info("a")
+	logger.Info("a")
+	logger.Error("a") // different level ends the streak
+	logger.Info("b")
+	handler.Close()
+
+	lines := buf.lines()
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 records, got %d: %v", len(lines), lines)
+	}
+	if !strings.Contains(lines[0], `INFO: a {"repeated":3}`) {
+		t.Errorf("expected collapsed INFO a with repeated=3, got %q", lines[0])
+	}
+	if !strings.HasSuffix(lines[1], "ERROR: a") {
+		t.Errorf("expected single ERROR a without count, got %q", lines[1])
+	}
+	if !strings.HasSuffix(lines[2], "INFO: b") {
+		t.Errorf("expected INFO b, got %q", lines[2])
+	}
+}
+
+func TestHandler_DedupWindow_Elapses(t *testing.T) {
+	var buf syncBuffer
+
+	handler := NewHandler(&Options{
+		Writer:      &buf,
+		Format:      FormatLine,
+		DedupWindow: 50 * time.Millisecond,
+	})
+	defer handler.Close()
+	logger := slog.New(handler)
+
+	logger.Info("tick")
+	logger.Info("tick")
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(buf.lines()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	lines := buf.lines()
+	if len(lines) != 1 || !strings.Contains(lines[0], `INFO: tick {"repeated":2}`) {
+		t.Fatalf("expected collapsed record after window elapsed, got %v", lines)
+	}
+}
+
+func TestHandler_DedupKeys(t *testing.T) {
+	var buf syncBuffer
+
+	handler := NewHandler(&Options{
+		Writer:      &buf,
+		Format:      FormatLine,
+		DedupWindow: time.Minute,
+		DedupKeys:   []string{"user"},
+	})
+	logger := slog.New(handler)
+
+	logger.Info("denied", "user", "u-1", "attempt", 1)
+	logger.Info("denied", "user", "u-1", "attempt", 2) // other attributes are not compared
+	logger.Info("denied", "user", "u-2")
+	logger.Info("denied")
+	logger.Info("denied", "user", "")
+	handler.Close()
+
+	lines := buf.lines()
+	want := []string{
+		`INFO: denied {"user":"u-1","attempt":1,"repeated":2}`,
+		`INFO: denied {"user":"u-2"}`,
+		`INFO: denied`,
+		`INFO: denied {"user":""}`,
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("expected %d records, got %d: %v", len(want), len(lines), lines)
+	}
+	for i := range want {
+		if !strings.HasSuffix(lines[i], want[i]) {
+			t.Errorf("record %d: expected %q, got %q", i, want[i], lines[i])
+		}
+	}
+}
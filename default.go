@@ -0,0 +1,31 @@
+package glog
+
+import (
+	"log"
+	"log/slog"
+	"sync"
+)
+
+// PushDefault makes a logger with a new Handler built from opts the slog default, and returns a func
+// that restores the previous default (including the standard log package's output and flags, which
+// slog.SetDefault redirects) and closes the handler. Calls may be nested as long as each restore runs
+// in reverse order, which deferring them guarantees:
+//
+//	defer glog.PushDefault(&glog.Options{Writer: &buf})()
+//
+// Calling the restore func more than once has no further effect.
+func PushDefault(opts *Options) (restore func()) {
+	prev, prevOutput, prevFlags := slog.Default(), log.Writer(), log.Flags()
+	handler := NewHandler(opts)
+	slog.SetDefault(slog.New(handler))
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			slog.SetDefault(prev)
+			log.SetOutput(prevOutput)
+			log.SetFlags(prevFlags)
+			_ = handler.Close()
+		})
+	}
+}
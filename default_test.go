@@ -0,0 +1,54 @@
+package glog
+
+import (
+	"bytes"
+	"log"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestPushDefault_Nested(t *testing.T) {
+	original, originalOutput := slog.Default(), log.Writer()
+
+	var outer, inner bytes.Buffer
+	restoreOuter := PushDefault(&Options{Writer: &outer, Level: slog.LevelInfo})
+	outerLogger := slog.Default()
+	slog.Info("outer 1")
+
+	restoreInner := PushDefault(&Options{Writer: &inner, Level: slog.LevelInfo})
+	slog.Info("inner")
+	log.Print("from log")
+	restoreInner()
+	restoreInner() // a second call must not restore again
+
+	if slog.Default() != outerLogger {
+		t.Error("expected the outer default after the inner restore")
+	}
+	slog.Info("outer 2")
+	restoreOuter()
+
+	if slog.Default() != original {
+		t.Error("expected the original default after the outer restore")
+	}
+	if log.Writer() != originalOutput {
+		t.Error("expected the original log output after the outer restore")
+	}
+	for _, tc := range []struct {
+		buf  *bytes.Buffer
+		want []string
+	}{
+		{&outer, []string{"INFO: outer 1", "INFO: outer 2"}},
+		{&inner, []string{"INFO: inner", "INFO: from log"}},
+	} {
+		lines := strings.Split(strings.TrimSpace(tc.buf.String()), "\n")
+		if len(lines) != len(tc.want) {
+			t.Fatalf("expected %d lines, got %d: %s", len(tc.want), len(lines), tc.buf.String())
+		}
+		for i, want := range tc.want {
+			if !strings.Contains(lines[i], want) {
+				t.Errorf("expected line %d to contain %q, got: %s", i, want, lines[i])
+			}
+		}
+	}
+}
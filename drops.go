@@ -0,0 +1,49 @@
+package glog
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// dropSummaryMessage is the message of the record LogDropSummary writes on Close.
+const dropSummaryMessage = "glog drop summary"
+
+// dropCounters counts the records each subsystem kept from being written. It is shared by a
+// Handler and the handlers derived from it.
+type dropCounters struct {
+	dedup        atomic.Int64 // repeats collapsed into the first record of their streak
+	emptyMessage atomic.Int64 // empty-message records dropped by RequireMessage
+	alert        atomic.Int64 // repeats suppressed by an AlertCooldown
+	sample       atomic.Int64 // records dropped by the Sampler
+}
+
+// summary returns the total and per-subsystem counts as attributes.
+func (c *dropCounters) summary() []slog.Attr {
+	bySubsystem := []slog.Attr{
+		slog.Int64("dedup", c.dedup.Load()),
+		slog.Int64("empty_message", c.emptyMessage.Load()),
+		slog.Int64("alert", c.alert.Load()),
+		slog.Int64("sample", c.sample.Load()),
+	}
+	var total int64
+	for _, a := range bySubsystem {
+		total += a.Value.Int64()
+	}
+	return []slog.Attr{
+		slog.Int64("dropped_total", total),
+		slog.Attr{Key: "dropped_by", Value: slog.GroupValue(bySubsystem...)},
+	}
+}
+
+// writeDropSummary writes an info record with the drop counters, if info records are enabled.
+func (h *Handler) writeDropSummary() error {
+	ctx := context.Background()
+	if !h.handler.Enabled(ctx, slog.LevelInfo) {
+		return nil
+	}
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, dropSummaryMessage, 0)
+	r.AddAttrs(h.drops.summary()...)
+	return h.handler.Handle(ctx, r)
+}
@@ -0,0 +1,65 @@
+package glog
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandler_LogDropSummary(t *testing.T) {
+	var buf bytes.Buffer
+
+	handler := NewHandler(&Options{
+		Writer:         &buf,
+		Format:         FormatJSON,
+		Level:          slog.LevelInfo,
+		DedupWindow:    time.Minute,
+		RequireMessage: true,
+		LogDropSummary: true,
+	})
+	logger := slog.New(handler)
+
+	for range 4 {
+		logger.Info("retrying") // 3 collapsed into the first
+	}
+	logger.Info("")
+	logger.Info("")
+	logger.Info("done")
+
+	if err := handler.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	last := lines[len(lines)-1]
+	var summary struct {
+		Msg       string           `json:"msg"`
+		Total     int64            `json:"dropped_total"`
+		DroppedBy map[string]int64 `json:"dropped_by"`
+	}
+	if err := json.Unmarshal([]byte(last), &summary); err != nil {
+		t.Fatalf("failed to parse JSON: %v, output: %s", err, last)
+	}
+	if summary.Msg != dropSummaryMessage {
+		t.Fatalf("expected the summary as the last record, got: %s", last)
+	}
+	if summary.Total != 5 || summary.DroppedBy["dedup"] != 3 || summary.DroppedBy["empty_message"] != 2 {
+		t.Errorf("unexpected drop counts: %+v", summary)
+	}
+}
+
+func TestHandler_NoDropSummaryByDefault(t *testing.T) {
+	var buf bytes.Buffer
+
+	handler := NewHandler(&Options{Writer: &buf, Level: slog.LevelInfo, RequireMessage: true})
+	slog.New(handler).Info("")
+	if err := handler.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected no output, got: %s", buf.String())
+	}
+}
@@ -0,0 +1,88 @@
+package glog
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+)
+
+const defaultDualDelimiter = "\t"
+
+// dualOutput is the state shared by a dualHandler and the handlers derived from it: the buffers the
+// two encoders write into and the writer the combined line goes to.
+type dualOutput struct {
+	mu        sync.Mutex // guards the buffers and orders combined writes
+	w         io.Writer
+	delimiter string
+	lineBuf   bytes.Buffer
+	jsonBuf   bytes.Buffer
+}
+
+// dualHandler writes each record once in line format and once as JSON on the same physical line:
+// [time] LEVEL: message {...}<delimiter>{"time":...}
+type dualHandler struct {
+	line slog.Handler
+	json slog.Handler
+	out  *dualOutput
+}
+
+// newDualHandler creates a dualHandler writing to w; an empty delimiter means a tab.
+func newDualHandler(w io.Writer, delimiter string, handlerOpts *slog.HandlerOptions, lineOpts *LineHandlerOptions) *dualHandler {
+	if delimiter == "" {
+		delimiter = defaultDualDelimiter
+	}
+	out := &dualOutput{w: w, delimiter: delimiter}
+	return &dualHandler{
+		line: NewLineHandlerWithOptions(&out.lineBuf, lineOpts),
+		json: slog.NewJSONHandler(&out.jsonBuf, handlerOpts),
+		out:  out,
+	}
+}
+
+// Enabled reports whether the line encoder is enabled; both encoders share the level.
+func (h *dualHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.line.Enabled(ctx, level)
+}
+
+// Handle encodes r with both encoders and writes the two representations as one line.
+func (h *dualHandler) Handle(ctx context.Context, r slog.Record) error {
+	out := h.out
+	out.mu.Lock()
+	defer out.mu.Unlock()
+
+	out.lineBuf.Reset()
+	out.jsonBuf.Reset()
+	if err := h.line.Handle(ctx, r); err != nil {
+		return err
+	}
+	if err := h.json.Handle(ctx, r); err != nil {
+		return err
+	}
+
+	b := make([]byte, 0, out.lineBuf.Len()+len(out.delimiter)+out.jsonBuf.Len())
+	b = append(b, bytes.TrimSuffix(out.lineBuf.Bytes(), []byte{'\n'})...)
+	b = append(b, out.delimiter...)
+	b = append(b, out.jsonBuf.Bytes()...)
+	_, err := out.w.Write(b)
+	return err
+}
+
+// WithAttrs returns a new dualHandler with attrs added to both encoders.
+func (h *dualHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &dualHandler{
+		line: h.line.WithAttrs(attrs),
+		json: h.json.WithAttrs(attrs),
+		out:  h.out,
+	}
+}
+
+// WithGroup returns a new dualHandler with the group opened in both encoders.
+func (h *dualHandler) WithGroup(name string) slog.Handler {
+	return &dualHandler{
+		line: h.line.WithGroup(name),
+		json: h.json.WithGroup(name),
+		out:  h.out,
+	}
+}
@@ -0,0 +1,57 @@
+package glog
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestHandler_FormatDual(t *testing.T) {
+	var buf bytes.Buffer
+
+	handler := NewHandler(&Options{
+		Writer:        &buf,
+		Format:        FormatDual,
+		Level:         slog.LevelInfo,
+		DualDelimiter: " | ",
+	})
+	defer handler.Close()
+	logger := slog.New(handler).With(slog.String("app", "demo"))
+
+	logger.Info("first", slog.Int("n", 1))
+	logger.WithGroup("req").Warn("second", slog.String("id", "r1"))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d: %s", len(lines), buf.String())
+	}
+	wantLines := []string{`INFO: first {"app":"demo","n":1}`, `WARN: second {"app":"demo","req.id":"r1"}`}
+	for i, line := range lines {
+		human, machine, ok := strings.Cut(line, " | ")
+		if !ok {
+			t.Fatalf("line %d: expected delimiter, got: %s", i, line)
+		}
+		if !strings.Contains(human, wantLines[i]) {
+			t.Errorf("line %d: expected %q in line part, got: %s", i, wantLines[i], human)
+		}
+		var logEntry map[string]interface{}
+		if err := json.Unmarshal([]byte(machine), &logEntry); err != nil {
+			t.Fatalf("line %d: expected JSON part: %v, got: %s", i, err, machine)
+		}
+		if logEntry["app"] != "demo" {
+			t.Errorf("line %d: expected app in JSON part, got: %v", i, logEntry)
+		}
+	}
+}
+
+func TestHandler_FormatDual_DefaultDelimiter(t *testing.T) {
+	var buf bytes.Buffer
+
+	slog.New(NewHandler(&Options{Writer: &buf, Format: FormatDual})).Info("tabbed")
+
+	if !strings.Contains(buf.String(), "INFO: tabbed\t{") {
+		t.Fatalf("expected tab between representations, got: %q", buf.String())
+	}
+}
@@ -0,0 +1,77 @@
+package glog
+
+import (
+	"log/slog"
+	"strconv"
+)
+
+// DuplicateKeyPolicy is what Handle does with attributes of one record that share a key.
+type DuplicateKeyPolicy int
+
+const (
+	// DuplicateKeysAllow writes every attribute, as slog does, so JSON output can repeat a key.
+	DuplicateKeysAllow DuplicateKeyPolicy = iota
+	// DuplicateKeysKeepLast keeps only the last attribute with each key, where it appears.
+	DuplicateKeysKeepLast
+	// DuplicateKeysKeepFirst keeps only the first attribute with each key.
+	DuplicateKeysKeepFirst
+	// DuplicateKeysRename keeps every attribute and renames repeats "<key>_2", "<key>_3", and so on,
+	// skipping names already in use.
+	DuplicateKeysRename
+)
+
+// dedupAttrs returns r with its top-level attributes made unique by key according to policy, or r
+// itself when no key repeats.
+func dedupAttrs(r slog.Record, policy DuplicateKeyPolicy) slog.Record {
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	count := make(map[string]int, r.NumAttrs())
+	duplicates := false
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		count[a.Key]++
+		duplicates = duplicates || count[a.Key] > 1
+		return true
+	})
+	if !duplicates {
+		return r
+	}
+
+	kept := attrs[:0:0]
+	switch policy {
+	case DuplicateKeysKeepLast:
+		for _, a := range attrs {
+			if count[a.Key]--; count[a.Key] == 0 {
+				kept = append(kept, a)
+			}
+		}
+	case DuplicateKeysKeepFirst:
+		for _, a := range attrs {
+			if count[a.Key] > 0 {
+				kept = append(kept, a)
+				count[a.Key] = 0
+			}
+		}
+	case DuplicateKeysRename:
+		seen := make(map[string]int, len(attrs))
+		for _, a := range attrs {
+			seen[a.Key]++
+			if n := seen[a.Key]; n > 1 {
+				key := a.Key + "_" + strconv.Itoa(n)
+				for count[key] > 0 || seen[key] > 0 {
+					n++
+					key = a.Key + "_" + strconv.Itoa(n)
+				}
+				seen[a.Key] = n
+				seen[key]++
+				a.Key = key
+			}
+			kept = append(kept, a)
+		}
+	default:
+		return r
+	}
+
+	out := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	out.AddAttrs(kept...)
+	return out
+}
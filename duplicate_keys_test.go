@@ -0,0 +1,42 @@
+package glog
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestHandler_DuplicateKeys(t *testing.T) {
+	cases := map[DuplicateKeyPolicy]string{
+		DuplicateKeysAllow:     `"k":1,"other":"o","k":2,"k":3,"k_2":"x"}`,
+		DuplicateKeysKeepLast:  `"other":"o","k":3,"k_2":"x"}`,
+		DuplicateKeysKeepFirst: `"k":1,"other":"o","k_2":"x"}`,
+		DuplicateKeysRename:    `"k":1,"other":"o","k_3":2,"k_4":3,"k_2":"x"}`,
+	}
+	for policy, want := range cases {
+		var buf bytes.Buffer
+		logger := slog.New(NewHandler(&Options{Writer: &buf, Format: FormatJSON, Level: slog.LevelInfo, DuplicateKeys: policy}))
+		logger.Info("m", "k", 1, "other", "o", "k", 2, "k", 3, "k_2", "x")
+
+		if out := strings.TrimSpace(buf.String()); !strings.HasSuffix(out, `"msg":"m",`+want) {
+			t.Errorf("policy %d: expected attributes %s, got: %s", policy, want, out)
+		}
+	}
+}
+
+func TestHandler_DuplicateKeysInjected(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&Options{
+		Writer:          &buf,
+		Format:          FormatJSON,
+		Level:           slog.LevelInfo,
+		IncludeRawLevel: true,
+		DuplicateKeys:   DuplicateKeysRename,
+	}))
+	logger.Info("m", "level_raw", "mine")
+
+	if !strings.Contains(buf.String(), `"level_raw":"mine","level_raw_2":"INFO"`) {
+		t.Errorf("expected the injected field renamed, got: %s", buf.String())
+	}
+}
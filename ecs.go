@@ -0,0 +1,39 @@
+package glog
+
+import "log/slog"
+
+// Elastic Common Schema field names used by Options.ECS.
+const (
+	ecsTimestampKey    = "@timestamp"
+	ecsLevelKey        = "log.level"
+	ecsMessageKey      = "message"
+	ecsErrorMessageKey = "error.message"
+	ecsTraceIDKey      = "trace.id"
+	ecsSpanIDKey       = "span.id"
+	ecsStackTraceKey   = "error.stack_trace"
+)
+
+// ecsReplaceAttr renames slog's built-in keys to their ECS equivalents and writes a top-level
+// error logged under the conventional "err" or "error" key as error.message. Errors under other
+// keys keep them, so several errors in one record do not collide.
+func ecsReplaceAttr(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) != 0 {
+		return a
+	}
+	switch a.Key {
+	case slog.TimeKey:
+		a.Key = ecsTimestampKey
+		return a
+	case slog.LevelKey:
+		return slog.String(ecsLevelKey, a.Value.String())
+	case slog.MessageKey:
+		a.Key = ecsMessageKey
+		return a
+	}
+	if a.Key == "err" || a.Key == "error" {
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(ecsErrorMessageKey, err.Error())
+		}
+	}
+	return a
+}
@@ -0,0 +1,66 @@
+package glog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandler_ECS(t *testing.T) {
+	var buf bytes.Buffer
+
+	handler := NewHandler(&Options{
+		Writer:         &buf,
+		Format:         FormatJSON,
+		Level:          slog.LevelInfo,
+		ECS:            true,
+		TraceExtractor: DefaultTraceExtractor,
+	})
+	ctx := SetSpanID(SetTraceID(context.Background(), "t-1"), "s-1")
+	slog.New(handler).ErrorContext(ctx, "request failed", slog.Any("err", errors.New("timeout")), slog.Int("status", 504),
+		slog.Any("cause", errors.New("dns failure")))
+
+	var logEntry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &logEntry); err != nil {
+		t.Fatalf("failed to parse JSON: %v, output: %s", err, buf.String())
+	}
+	want := map[string]interface{}{
+		"log.level":     "ERROR",
+		"message":       "request failed",
+		"error.message": "timeout",
+		"trace.id":      "t-1",
+		"span.id":       "s-1",
+		"status":        float64(504),
+		"cause":         "dns failure",
+	}
+	for k, v := range want {
+		if logEntry[k] != v {
+			t.Errorf("expected %s=%v, got %v", k, v, logEntry[k])
+		}
+	}
+	for _, k := range []string{"time", "level", "msg", "err", "trace_id"} {
+		if _, ok := logEntry[k]; ok {
+			t.Errorf("expected %s renamed, got: %s", k, buf.String())
+		}
+	}
+	ts, _ := logEntry["@timestamp"].(string)
+	if _, err := time.Parse(time.RFC3339, ts); err != nil {
+		t.Errorf("expected RFC 3339 @timestamp, got %q", ts)
+	}
+}
+
+func TestHandler_ECSText(t *testing.T) {
+	var buf bytes.Buffer
+
+	handler := NewHandler(&Options{Writer: &buf, Format: FormatText, Level: slog.LevelInfo, ECS: true})
+	slog.New(handler).Warn("slow")
+	out := buf.String()
+	if !strings.Contains(out, "log.level=WARN message=slow") || !strings.Contains(out, "@timestamp=") {
+		t.Errorf("expected ECS keys in text output, got: %s", out)
+	}
+}
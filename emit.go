@@ -0,0 +1,59 @@
+package glog
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+)
+
+// emitCapture is the writer the encoders of a Handler with Emit write into. It records what one
+// record produces and forwards it to the real writer.
+type emitCapture struct {
+	mu  sync.Mutex // held for the whole encoding of one record
+	w   io.Writer
+	buf bytes.Buffer
+}
+
+func (c *emitCapture) Write(p []byte) (int, error) {
+	c.buf.Write(p)
+	return c.w.Write(p)
+}
+
+// emitHandler encodes each record through next and passes the resulting bytes to emit.
+type emitHandler struct {
+	next    slog.Handler
+	capture *emitCapture // shared with derived handlers
+	emit    func(ctx context.Context, formatted []byte, r slog.Record)
+}
+
+// Enabled reports whether next is enabled.
+func (h *emitHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle encodes r and then calls emit with a copy of the formatted bytes.
+func (h *emitHandler) Handle(ctx context.Context, r slog.Record) error {
+	c := h.capture
+	c.mu.Lock()
+	c.buf.Reset()
+	err := h.next.Handle(ctx, r)
+	formatted := bytes.Clone(c.buf.Bytes())
+	c.mu.Unlock()
+
+	if len(formatted) > 0 {
+		h.emit(ctx, formatted, r)
+	}
+	return err
+}
+
+// WithAttrs returns a new emitHandler sharing this handler's capture.
+func (h *emitHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &emitHandler{next: h.next.WithAttrs(attrs), capture: h.capture, emit: h.emit}
+}
+
+// WithGroup returns a new emitHandler sharing this handler's capture.
+func (h *emitHandler) WithGroup(name string) slog.Handler {
+	return &emitHandler{next: h.next.WithGroup(name), capture: h.capture, emit: h.emit}
+}
@@ -0,0 +1,64 @@
+package glog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestHandler_Emit(t *testing.T) {
+	var formatted []string
+	var records []slog.Record
+	emit := func(ctx context.Context, b []byte, r slog.Record) {
+		formatted = append(formatted, string(b))
+		records = append(records, r)
+	}
+
+	handler := NewHandler(&Options{
+		Format:         FormatLine,
+		Level:          slog.LevelInfo,
+		TraceExtractor: DefaultTraceExtractor,
+		Emit:           emit,
+	})
+	defer handler.Close()
+
+	ctx := SetTraceID(context.Background(), "trace-1")
+	slog.New(handler).With(slog.String("app", "demo")).InfoContext(ctx, "emitted", slog.Int("n", 1))
+
+	if len(formatted) != 1 {
+		t.Fatalf("expected one emitted record, got %d", len(formatted))
+	}
+	if !strings.HasSuffix(formatted[0], `INFO: emitted {"app":"demo","n":1,"trace_id":"trace-1"}`+"\n") {
+		t.Errorf("expected the formatted line, got: %q", formatted[0])
+	}
+
+	attrs := map[string]string{}
+	records[0].Attrs(func(a slog.Attr) bool {
+		attrs[a.Key] = a.Value.String()
+		return true
+	})
+	if attrs["n"] != "1" || attrs["trace_id"] != "trace-1" {
+		t.Errorf("expected enriched record attrs, got %v", attrs)
+	}
+}
+
+func TestHandler_EmitWithWriter(t *testing.T) {
+	var buf bytes.Buffer
+	var emitted []byte
+
+	handler := NewHandler(&Options{
+		Writer: &buf,
+		Format: FormatJSON,
+		Level:  slog.LevelInfo,
+		Emit: func(_ context.Context, b []byte, _ slog.Record) {
+			emitted = append(emitted, b...)
+		},
+	})
+	slog.New(handler).Info("both")
+
+	if buf.String() == "" || buf.String() != string(emitted) {
+		t.Fatalf("expected the same bytes written and emitted, got %q and %q", buf.String(), emitted)
+	}
+}
@@ -0,0 +1,40 @@
+package glog
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// envelopeWriter nests each JSON record written to it under a key: the line {...} is written to w
+// as {"<key>":{...}}. It expects one complete record per Write, as slog's JSON handler writes them.
+type envelopeWriter struct {
+	w      io.Writer
+	prefix []byte // {"<key>":
+
+	mu  sync.Mutex // guards buf
+	buf []byte
+}
+
+// newEnvelopeWriter returns an envelopeWriter nesting records under key.
+func newEnvelopeWriter(w io.Writer, key string) *envelopeWriter {
+	k, _ := json.Marshal(key) // marshaling a string cannot fail
+	prefix := append([]byte{'{'}, k...)
+	return &envelopeWriter{w: w, prefix: append(prefix, ':')}
+}
+
+// Write writes p, wrapped, to w in a single call. It reports len(p) when the wrapped line was written.
+func (e *envelopeWriter) Write(p []byte) (int, error) {
+	record := bytes.TrimSuffix(p, []byte{'\n'})
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.buf = append(e.buf[:0], e.prefix...)
+	e.buf = append(e.buf, record...)
+	e.buf = append(e.buf, '}', '\n')
+	if _, err := e.w.Write(e.buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,52 @@
+package glog
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestHandler_JSONEnvelope(t *testing.T) {
+	var buf bytes.Buffer
+	handler := NewHandler(&Options{Writer: &buf, Format: FormatJSON, Level: slog.LevelInfo, JSONEnvelope: "log"})
+
+	logger := slog.New(handler)
+	logger.Info("first", "user", "u-1")
+	logger.WithGroup("req").Warn("second", "status", 500)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d: %s", len(lines), buf.String())
+	}
+	for i, want := range []map[string]any{
+		{"level": "INFO", "msg": "first", "user": "u-1"},
+		{"level": "WARN", "msg": "second", "req": map[string]any{"status": float64(500)}},
+	} {
+		var entry map[string]map[string]any
+		if err := json.Unmarshal([]byte(lines[i]), &entry); err != nil {
+			t.Fatalf("failed to parse %q: %v", lines[i], err)
+		}
+		if len(entry) != 1 || entry["log"] == nil {
+			t.Fatalf("expected the record nested under log, got: %s", lines[i])
+		}
+		for k, v := range want {
+			got, _ := json.Marshal(entry["log"][k])
+			exp, _ := json.Marshal(v)
+			if !bytes.Equal(got, exp) {
+				t.Errorf("line %d: expected %s=%s, got %s", i, k, exp, got)
+			}
+		}
+	}
+}
+
+func TestHandler_JSONEnvelope_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	handler := NewHandler(&Options{Writer: &buf, Format: FormatJSON, Level: slog.LevelInfo})
+	slog.New(handler).Info("plain")
+
+	if !strings.HasPrefix(buf.String(), `{"time":`) {
+		t.Errorf("expected an unwrapped record, got: %s", buf.String())
+	}
+}
@@ -0,0 +1,28 @@
+//go:build !windows
+
+package glog
+
+import "log/slog"
+
+// EventLogWriter is a no-op on non-Windows platforms so code using it still compiles everywhere.
+type EventLogWriter struct{}
+
+// NewEventLogWriter returns a writer that discards everything on non-Windows platforms.
+func NewEventLogWriter(source string) (*EventLogWriter, error) {
+	return &EventLogWriter{}, nil
+}
+
+// Write discards p.
+func (w *EventLogWriter) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+// WriteLevel discards p.
+func (w *EventLogWriter) WriteLevel(level slog.Level, p []byte) (int, error) {
+	return len(p), nil
+}
+
+// Close does nothing.
+func (w *EventLogWriter) Close() error {
+	return nil
+}
@@ -0,0 +1,59 @@
+//go:build windows
+
+package glog
+
+import (
+	"log/slog"
+	"strings"
+
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+// defaultEventID is the event ID reported for every record.
+const defaultEventID = 1
+
+// EventLogWriter writes each formatted record to the Windows Event Log. Use it as Options.Writer: the
+// Handler passes each record's level (see LevelWriter), so error records are reported as Error events,
+// warnings as Warning events, everything else as Information.
+type EventLogWriter struct {
+	log *eventlog.Log
+}
+
+// NewEventLogWriter opens the event log for the given source. The source must be registered
+// (e.g. with eventlog.InstallAsEventCreate) before events show up under its name.
+func NewEventLogWriter(source string) (*EventLogWriter, error) {
+	l, err := eventlog.Open(source)
+	if err != nil {
+		return nil, err
+	}
+	return &EventLogWriter{log: l}, nil
+}
+
+// Write reports p as one Information event; the trailing newline is dropped.
+func (w *EventLogWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(slog.LevelInfo, p)
+}
+
+// WriteLevel reports p as one event of the type matching level; the trailing newline is dropped.
+func (w *EventLogWriter) WriteLevel(level slog.Level, p []byte) (int, error) {
+	msg := strings.TrimRight(string(p), "\r\n")
+
+	var err error
+	switch {
+	case level >= slog.LevelError:
+		err = w.log.Error(defaultEventID, msg)
+	case level >= slog.LevelWarn:
+		err = w.log.Warning(defaultEventID, msg)
+	default:
+		err = w.log.Info(defaultEventID, msg)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close closes the event log handle.
+func (w *EventLogWriter) Close() error {
+	return w.log.Close()
+}
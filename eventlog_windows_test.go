@@ -0,0 +1,43 @@
+//go:build windows
+
+package glog
+
+import (
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+func TestEventLogWriter(t *testing.T) {
+	source := fmt.Sprintf("glog-test-%d", time.Now().UnixNano())
+	if err := eventlog.InstallAsEventCreate(source, eventlog.Error|eventlog.Warning|eventlog.Info); err != nil {
+		t.Skipf("cannot register event source (requires administrator): %v", err)
+	}
+	defer eventlog.Remove(source)
+
+	w, err := NewEventLogWriter(source)
+	if err != nil {
+		t.Fatalf("NewEventLogWriter failed: %v", err)
+	}
+	defer w.Close()
+
+	logger := slog.New(NewHandler(&Options{Writer: w, Format: FormatLine}))
+	logger.Info("event log info")
+	logger.Error("event log error")
+
+	query := fmt.Sprintf("*[System[Provider[@Name='%s']]]", source)
+	out, err := exec.Command("wevtutil", "qe", "Application", "/q:"+query, "/f:text").CombinedOutput()
+	if err != nil {
+		t.Fatalf("wevtutil failed: %v: %s", err, out)
+	}
+	for _, want := range []string{"event log info", "event log error"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("expected %q in event log, got: %s", want, out)
+		}
+	}
+}
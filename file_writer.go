@@ -2,13 +2,26 @@ package glog
 
 import (
 	"bufio"
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 )
 
@@ -19,32 +32,306 @@ type FileWriter struct {
 	fileName      string
 	current       string
 	file          *os.File
+	out           io.Writer // where records are written: file, possibly wrapped (tests)
+	wrapFile      func(io.Writer) io.Writer
 	buf           *bufio.Writer
-	maxFiles      int           // max old files to keep; 0 = no limit
-	flushInterval time.Duration // flush interval in seconds; 0 = flush on every write
+	maxFiles      int                          // max old files to keep; 0 = no limit
+	flushInterval time.Duration                // flush interval in seconds; 0 = flush on every write
+	preallocate   int64                        // bytes to preallocate per opened file; 0 = none
+	size          int64                        // logical size of the current file, including buffered bytes
+	footer        bool                         // append an integrity footer to a file before rotating away from it
+	hash          hash.Hash                    // running SHA-256 of the current file's content when footer is set
+	records       int64                        // lines written to the current file when footer is set
+	minRotate     time.Duration                // minimum time between rotations; 0 = no limit
+	lastRotate    time.Time                    // when the current file was opened by a rotation
+	lastRotation  time.Time                    // when a file was last rotated away from, by time or size; zero if never
+	lastErr       error                        // most recent write, flush or cleanup error
+	recent        [recentErrorsSize]TimedError // ring of the latest errors, for RecentErrors
+	recentNext    int                          // index in recent the next error goes to
+	recentCount   int                          // number of errors in recent
+	onError       func(error)                  // OnError
+	remove        func(name string) error
+	onDiskFull    DiskFullPolicy
+	fallback      io.Writer // DiskFullFallback destination
+	rotateStep    func(stage string)
+	now           func() time.Time
+	lockFile      *os.File      // held lock file with ExclusiveLock; nil otherwise
+	lockErr       error         // ErrLogFileLocked when the lock is held elsewhere and LockFallbackError is set
+	nameSuffix    string        // inserted before the extension of every file name (per-PID fallback)
+	maxSize       int64         // rotate before a write would grow the file beyond this; 0 = no limit
+	epochMarker   bool          // write an EpochPrefix line each time a file is opened
+	compress      bool          // gzip each file once it is finished (rotation or Close)
+	flushNewline  bool          // flush the buffer through the last complete line of each write
+	renameActive  bool          // write to current+ActiveSuffix and rename it to current when done
+	sizeCheck     time.Duration // check MaxSize in the background this often; 0 = on every write
+	maxIdleCheck  time.Duration // cap of the idle rotation check backoff; 0 = no backoff
+	closeMarker   string        // line written before a file is closed; "" = none
+	coalesce      bool          // drop a file left empty by back-to-back rotations
+	fresh         bool          // with coalesce: the current file was created by the last open and has no records yet
+	active        atomic.Bool   // a write happened since the last rotation check, with maxIdleCheck
+	wake          chan struct{} // tells the rotate loop to resume frequent checks, with maxIdleCheck
+	maxBatch      int           // most writes one coordinator performs per hold of mu
+	queueMu       sync.Mutex    // guards queue and leading; never held while acquiring mu
+	queue         []*writeReq   // writes waiting for the coordinator, oldest first
+	leading       bool          // a Write is coordinating and will hand off or drain the queue
 
 	ctx    context.Context
 	cancel context.CancelFunc
 	done   chan struct{}
 }
 
+// FileWriterOptions configures a FileWriter.
+type FileWriterOptions struct {
+	// MaxFiles is the max number of old log files to keep; 0 means no limit.
+	MaxFiles int
+	// FlushInterval is the buffer flush interval in seconds; 0 means flush on every write.
+	FlushInterval int
+	// FlushOnNewline, with FlushInterval, also flushes the buffer whenever a write completes a line, so
+	// tailing sees each line promptly while a trailing partial line stays buffered.
+	FlushOnNewline bool
+	// PreallocateBytes reserves this much disk space for each newly opened file to reduce
+	// fragmentation (Linux only; ignored elsewhere). The unused tail is released on rotation and Close.
+	PreallocateBytes int64
+	// WriteFooter appends a footer line (see FooterPrefix) to each file when rotating away from it,
+	// holding the SHA-256 of the file's content before the footer and its record count.
+	WriteFooter bool
+	// MinRotateInterval is the minimum time between two rotations. A rotation due sooner (e.g. because
+	// the clock jumped) is skipped and writes keep going to the current file. 0 means no limit.
+	MinRotateInterval time.Duration
+	// MaxSize rotates the current file before a write would make it larger than this many bytes, counting
+	// data still in the buffer. The full file is renamed to "<name>.<N><ext>" (N increasing) and writing
+	// continues in a new file under the current name. A single larger write still goes to an empty file.
+	// 0 means no size limit.
+	MaxSize int64
+	// SizeCheckInterval, when positive, moves the MaxSize check off the write path: the background loop
+	// checks the size this often and rotates once it reaches MaxSize. Writes get a little cheaper, and a
+	// file may grow past MaxSize by whatever is written between two checks. 0 checks on every write.
+	SizeCheckInterval time.Duration
+	// MaxIdleCheckInterval, when positive, lets the background loop check for time rotation less often
+	// while nothing is written, to save idle wakeups: each check that finds no writes since the previous
+	// one doubles the interval, up to this cap. The first write after a quiet spell checks for rotation
+	// itself, so it still lands in the right file, and frequent checks resume. 0 keeps a fixed interval.
+	MaxIdleCheckInterval time.Duration
+	// ExclusiveLock takes an advisory lock (flock) on a sibling "<path>.lock" file, where path is the
+	// unformatted layout, so two processes cannot write the same log files. The lock file holds the owner's
+	// PID. Unix only; ignored elsewhere.
+	ExclusiveLock bool
+	// LockFallback decides what a FileWriter does when another process holds the lock.
+	LockFallback LockFallback
+	// EpochMarker writes a marker line (see EpochPrefix) with a fresh random ID as the first line each
+	// time a file is opened, including reopening an existing file on restart, so tailing collectors can
+	// tell a new writer session from a continuation.
+	EpochMarker bool
+	// CloseMarker, when set, is written as the last line of each file just before the writer closes it,
+	// on rotation and on Close, so tailers know the file is complete (a newline is added if missing).
+	// With WriteFooter, the footer still follows it. A restart appending to the same file continues
+	// after the marker.
+	CloseMarker string
+	// CoalesceRotations merges rotations that fire in quick succession (e.g. the clock and MaxSize
+	// within milliseconds of each other): while the file the writer created last has had nothing written
+	// to it, a rotation trigger reuses it instead of creating another one. A size trigger leaves it in
+	// place, and a time trigger removes it before opening the new period's file, so no empty files are
+	// left behind. Marker lines (EpochMarker) do not count as written.
+	CoalesceRotations bool
+	// MaxBatchWrites caps how many writes one batch holds the writer's lock for under concurrency. Writes
+	// arriving while another is in progress queue up; the writer holding the lock performs up to this many
+	// of them (its own included) in one hold, then hands the lock to the next queued write. Larger batches
+	// mean fewer lock handoffs and more throughput, smaller ones a lower worst-case wait per write. 0
+	// means DefaultMaxBatchWrites; 1 writes each record on its own.
+	MaxBatchWrites int
+	// OnDiskFull decides what happens when a write fails because the disk is full (ENOSPC), including
+	// the flush of buffered data. Whatever the policy cannot place is dropped: with a buffer, everything
+	// it held at that point, and a Write counts only the bytes of its data that reached the file.
+	OnDiskFull DiskFullPolicy
+	// DiskFullFallback receives the data that did not fit with DiskFullFallback.
+	DiskFullFallback io.Writer
+	// Compress gzips each file once the writer is done with it: on rotation, and on Close for the
+	// current file, so short-lived jobs that never rotate get a compressed file too. The data is appended
+	// to "<name>.gz" as a new gzip member (concatenated members are one valid gzip stream, so a restart
+	// writing the same name again extends the archive) and the uncompressed file is removed. Compression
+	// runs synchronously while the writer is locked.
+	Compress bool
+	// RenameOnRotate writes the current file under a temporary name, its final name plus ActiveSuffix,
+	// and renames it to the final name only when the writer is done with it (on rotation and on Close),
+	// so watchers of the final names never see a file still being written. A restart in the same period
+	// renames the final file back and continues it; leftover active files of earlier periods (e.g. after
+	// a crash) are renamed to their final names when the writer starts.
+	RenameOnRotate bool
+	// OnError is called with errors from work no caller can be told about: flushes by the background
+	// loop, time-based rotation, and removing old files during rotation cleanup, which keeps going past
+	// files it cannot remove and reports them joined in one error. It runs with the writer locked, so
+	// it must not use the writer.
+	OnError func(error)
+
+	now      func() time.Time          // clock; nil means time.Now (tests inject a fake one)
+	wrapFile func(io.Writer) io.Writer // wraps each opened file for writing; tests inject failures
+	// rotateStep is called at each stage of a rotation ("flush", "close", "compress", "rename", "open",
+	// "clean") before it runs; tests panic in it to simulate a crash at that point.
+	rotateStep func(stage string)
+	remove     func(name string) error // removes an old file; nil means os.Remove (tests inject failures)
+}
+
+// DiskFullPolicy is what a FileWriter does when a write fails because the disk is full.
+type DiskFullPolicy int
+
+const (
+	// DiskFullDropNew drops the data that did not fit and returns the error.
+	DiskFullDropNew DiskFullPolicy = iota
+	// DiskFullDropOldest removes old log files, oldest first, until the write fits or no old file is left.
+	DiskFullDropOldest
+	// DiskFullFallback writes the data that did not fit to FileWriterOptions.DiskFullFallback instead,
+	// e.g. os.Stderr, and returns that writer's result.
+	DiskFullFallback
+)
+
+// DefaultMaxBatchWrites is the batch cap a FileWriter uses when MaxBatchWrites is 0.
+const DefaultMaxBatchWrites = 32
+
+// writeReq is a Write queued for the coordinating writer to perform.
+type writeReq struct {
+	p    []byte
+	n    int
+	err  error
+	lead bool // set instead of performing p: the waiting Write is now the coordinator
+	done chan struct{}
+}
+
+// LockFallback is what a FileWriter with ExclusiveLock does when the lock is already held.
+type LockFallback int
+
+const (
+	// LockFallbackPID writes to per-process files instead, named with ".pid<pid>" before the extension
+	// (and before the index of size-rotated files). MaxFiles cleanup in each writer only counts and
+	// removes its own files.
+	LockFallbackPID LockFallback = iota
+	// LockFallbackError fails every write with ErrLogFileLocked.
+	LockFallbackError
+)
+
+// ErrLogFileLocked is returned by writes when ExclusiveLock is set, another process holds the
+// lock, and LockFallback is LockFallbackError.
+var ErrLogFileLocked = errors.New("glog: log file is locked by another process")
+
+// ActiveSuffix is appended to the name of the file being written with RenameOnRotate.
+const ActiveSuffix = ".active"
+
+// FooterPrefix starts the footer line a FileWriter with WriteFooter appends as the last line of a
+// rotated file:
+//
+//	#glog-footer sha256=<hex digest of all preceding bytes> records=<number of preceding lines>
+//
+// Log parsers reading rotated files should treat a final line with this prefix as metadata, not a record.
+const FooterPrefix = "#glog-footer "
+
+// EpochPrefix starts the marker line a FileWriter with EpochMarker writes each time it opens a file:
+//
+//	#glog-epoch id=<random UUID>
+//
+// Every line up to the next marker was written in the same epoch. Log parsers should treat lines with
+// this prefix as metadata, not records.
+const EpochPrefix = "#glog-epoch "
+
+// ParseEpochMarker parses a marker line written by a FileWriter with EpochMarker and returns its ID.
+// The trailing newline is optional. It reports false if line is not an epoch marker.
+func ParseEpochMarker(line string) (string, bool) {
+	rest, ok := strings.CutPrefix(strings.TrimSuffix(line, "\n"), EpochPrefix)
+	if !ok {
+		return "", false
+	}
+	id, ok := strings.CutPrefix(rest, "id=")
+	if !ok || id == "" {
+		return "", false
+	}
+	return id, true
+}
+
+// newEpochID returns a random (version 4) UUID.
+func newEpochID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:]) // crypto/rand.Read never fails
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// Footer is the integrity information of a rotated log file.
+type Footer struct {
+	SHA256  string // hex-encoded SHA-256 of the file content preceding the footer
+	Records int64  // number of lines preceding the footer
+}
+
+// ParseFooter parses a footer line written by a FileWriter with WriteFooter.
+// The trailing newline is optional. It reports false if line is not a footer.
+func ParseFooter(line string) (Footer, bool) {
+	var ft Footer
+	rest, ok := strings.CutPrefix(strings.TrimSuffix(line, "\n"), FooterPrefix)
+	if !ok {
+		return ft, false
+	}
+	if _, err := fmt.Sscanf(rest, "sha256=%s records=%d", &ft.SHA256, &ft.Records); err != nil {
+		return Footer{}, false
+	}
+	return ft, true
+}
+
 func NewFileWriter(path string, maxFiles int) *FileWriter {
 	return NewFileWriterWithFlushInterval(path, maxFiles, 0)
 }
 
 func NewFileWriterWithFlushInterval(path string, maxFiles int, flushIntervalSeconds int) *FileWriter {
+	return NewFileWriterWithOptions(path, FileWriterOptions{
+		MaxFiles:      maxFiles,
+		FlushInterval: flushIntervalSeconds,
+	})
+}
+
+func NewFileWriterWithOptions(path string, opts FileWriterOptions) *FileWriter {
+	if opts.now == nil {
+		opts.now = time.Now
+	}
+	if opts.remove == nil {
+		opts.remove = os.Remove
+	}
+	if opts.MaxBatchWrites <= 0 {
+		opts.MaxBatchWrites = DefaultMaxBatchWrites
+	}
 	ctx, cancel := context.WithCancel(context.Background())
 	fw := &FileWriter{
 		path:          path,
 		dir:           filepath.Dir(path),
 		fileName:      filepath.Base(path),
-		maxFiles:      maxFiles,
-		flushInterval: time.Duration(flushIntervalSeconds) * time.Second,
+		maxFiles:      opts.MaxFiles,
+		flushInterval: time.Duration(opts.FlushInterval) * time.Second,
+		preallocate:   opts.PreallocateBytes,
+		footer:        opts.WriteFooter,
+		minRotate:     opts.MinRotateInterval,
+		maxSize:       opts.MaxSize,
+		epochMarker:   opts.EpochMarker,
+		compress:      opts.Compress,
+		flushNewline:  opts.FlushOnNewline,
+		renameActive:  opts.RenameOnRotate,
+		sizeCheck:     opts.SizeCheckInterval,
+		maxIdleCheck:  opts.MaxIdleCheckInterval,
+		closeMarker:   opts.CloseMarker,
+		coalesce:      opts.CoalesceRotations,
+		maxBatch:      opts.MaxBatchWrites,
+		wake:          make(chan struct{}, 1),
+		onDiskFull:    opts.OnDiskFull,
+		fallback:      opts.DiskFullFallback,
+		wrapFile:      opts.wrapFile,
+		rotateStep:    opts.rotateStep,
+		onError:       opts.OnError,
+		remove:        opts.remove,
+		now:           opts.now,
 		ctx:           ctx,
 		cancel:        cancel,
 		done:          make(chan struct{}),
 	}
 
+	if opts.ExclusiveLock {
+		fw.acquireLock(opts.LockFallback)
+	}
+
 	// open initial file
 	fw.checkAndRotate()
 
@@ -54,9 +341,63 @@ func NewFileWriterWithFlushInterval(path string, maxFiles int, flushIntervalSeco
 	return fw
 }
 
-func (f *FileWriter) Write(p []byte) (n int, err error) {
+// Write writes p to the current file. Concurrent writes are batched: see MaxBatchWrites.
+func (f *FileWriter) Write(p []byte) (int, error) {
+	f.queueMu.Lock()
+	if f.leading {
+		req := &writeReq{p: p, done: make(chan struct{})}
+		f.queue = append(f.queue, req)
+		f.queueMu.Unlock()
+		<-req.done
+		if !req.lead {
+			return req.n, req.err
+		}
+	} else {
+		f.leading = true
+		f.queueMu.Unlock()
+	}
+	return f.coordinate(p)
+}
+
+// coordinate writes p and then up to maxBatch-1 queued writes in one hold of mu, and hands
+// coordination to the next queued write, if any, so no Write waits behind more than one batch
+// of others.
+func (f *FileWriter) coordinate(p []byte) (int, error) {
 	f.mu.Lock()
-	defer f.mu.Unlock()
+	n, err := f.writeOneLocked(p)
+	f.queueMu.Lock()
+	batch := f.queue[:min(len(f.queue), f.maxBatch-1)]
+	f.queue = f.queue[len(batch):]
+	f.queueMu.Unlock()
+	for _, req := range batch {
+		req.n, req.err = f.writeOneLocked(req.p)
+	}
+	f.mu.Unlock()
+	for _, req := range batch {
+		close(req.done)
+	}
+
+	f.queueMu.Lock()
+	if len(f.queue) > 0 {
+		next := f.queue[0]
+		f.queue = f.queue[1:]
+		next.lead = true
+		close(next.done)
+	} else {
+		f.queue, f.leading = nil, false
+	}
+	f.queueMu.Unlock()
+	return n, err
+}
+
+// writeOneLocked writes one record: it reopens the file if needed, rotates by size, writes p and
+// applies the OnDiskFull policy. Caller must hold f.mu.
+func (f *FileWriter) writeOneLocked(p []byte) (n int, err error) {
+	defer func() {
+		if err != nil {
+			f.recordErrLocked(err)
+		}
+	}()
 
 	// if file is not open (e.g. after Close), try to reopen current file
 	if f.file == nil {
@@ -65,28 +406,235 @@ func (f *FileWriter) Write(p []byte) (n int, err error) {
 		}
 	}
 
+	if f.maxIdleCheck > 0 && !f.active.Load() {
+		// the rotate loop may be backed off: check here and have it resume frequent checks
+		f.active.Store(true)
+		f.checkAndRotateLocked()
+		select {
+		case f.wake <- struct{}{}:
+		default:
+		}
+	}
+
+	// size counts buffered bytes too, so rotation follows the logical file size
+	if f.maxSize > 0 && f.sizeCheck == 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize {
+		if err := f.rotateBySizeLocked(); err != nil {
+			return 0, err
+		}
+	}
+
+	before := f.size
+	n, err = f.writeLocked(p)
+	if errors.Is(err, syscall.ENOSPC) && f.buf != nil {
+		// the flush that made room in the buffer could not place everything (OnDiskFull has run): drop
+		// the buffer and count only the bytes of p that reached the file
+		f.resetBufferLocked()
+		n = int(min(max(f.size-before, 0), int64(len(p))))
+	}
+	if n > 0 {
+		f.fresh = false
+	}
+	return n, err
+}
+
+// writeLocked writes p to the current file or its buffer. Caller must hold f.mu.
+func (f *FileWriter) writeLocked(p []byte) (n int, err error) {
 	// no flushInterval: write directly to file, no bufio
 	if f.flushInterval == 0 {
-		return f.file.Write(p)
+		n, err = f.writeOutLocked(p)
+		f.size += int64(n)
+		return n, err
 	}
 
 	// with flushInterval: use buffered write
 	if f.buf == nil {
-		f.buf = bufio.NewWriter(f.file)
+		f.buf = bufio.NewWriter(outWriter{f})
+	}
+	if f.flushNewline {
+		if i := bytes.LastIndexByte(p, '\n'); i >= 0 {
+			n, err = f.buf.Write(p[:i+1])
+			f.size += int64(n)
+			if err == nil {
+				err = f.buf.Flush()
+			}
+			if err != nil {
+				return n, err
+			}
+			m, err := f.buf.Write(p[i+1:])
+			f.size += int64(m)
+			return n + m, err
+		}
 	}
 	n, err = f.buf.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// outWriter is the target of a FileWriter's buffer, so flushes go through writeOutLocked too.
+type outWriter struct{ f *FileWriter }
+
+func (w outWriter) Write(p []byte) (int, error) { return w.f.writeOutLocked(p) }
+
+// writeOutLocked writes p to the file, applying the OnDiskFull policy when the disk is full. It
+// returns how much of p the file or the fallback writer took; bytes diverted to the fallback are
+// taken off f.size, which counted them for the file. Caller must hold f.mu.
+func (f *FileWriter) writeOutLocked(p []byte) (int, error) {
+	n, err := f.out.Write(p)
+	f.trackLocked(p[:n])
+	if !errors.Is(err, syscall.ENOSPC) {
+		return n, err
+	}
+	switch f.onDiskFull {
+	case DiskFullDropOldest:
+		for errors.Is(err, syscall.ENOSPC) && f.removeOldestLocked() {
+			var m int
+			m, err = f.out.Write(p[n:])
+			f.trackLocked(p[n : n+m])
+			n += m
+		}
+	case DiskFullFallback:
+		if f.fallback != nil {
+			m, ferr := f.fallback.Write(p[n:])
+			f.size -= int64(m)
+			if ferr != nil {
+				return n + m, errors.Join(err, ferr)
+			}
+			return n + m, nil
+		}
+	}
 	return n, err
 }
 
-func (f *FileWriter) Close() error {
-	// stop async rotation goroutine
+// resetBufferLocked discards the buffer after a failed flush (bufio keeps failing once a write to the
+// file failed) and resyncs the size with the file. Caller must hold f.mu.
+func (f *FileWriter) resetBufferLocked() {
+	if f.buf == nil {
+		return
+	}
+	f.buf.Reset(outWriter{f})
+	if info, err := f.file.Stat(); err == nil {
+		f.size = info.Size()
+	}
+}
+
+// trackLocked feeds written bytes into the footer hash and record count. Caller must hold f.mu.
+func (f *FileWriter) trackLocked(p []byte) {
+	if f.hash == nil {
+		return
+	}
+	f.hash.Write(p)
+	f.records += int64(bytes.Count(p, []byte{'\n'}))
+}
+
+// writeFooterLocked appends the footer line to the current file. Caller must hold f.mu and
+// have flushed the buffer.
+func (f *FileWriter) writeFooterLocked() {
+	if f.hash == nil {
+		return
+	}
+	footer := fmt.Sprintf("%ssha256=%s records=%d\n", FooterPrefix, hex.EncodeToString(f.hash.Sum(nil)), f.records)
+	if n, err := io.WriteString(f.file, footer); err == nil {
+		f.size += int64(n)
+	}
+}
+
+// writeCloseMarkerLocked writes the CloseMarker line, if set, to the current file. The buffer must be
+// flushed. Caller must hold f.mu.
+func (f *FileWriter) writeCloseMarkerLocked() {
+	if f.closeMarker == "" {
+		return
+	}
+	marker := f.closeMarker
+	if !strings.HasSuffix(marker, "\n") {
+		marker += "\n"
+	}
+	n, err := io.WriteString(f.file, marker)
+	f.size += int64(n)
+	if err == nil {
+		f.trackLocked([]byte(marker))
+	}
+}
+
+// Flush writes buffered data to the current file.
+func (f *FileWriter) Flush() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.flushLocked(); err != nil {
+		f.recordErrLocked(err)
+		return err
+	}
+	return nil
+}
+
+// flushLocked flushes the buffer, with the OnDiskFull policy. When the disk is still full after it,
+// the buffered data is dropped so later writes can succeed once there is room again. Caller must
+// hold f.mu.
+func (f *FileWriter) flushLocked() error {
+	if f.buf == nil {
+		return nil
+	}
+	err := f.buf.Flush()
+	if errors.Is(err, syscall.ENOSPC) {
+		f.resetBufferLocked()
+	}
+	return err
+}
+
+// WriterState is a point-in-time snapshot of a FileWriter, e.g. for a health check endpoint.
+type WriterState struct {
+	// CurrentFile is the path of the file being written.
+	CurrentFile string `json:"current_file"`
+	// Size is the logical size of the current file in bytes, including buffered data.
+	Size int64 `json:"size"`
+	// BufferedBytes is how much written data has not reached the file yet.
+	BufferedBytes int `json:"buffered_bytes"`
+	// LastRotation is when the writer last rotated to a new file; zero if it has not rotated.
+	LastRotation time.Time `json:"last_rotation"`
+	// LastError is the most recent write or flush error, or "" if there was none.
+	LastError string `json:"last_error,omitempty"`
+}
+
+// State returns a consistent snapshot of the writer's state.
+func (f *FileWriter) State() WriterState {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	st := WriterState{
+		CurrentFile:  f.activePath(),
+		Size:         f.size,
+		LastRotation: f.lastRotation,
+	}
+	if f.buf != nil {
+		st.BufferedBytes = f.buf.Buffered()
+	}
+	if f.lastErr != nil {
+		st.LastError = f.lastErr.Error()
+	}
+	return st
+}
+
+func (f *FileWriter) Close() (err error) {
+	// stop async rotation goroutine and wait for it to exit, so no periodic flush
+	// or rotation can run after the final flush below
 	f.cancel()
 	<-f.done
 
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
-	// flush buffer
+	if f.lockFile != nil {
+		// release the lock however closing ends, so another process can take over the files
+		defer func() {
+			_ = unlock(f.lockFile)
+			if cerr := f.lockFile.Close(); cerr != nil && err == nil {
+				err = cerr
+			}
+			f.lockFile = nil
+		}()
+	}
+
+	// final flush: everything written up to Close reaches the file
 	if f.buf != nil {
 		if err := f.buf.Flush(); err != nil {
 			return err
@@ -95,21 +643,53 @@ func (f *FileWriter) Close() error {
 	}
 
 	if f.file != nil {
+		f.writeCloseMarkerLocked()
+		f.trimLocked()
 		if err := f.file.Close(); err != nil {
 			return err
 		}
 		f.file = nil
+		if err := f.finalizeLocked(); err != nil {
+			return err
+		}
+		if f.compress {
+			if err := compressFile(f.current); err != nil {
+				return err
+			}
+		}
 	}
 	return nil
 }
 
+// acquireLock takes the ExclusiveLock lock file, or applies fallback when another process holds it.
+func (f *FileWriter) acquireLock(fallback LockFallback) {
+	lockFile, err := os.OpenFile(f.path+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err == nil {
+		if err = tryLock(lockFile); err == nil {
+			// best effort: the PID is informational, the lock is what matters
+			_ = lockFile.Truncate(0)
+			_, _ = lockFile.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+			f.lockFile = lockFile
+			return
+		}
+		lockFile.Close()
+	}
+
+	if fallback == LockFallbackError {
+		f.lockErr = ErrLogFileLocked
+		return
+	}
+	f.nameSuffix = ".pid" + strconv.Itoa(os.Getpid())
+}
+
 // rotateLoop runs the async rotation loop.
 func (f *FileWriter) rotateLoop() {
 	defer close(f.done)
 
 	checkInterval := f.getCheckInterval()
-	rotateTicker := time.NewTicker(checkInterval)
-	defer rotateTicker.Stop()
+	interval := checkInterval
+	rotateTimer := time.NewTimer(interval)
+	defer rotateTimer.Stop()
 
 	// if flush interval is set, use a ticker to flush
 	var flushTicker *time.Ticker
@@ -120,30 +700,125 @@ func (f *FileWriter) rotateLoop() {
 		flushChan = flushTicker.C
 	}
 
+	var sizeChan <-chan time.Time
+	if f.maxSize > 0 && f.sizeCheck > 0 {
+		sizeTicker := time.NewTicker(f.sizeCheck)
+		defer sizeTicker.Stop()
+		sizeChan = sizeTicker.C
+	}
+
 	for {
 		select {
 		case <-f.ctx.Done():
 			return
-		case <-rotateTicker.C:
+		case <-rotateTimer.C:
 			f.checkAndRotate()
+			interval = f.nextCheckInterval(checkInterval, interval)
+			rotateTimer.Reset(interval)
+		case <-f.wake:
+			interval = checkInterval
+			rotateTimer.Reset(interval)
 		case <-flushChan:
 			f.flushBuffer()
+		case <-sizeChan:
+			f.checkSize()
 		}
 	}
 }
 
+// nextCheckInterval returns the interval until the next rotation check after one that waited cur:
+// base while there are writes, and otherwise cur doubled, up to MaxIdleCheckInterval.
+func (f *FileWriter) nextCheckInterval(base, cur time.Duration) time.Duration {
+	if f.maxIdleCheck <= 0 || f.active.Swap(false) {
+		return base
+	}
+	return max(min(cur*2, f.maxIdleCheck), base)
+}
+
+// checkSize rotates the current file if it has reached MaxSize, for SizeCheckInterval.
+func (f *FileWriter) checkSize() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file != nil && f.size >= f.maxSize {
+		f.reportLocked(f.rotateBySizeLocked())
+	}
+}
+
 func (f *FileWriter) flushBuffer() {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
-	if f.buf != nil {
-		_ = f.buf.Flush() // ignore error; does not affect writes
+	f.reportLocked(f.flushLocked())
+}
+
+// reportLocked records err, which cannot be returned to any caller, for State and passes it to OnError.
+// Caller must hold f.mu.
+func (f *FileWriter) reportLocked(err error) {
+	if err == nil {
+		return
 	}
+	f.recordErrLocked(err)
+	if f.onError != nil {
+		f.onError(err)
+	}
+}
+
+// recentErrorsSize is how many errors RecentErrors keeps.
+const recentErrorsSize = 16
+
+// TimedError is an error a FileWriter ran into and when it happened.
+type TimedError struct {
+	Time  time.Time `json:"time"`
+	Err   error     `json:"-"`
+	Error string    `json:"error"` // Err's message, so the error survives JSON encoding
 }
 
+// recordErrLocked makes err the last error and adds it to the RecentErrors ring. Caller must hold f.mu.
+func (f *FileWriter) recordErrLocked(err error) {
+	f.lastErr = err
+	f.recent[f.recentNext] = TimedError{Time: f.now(), Err: err, Error: err.Error()}
+	f.recentNext = (f.recentNext + 1) % recentErrorsSize
+	f.recentCount = min(f.recentCount+1, recentErrorsSize)
+}
+
+// RecentErrors returns the latest write, flush, rotation and cleanup errors, oldest first; only the
+// last 16 are kept. Unlike WriterState.LastError it shows how often, and since when, errors occur,
+// e.g. on a status page while chasing an intermittent disk problem.
+func (f *FileWriter) RecentErrors() []TimedError {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	errs := make([]TimedError, 0, f.recentCount)
+	start := (f.recentNext - f.recentCount + recentErrorsSize) % recentErrorsSize
+	for i := range f.recentCount {
+		errs = append(errs, f.recent[(start+i)%recentErrorsSize])
+	}
+	return errs
+}
+
+// fracSecondPattern matches a fractional-second token (".000", ".999", ",000", ...) directly after the
+// seconds ("05") of a time layout, so a version such as "app-v1.0.log" is not taken for one; Go
+// treats it as one only when no digit follows.
+var fracSecondPattern = regexp.MustCompile(`(05)[.,](0+|9+)([^0-9]|$)`)
+
+// minCheckInterval caps how often a sub-second layout is checked for rotation.
+const minCheckInterval = 10 * time.Millisecond
+
 // getCheckInterval returns the rotation check interval based on the filename layout.
+// A fractional-second token is checked at its resolution, but no more often than every 10ms.
 func (f *FileWriter) getCheckInterval() time.Duration {
 	fileName := f.fileName
+	if m := fracSecondPattern.FindStringSubmatch(fileName); m != nil {
+		interval := time.Second
+		for range len(m[2]) {
+			interval /= 10
+			if interval <= minCheckInterval {
+				return minCheckInterval
+			}
+		}
+		return interval
+	}
 	if strings.Contains(fileName, "05") || strings.Contains(fileName, "5") {
 		return time.Second
 	}
@@ -154,38 +829,210 @@ func (f *FileWriter) getCheckInterval() time.Duration {
 func (f *FileWriter) checkAndRotate() {
 	f.mu.Lock()
 	defer f.mu.Unlock()
+	f.checkAndRotateLocked()
+}
 
-	formattedFileName := time.Now().Format(f.fileName)
+// checkAndRotateLocked is checkAndRotate for callers holding f.mu.
+func (f *FileWriter) checkAndRotateLocked() {
+	now := f.now()
+	formattedFileName := now.Format(f.fileName)
+	if f.nameSuffix != "" {
+		// inserted after formatting, so the suffix digits are not read as layout tokens
+		ext := filepath.Ext(formattedFileName)
+		formattedFileName = strings.TrimSuffix(formattedFileName, ext) + f.nameSuffix + ext
+	}
 	current := filepath.Join(f.dir, formattedFileName)
 
 	if current != f.current {
-		if f.buf != nil {
-			if err := f.buf.Flush(); err != nil {
-				return
-			}
-			f.buf = nil
+		// throttle rotations; a clock going backwards counts as too soon as well
+		if f.file != nil && f.minRotate > 0 && now.Sub(f.lastRotate) < f.minRotate {
+			return
 		}
-
-		if f.file != nil {
-			if err := f.file.Close(); err != nil {
+		wasOpen := f.file != nil
+		if wasOpen && f.fresh {
+			// CoalesceRotations: nothing was written to the file since it was created, so drop it
+			// rather than leave it empty
+			f.reportLocked(f.discardCurrentLocked())
+		} else {
+			if err := f.finishCurrentLocked(); err != nil {
+				f.reportLocked(err)
 				return
 			}
-			f.file = nil
+			if wasOpen {
+				if err := f.finalizeLocked(); err != nil {
+					f.reportLocked(err)
+				}
+			} else if f.renameActive {
+				f.finalizeStaleLocked(current)
+			}
+			if wasOpen && f.compress {
+				f.step("compress")
+				_ = compressFile(f.current) // best effort; the uncompressed file is kept on failure
+			}
+		}
+		if wasOpen {
+			f.lastRotation = now
 		}
 		f.current = current
+		f.lastRotate = now
+		f.step("open")
 		if err := f.openCurrentLocked(); err != nil {
+			f.reportLocked(err)
 			return
 		}
 
 		if f.maxFiles > 0 {
-			_ = f.cleanOldFiles()
+			f.step("clean")
+			f.reportLocked(f.cleanOldFiles())
+		}
+	}
+}
+
+// finishCurrentLocked flushes the buffer and closes the current file for good, writing its footer.
+// Caller must hold f.mu.
+func (f *FileWriter) finishCurrentLocked() error {
+	f.step("flush")
+	if f.buf != nil {
+		if err := f.buf.Flush(); err != nil {
+			return err
+		}
+		f.buf = nil
+	}
+
+	if f.file != nil {
+		f.step("close")
+		f.writeCloseMarkerLocked()
+		f.writeFooterLocked()
+		f.trimLocked()
+		if err := f.file.Close(); err != nil {
+			return err
+		}
+		f.file = nil
+	}
+	return nil
+}
+
+// discardCurrentLocked closes the current file without its close marker or footer and removes it,
+// for a file CoalesceRotations found empty. Caller must hold f.mu.
+func (f *FileWriter) discardCurrentLocked() error {
+	f.buf = nil
+	f.fresh = false
+	f.step("close")
+	err := f.file.Close()
+	f.file = nil
+	if err != nil {
+		return err
+	}
+	if err := f.remove(f.activePath()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// step calls the rotateStep test hook, if set.
+func (f *FileWriter) step(stage string) {
+	if f.rotateStep != nil {
+		f.rotateStep(stage)
+	}
+}
+
+// rotateBySizeLocked moves the current file aside as "<name>.<N><ext>", with N one past the highest
+// index in use, and reopens the current name empty. Caller must hold f.mu.
+func (f *FileWriter) rotateBySizeLocked() error {
+	if f.fresh {
+		return nil // CoalesceRotations: the current file is already a new, empty one
+	}
+	if err := f.finishCurrentLocked(); err != nil {
+		return err
+	}
+
+	ext := filepath.Ext(f.current)
+	base := strings.TrimSuffix(f.current, ext)
+	n := 1
+	for _, suffix := range []string{ext, ext + ".gz"} {
+		matches, err := filepath.Glob(base + ".*" + suffix)
+		if err != nil {
+			continue
+		}
+		for _, m := range matches {
+			idx, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(m, base+"."), suffix))
+			if err == nil && idx >= n {
+				n = idx + 1
+			}
 		}
 	}
+	rotated := fmt.Sprintf("%s.%d%s", base, n, ext)
+	f.step("rename")
+	if err := os.Rename(f.activePath(), rotated); err != nil {
+		return err
+	}
+	f.lastRotation = f.now()
+	if f.compress {
+		f.step("compress")
+		_ = compressFile(rotated) // best effort; the uncompressed file is kept on failure
+	}
+
+	f.step("open")
+	if err := f.openCurrentLocked(); err != nil {
+		return err
+	}
+	if f.maxFiles > 0 {
+		f.step("clean")
+		f.reportLocked(f.cleanOldFiles())
+	}
+	return nil
 }
 
-// openCurrentLocked opens the file at f.current and initializes the buffer. Caller must hold f.mu.
+// activePath returns the path the current file is written at: f.current, plus ActiveSuffix with
+// RenameOnRotate.
+func (f *FileWriter) activePath() string {
+	if f.renameActive {
+		return f.current + ActiveSuffix
+	}
+	return f.current
+}
+
+// finalizeLocked renames the active file to its final name, with RenameOnRotate. The file must be
+// closed. Caller must hold f.mu.
+func (f *FileWriter) finalizeLocked() error {
+	if !f.renameActive {
+		return nil
+	}
+	f.step("rename")
+	if err := os.Rename(f.activePath(), f.current); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// finalizeStaleLocked renames active files left by an earlier run, other than the one for current,
+// to their final names. Caller must hold f.mu.
+func (f *FileWriter) finalizeStaleLocked(current string) {
+	matches, _ := filepath.Glob(f.buildGlobPattern() + ActiveSuffix)
+	for _, m := range matches {
+		if m == current+ActiveSuffix {
+			continue
+		}
+		final := strings.TrimSuffix(m, ActiveSuffix)
+		if _, err := os.Stat(final); err == nil {
+			continue // never overwrite a finished file
+		}
+		f.reportLocked(os.Rename(m, final))
+	}
+}
+
+// openCurrentLocked opens the file at f.activePath() and initializes the buffer. Caller must hold f.mu.
 func (f *FileWriter) openCurrentLocked() error {
-	file, err := os.OpenFile(f.current, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if f.lockErr != nil {
+		return f.lockErr
+	}
+	if f.renameActive {
+		// continue a file finished by an earlier run in the same period, as without RenameOnRotate
+		if _, err := os.Stat(f.activePath()); errors.Is(err, fs.ErrNotExist) {
+			_ = os.Rename(f.current, f.activePath())
+		}
+	}
+	file, err := os.OpenFile(f.activePath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 	if err != nil {
 		f.file = nil
 		f.buf = nil
@@ -193,66 +1040,218 @@ func (f *FileWriter) openCurrentLocked() error {
 	}
 
 	f.file = file
+	f.out = file
+	if f.wrapFile != nil {
+		f.out = f.wrapFile(file)
+	}
+	f.size = 0
+	if info, err := file.Stat(); err == nil {
+		f.size = info.Size()
+	}
+	f.fresh = f.coalesce && f.size == 0
+	if f.footer {
+		f.resetFooterLocked()
+	}
+	if f.preallocate > 0 {
+		// best effort: filesystems without fallocate support just grow on demand
+		_ = preallocate(file, f.size, f.preallocate)
+	}
 	if f.flushInterval > 0 {
-		f.buf = bufio.NewWriter(f.file)
+		f.buf = bufio.NewWriter(outWriter{f})
 	} else {
 		f.buf = nil
 	}
+	if f.epochMarker {
+		f.writeEpochMarkerLocked()
+	}
 	return nil
 }
 
+// writeEpochMarkerLocked writes an epoch marker line with a new ID to the file just opened.
+// It goes through the footer tracking like any other line. Caller must hold f.mu.
+func (f *FileWriter) writeEpochMarkerLocked() {
+	marker := EpochPrefix + "id=" + newEpochID() + "\n"
+	n, err := io.WriteString(f.file, marker)
+	f.size += int64(n)
+	if err == nil {
+		f.trackLocked([]byte(marker))
+	}
+}
+
+// resetFooterLocked starts the footer hash and record count for the current file,
+// seeding them with any content it already has. Caller must hold f.mu.
+func (f *FileWriter) resetFooterLocked() {
+	f.hash = sha256.New()
+	f.records = 0
+	if f.size == 0 {
+		return
+	}
+	existing, err := os.Open(f.activePath())
+	if err != nil {
+		return
+	}
+	defer existing.Close()
+	_, _ = io.Copy(writerFunc(f.trackLocked), io.LimitReader(existing, f.size))
+}
+
+// compressFile appends the content of path to "<path>.gz" as a new gzip member and removes path.
+// On failure the archive is truncated back to its previous size and path is kept.
+func compressFile(path string) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	var prevSize int64
+	if info, err := dst.Stat(); err == nil {
+		prevSize = info.Size()
+	}
+	defer func() {
+		if err != nil {
+			_ = dst.Truncate(prevSize)
+		}
+		if cerr := dst.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	zw := gzip.NewWriter(dst)
+	if _, err = io.Copy(zw, src); err != nil {
+		return err
+	}
+	if err = zw.Close(); err != nil {
+		return err
+	}
+	if err = dst.Sync(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// writerFunc adapts a function to io.Writer.
+type writerFunc func(p []byte)
+
+func (w writerFunc) Write(p []byte) (int, error) {
+	w(p)
+	return len(p), nil
+}
+
+// trimLocked releases preallocated space beyond the data actually written. Caller must hold f.mu
+// and have flushed the buffer.
+func (f *FileWriter) trimLocked() {
+	if f.preallocate > 0 {
+		_ = f.file.Truncate(f.size)
+	}
+}
+
 // cleanOldFiles removes old files beyond maxFiles. Caller must hold f.mu.
 func (f *FileWriter) cleanOldFiles() error {
 	if f.maxFiles <= 0 {
 		return nil
 	}
 
-	matches, err := filepath.Glob(f.buildGlobPattern())
+	files, err := f.oldFilesLocked()
 	if err != nil {
 		return err
 	}
+	// a file that cannot be removed (e.g. held open elsewhere on Windows) does not stop the rest
+	var errs []error
+	for i := f.maxFiles; i < len(files); i++ {
+		if err := f.remove(files[i].name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
 
-	var files []struct {
-		name    string
-		modTime time.Time
+// removeOldestLocked removes the oldest old log file and reports whether it did. Caller must hold f.mu.
+func (f *FileWriter) removeOldestLocked() bool {
+	files, err := f.oldFilesLocked()
+	if err != nil || len(files) == 0 {
+		return false
 	}
+	return f.remove(files[len(files)-1].name) == nil
+}
+
+// oldFile is a log file other than the current one.
+type oldFile struct {
+	name    string
+	modTime time.Time
+}
+
+// oldFilesLocked returns the log files other than the current one, newest first. Caller must hold f.mu.
+func (f *FileWriter) oldFilesLocked() ([]oldFile, error) {
+	pattern := f.buildGlobPattern()
+	patterns := []string{pattern}
+	if f.maxSize > 0 {
+		// size-rotated files carry an index before the extension
+		ext := filepath.Ext(pattern)
+		patterns = append(patterns, strings.TrimSuffix(pattern, ext)+".*"+ext)
+	}
+	if f.compress {
+		for _, p := range patterns {
+			patterns = append(patterns, p+".gz")
+		}
+	}
+	var matches []string
+	seen := make(map[string]bool)
+	for _, p := range patterns {
+		found, err := filepath.Glob(p)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range found {
+			if !seen[m] {
+				seen[m] = true
+				matches = append(matches, m)
+			}
+		}
+	}
+
+	var files []oldFile
 	for _, match := range matches {
-		if match == f.current {
+		if match == f.current || match == f.activePath() || match == f.path+".lock" || !f.ownsFile(match) {
 			continue
 		}
 		info, err := os.Stat(match)
 		if err != nil || info.IsDir() {
 			continue
 		}
-		files = append(files, struct {
-			name    string
-			modTime time.Time
-		}{
-			name:    match,
-			modTime: info.ModTime(),
-		})
-	}
-
-	if len(files) <= f.maxFiles {
-		return nil
+		files = append(files, oldFile{name: match, modTime: info.ModTime()})
 	}
 
 	sort.Slice(files, func(i, j int) bool {
 		return files[i].modTime.After(files[j].modTime)
 	})
+	return files, nil
+}
 
-	for i := f.maxFiles; i < len(files); i++ {
-		if err := os.Remove(files[i].name); err != nil {
-			return err
-		}
-	}
+// pidSuffixPattern matches the per-PID suffix (LockFallbackPID) at the end of a file name without its
+// extension, followed by the index of a size-rotated file, if any.
+var pidSuffixPattern = regexp.MustCompile(`(\.pid[0-9]+)(?:\.[0-9]+)?$`)
 
-	return nil
+// ownsFile reports whether name, matched by the glob patterns, carries this writer's per-PID suffix
+// (none for the lock owner), as the patterns also match the files of the other writers on the path.
+func (f *FileWriter) ownsFile(name string) bool {
+	base := strings.TrimSuffix(filepath.Base(name), ".gz")
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+	var suffix string
+	if m := pidSuffixPattern.FindStringSubmatch(base); m != nil {
+		suffix = m[1]
+	}
+	return suffix == f.nameSuffix
 }
 
 func (f *FileWriter) buildGlobPattern() string {
-	// replace time placeholders (2006, 06, 01-05, 15, etc.) with * and collapse runs
-	pattern := regexp.MustCompile(`2006|0[1-6]|[1-5]|15`).ReplaceAllString(f.fileName, "*")
+	// replace fractional seconds, including the separator (".999" drops it for whole seconds),
+	// then time placeholders (2006, 06, 01-05, 15, etc.) with * and collapse runs
+	pattern := fracSecondPattern.ReplaceAllString(f.fileName, "${1}*${3}")
+	pattern = regexp.MustCompile(`2006|0[1-6]|[1-5]|15`).ReplaceAllString(pattern, "*")
 	pattern = regexp.MustCompile(`\*+`).ReplaceAllString(pattern, "*")
 	return filepath.Join(f.dir, pattern)
 }
@@ -1,10 +1,22 @@
 package glog
 
 import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
+	"syscall"
 	"testing"
 	"time"
 )
@@ -243,6 +255,43 @@ func TestFileWriter_Close(t *testing.T) {
 	}
 }
 
+func TestFileWriter_CloseFlushesPendingBuffer(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "glog_test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	filePath := filepath.Join(tmpDir, "close_flush_test.log")
+	// long interval: no periodic flush happens during the test
+	fw := NewFileWriterWithFlushInterval(filePath, 0, 3600)
+
+	const numRecords = 500
+	var expected strings.Builder
+	for i := 0; i < numRecords; i++ {
+		line := fmt.Sprintf("record %d\n", i)
+		expected.WriteString(line)
+		if _, err := fw.Write([]byte(line)); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	if err := fw.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	if string(content) != expected.String() {
+		t.Fatalf("file content mismatch after Close: got %d bytes, expected %d", len(content), expected.Len())
+	}
+	if !strings.HasSuffix(string(content), fmt.Sprintf("record %d\n", numRecords-1)) {
+		t.Error("last record written before Close is missing")
+	}
+}
+
 func TestFileWriter_CloseConcurrent(t *testing.T) {
 	tmpDir, err := os.MkdirTemp("", "glog_test")
 	if err != nil {
@@ -432,3 +481,1161 @@ func TestFileWriter_CleanOldFiles(t *testing.T) {
 		t.Errorf("expected at least 3 files, got %d", fileCount)
 	}
 }
+
+func TestFileWriter_WriteFooter(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "glog_test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	timeFormat := filepath.Join(tmpDir, "footer-2006-01-02-15-04-05.log")
+	fw := NewFileWriterWithOptions(timeFormat, FileWriterOptions{WriteFooter: true, FlushInterval: 1})
+	defer fw.Close()
+
+	body := "first record\nsecond record\n"
+	if _, err := fw.Write([]byte(body)); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	firstFile := fw.State().CurrentFile
+
+	time.Sleep(2 * time.Second)
+	if _, err := fw.Write([]byte("next file\n")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if fw.State().CurrentFile == firstFile {
+		t.Fatal("file should have rotated")
+	}
+
+	content, err := os.ReadFile(firstFile)
+	if err != nil {
+		t.Fatalf("failed to read rotated file: %v", err)
+	}
+	if !strings.HasPrefix(string(content), body) {
+		t.Fatalf("rotated file should start with the written records, got %q", content)
+	}
+
+	footer, ok := ParseFooter(string(content[len(body):]))
+	if !ok {
+		t.Fatalf("expected a footer as the last line, got %q", content[len(body):])
+	}
+	sum := sha256.Sum256([]byte(body))
+	if footer.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("footer hash %s does not match body hash %x", footer.SHA256, sum)
+	}
+	if footer.Records != 2 {
+		t.Errorf("expected 2 records in footer, got %d", footer.Records)
+	}
+}
+
+func TestParseFooter(t *testing.T) {
+	if _, ok := ParseFooter(`{"msg":"not a footer"}`); ok {
+		t.Error("expected a regular record not to parse as a footer")
+	}
+	ft, ok := ParseFooter(FooterPrefix + "sha256=abc records=7\n")
+	if !ok || ft.SHA256 != "abc" || ft.Records != 7 {
+		t.Errorf("unexpected footer %+v (ok=%v)", ft, ok)
+	}
+}
+
+// fakeClock is a settable clock for FileWriter tests.
+type fakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = t
+}
+
+func TestFileWriter_MinRotateInterval(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "glog_test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local)
+	clock := &fakeClock{t: start}
+	timeFormat := filepath.Join(tmpDir, "throttle-2006-01-02-15-04-05.log")
+	fw := NewFileWriterWithOptions(timeFormat, FileWriterOptions{
+		MinRotateInterval: 10 * time.Second,
+		now:               clock.Now,
+	})
+	defer fw.Close()
+	first := fw.current
+
+	// an oscillating clock changes the file name every check, but rotation is throttled
+	for _, offset := range []time.Duration{time.Second, -time.Second, 3 * time.Second, -2 * time.Second, 9 * time.Second} {
+		clock.Set(start.Add(offset))
+		fw.checkAndRotate()
+		if _, err := fw.Write([]byte("line\n")); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		if fw.current != first {
+			t.Fatalf("rotated after %v, within MinRotateInterval", offset)
+		}
+	}
+
+	clock.Set(start.Add(10 * time.Second))
+	fw.checkAndRotate()
+	if fw.current == first {
+		t.Fatal("expected rotation once MinRotateInterval has elapsed")
+	}
+
+	entries, err := os.ReadDir(tmpDir)
+	if err != nil {
+		t.Fatalf("failed to read dir: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("expected 2 files, got %d", len(entries))
+	}
+	content, err := os.ReadFile(first)
+	if err != nil {
+		t.Fatalf("failed to read first file: %v", err)
+	}
+	if got := strings.Count(string(content), "line\n"); got != 5 {
+		t.Errorf("expected throttled writes in the first file, got %d lines", got)
+	}
+}
+
+func TestFileWriter_MillisecondLayout(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "glog_test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local)
+	clock := &fakeClock{t: start}
+	timeFormat := filepath.Join(tmpDir, "ms-150405.000.log")
+	fw := NewFileWriterWithOptions(timeFormat, FileWriterOptions{MaxFiles: 2, now: clock.Now})
+	defer fw.Close()
+
+	if got := fw.getCheckInterval(); got != minCheckInterval {
+		t.Errorf("expected check interval %v for a millisecond layout, got %v", minCheckInterval, got)
+	}
+	if got := fw.buildGlobPattern(); got != filepath.Join(tmpDir, "ms-*.log") {
+		t.Errorf("unexpected glob pattern %q", got)
+	}
+
+	for i := 1; i <= 5; i++ {
+		clock.Set(start.Add(time.Duration(i) * time.Millisecond))
+		fw.checkAndRotate()
+		if _, err := fw.Write([]byte("batch\n")); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	if want := filepath.Join(tmpDir, "ms-120000.005.log"); fw.current != want {
+		t.Errorf("expected current file %q, got %q", want, fw.current)
+	}
+
+	entries, err := os.ReadDir(tmpDir)
+	if err != nil {
+		t.Fatalf("failed to read dir: %v", err)
+	}
+	// 2 old files + the current one
+	if len(entries) != 3 {
+		t.Errorf("expected 3 files after cleanup, got %d", len(entries))
+	}
+}
+
+func TestFileWriter_FractionalCheckInterval(t *testing.T) {
+	cases := map[string]time.Duration{
+		"app-05.0.log":   100 * time.Millisecond,
+		"app-05.99.log":  minCheckInterval,
+		"app-05.000.log": minCheckInterval,
+		"app-05.log":     time.Second,
+		"app.log0.txt":   time.Minute,
+		"app-v0.0.log":   time.Minute, // a version, not fractional seconds
+	}
+	for name, want := range cases {
+		fw := &FileWriter{fileName: name}
+		if got := fw.getCheckInterval(); got != want {
+			t.Errorf("%s: expected %v, got %v", name, want, got)
+		}
+	}
+
+	fw := &FileWriter{fileName: "app-v0.0-20060102.log"}
+	if got := fw.buildGlobPattern(); got != "app-v0.0-*.log" {
+		t.Errorf("expected the version kept in the glob pattern, got %q", got)
+	}
+}
+
+func TestFileWriter_MaxSizeCountsBufferedBytes(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "glog_test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	logPath := filepath.Join(tmpDir, "sized.log")
+	// a long flush interval: nothing reaches the file unless rotation flushes it
+	fw := NewFileWriterWithOptions(logPath, FileWriterOptions{FlushInterval: 3600, MaxSize: 100})
+	defer fw.Close()
+
+	line := strings.Repeat("x", 39) + "\n" // 40 bytes
+	for i := 0; i < 2; i++ {
+		if _, err := fw.Write([]byte(line)); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "sized.1.log")); !os.IsNotExist(err) {
+		t.Fatal("rotated before reaching MaxSize")
+	}
+
+	// the third line would make the logical size 120 > 100, although nothing has been flushed yet
+	if _, err := fw.Write([]byte(line)); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	rotated, err := os.ReadFile(filepath.Join(tmpDir, "sized.1.log"))
+	if err != nil {
+		t.Fatalf("expected rotated file: %v", err)
+	}
+	if string(rotated) != line+line {
+		t.Errorf("expected the first two lines flushed into the rotated file, got %q", rotated)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := fw.Write([]byte(line)); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "sized.2.log")); err != nil {
+		t.Errorf("expected a second rotation: %v", err)
+	}
+	if err := fw.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if content, _ := os.ReadFile(logPath); string(content) != line {
+		t.Errorf("expected the last line in the current file, got %q", content)
+	}
+}
+
+func TestFileWriter_MaxSizeCleanup(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "glog_test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	fw := NewFileWriterWithOptions(filepath.Join(tmpDir, "app.log"), FileWriterOptions{MaxFiles: 2, MaxSize: 10})
+	defer fw.Close()
+	for i := 0; i < 6; i++ {
+		if _, err := fw.Write([]byte("123456789\n")); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	entries, err := os.ReadDir(tmpDir)
+	if err != nil {
+		t.Fatalf("failed to read dir: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Errorf("expected 2 old files and the current one, got %d", len(entries))
+	}
+}
+
+func TestFileWriter_CleanupContinuesPastRemoveErrors(t *testing.T) {
+	tmpDir := t.TempDir()
+	old := time.Now().Add(-time.Hour)
+	for i := 1; i <= 4; i++ {
+		name := filepath.Join(tmpDir, fmt.Sprintf("app.%d.log", i))
+		if err := os.WriteFile(name, []byte("old\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(name, old, old.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stuck := filepath.Join(tmpDir, "app.3.log")
+	var reported []error
+	fw := NewFileWriterWithOptions(filepath.Join(tmpDir, "app.log"), FileWriterOptions{
+		MaxFiles: 1,
+		MaxSize:  10,
+		OnError:  func(err error) { reported = append(reported, err) },
+		remove: func(name string) error {
+			if name == stuck {
+				return &os.PathError{Op: "remove", Path: name, Err: syscall.EBUSY}
+			}
+			return os.Remove(name)
+		},
+	})
+	defer fw.Close()
+	for i := 0; i < 2; i++ {
+		if _, err := fw.Write([]byte("123456789\n")); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	entries, err := os.ReadDir(tmpDir)
+	if err != nil {
+		t.Fatalf("failed to read dir: %v", err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	// the current file, the newest old file, and the one that could not be removed
+	if want := []string{"app.3.log", "app.5.log", "app.log"}; !slices.Equal(names, want) {
+		t.Errorf("expected %v left, got %v", want, names)
+	}
+	// cleanup runs when the first file is opened and again after the rotation
+	if len(reported) != 2 {
+		t.Fatalf("expected an error reported by each cleanup, got %v", reported)
+	}
+	for _, err := range reported {
+		if !errors.Is(err, syscall.EBUSY) {
+			t.Errorf("expected the remove error to be reported, got %v", err)
+		}
+	}
+	if st := fw.State(); !strings.Contains(st.LastError, "app.3.log") {
+		t.Errorf("expected the cleanup error in State, got %q", st.LastError)
+	}
+}
+
+func TestFileWriter_RenameOnRotate(t *testing.T) {
+	tmpDir := t.TempDir()
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local)
+	clock := &fakeClock{t: start}
+	opts := FileWriterOptions{RenameOnRotate: true, now: clock.Now}
+	fw := NewFileWriterWithOptions(filepath.Join(tmpDir, "app-15.log"), opts)
+
+	files := func() []string {
+		t.Helper()
+		entries, err := os.ReadDir(tmpDir)
+		if err != nil {
+			t.Fatalf("failed to read dir: %v", err)
+		}
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		return names
+	}
+	read := func(name string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(tmpDir, name))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", name, err)
+		}
+		return string(data)
+	}
+
+	if _, err := fw.Write([]byte("a1\n")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if got := files(); !slices.Equal(got, []string{"app-12.log.active"}) {
+		t.Fatalf("expected only the active file before rotation, got %v", got)
+	}
+	if got := read("app-12.log.active"); got != "a1\n" {
+		t.Errorf("expected the in-progress data in the active file, got %q", got)
+	}
+	if st := fw.State(); st.CurrentFile != filepath.Join(tmpDir, "app-12.log.active") {
+		t.Errorf("expected State to report the active name, got %s", st.CurrentFile)
+	}
+
+	clock.Set(start.Add(time.Hour))
+	fw.checkAndRotate()
+	if _, err := fw.Write([]byte("b1\n")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if got := files(); !slices.Equal(got, []string{"app-12.log", "app-13.log.active"}) {
+		t.Fatalf("expected the rotated file under its final name, got %v", got)
+	}
+	if got := read("app-12.log"); got != "a1\n" {
+		t.Errorf("expected the rotated file's content, got %q", got)
+	}
+
+	if err := fw.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if got := files(); !slices.Equal(got, []string{"app-12.log", "app-13.log"}) {
+		t.Fatalf("expected Close to give the current file its final name, got %v", got)
+	}
+
+	// a restart in the same period continues the finished file under the active name
+	fw = NewFileWriterWithOptions(filepath.Join(tmpDir, "app-15.log"), opts)
+	if _, err := fw.Write([]byte("b2\n")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if got := files(); !slices.Equal(got, []string{"app-12.log", "app-13.log.active"}) {
+		t.Fatalf("expected the current file active again after a restart, got %v", got)
+	}
+	fw.Close()
+	if got := read("app-13.log"); got != "b1\nb2\n" {
+		t.Errorf("expected the restart to append to the file, got %q", got)
+	}
+}
+
+func TestFileWriter_RenameOnRotate_StaleActiveFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "app-11.log.active"), []byte("crashed\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local)}
+	fw := NewFileWriterWithOptions(filepath.Join(tmpDir, "app-15.log"), FileWriterOptions{RenameOnRotate: true, now: clock.Now})
+	defer fw.Close()
+
+	if _, err := os.Stat(filepath.Join(tmpDir, "app-11.log")); err != nil {
+		t.Errorf("expected the leftover active file renamed to its final name: %v", err)
+	}
+}
+
+func TestFileWriter_SizeCheckInterval(t *testing.T) {
+	tmpDir := t.TempDir()
+	fw := NewFileWriterWithOptions(filepath.Join(tmpDir, "app.log"), FileWriterOptions{MaxSize: 10, SizeCheckInterval: time.Hour})
+	defer fw.Close()
+
+	for i := 0; i < 4; i++ {
+		if _, err := fw.Write([]byte("123456789\n")); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "app.1.log")); err == nil {
+		t.Fatal("expected no rotation on the write path")
+	}
+
+	fw.checkSize() // the background tick
+	data, err := os.ReadFile(filepath.Join(tmpDir, "app.1.log"))
+	if err != nil {
+		t.Fatalf("expected the background check to rotate the file: %v", err)
+	}
+	if len(data) != 40 {
+		t.Errorf("expected the oversized file rotated whole, got %d bytes", len(data))
+	}
+}
+
+func TestFileWriter_CoalesceRotations(t *testing.T) {
+	tmpDir := t.TempDir()
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local)
+	clock := &fakeClock{t: start}
+	fw := NewFileWriterWithOptions(filepath.Join(tmpDir, "merge-15.log"), FileWriterOptions{
+		MaxSize:           16,
+		SizeCheckInterval: time.Hour,
+		CoalesceRotations: true,
+		now:               clock.Now,
+	})
+	defer fw.Close()
+
+	if _, err := fw.Write([]byte(strings.Repeat("x", 19) + "\n")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	// the size and the clock trigger back to back
+	fw.checkSize()
+	clock.Set(start.Add(time.Hour))
+	fw.checkAndRotate()
+	fw.checkSize()
+	if _, err := fw.Write([]byte("late\n")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	entries, err := os.ReadDir(tmpDir)
+	if err != nil {
+		t.Fatalf("failed to read dir: %v", err)
+	}
+	var names []string
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil {
+			t.Fatalf("failed to stat %s: %v", e.Name(), err)
+		}
+		if info.Size() == 0 {
+			t.Errorf("expected no empty files, got %s", e.Name())
+		}
+		names = append(names, e.Name())
+	}
+	if want := []string{"merge-12.1.log", "merge-13.log"}; !slices.Equal(names, want) {
+		t.Errorf("expected files %q, got %q", want, names)
+	}
+}
+
+func TestFileWriter_IdleCheckBackoff(t *testing.T) {
+	tmpDir := t.TempDir()
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local)
+	clock := &fakeClock{t: start}
+	fw := NewFileWriterWithOptions(filepath.Join(tmpDir, "idle-15.log"), FileWriterOptions{
+		MaxIdleCheckInterval: 8 * time.Minute,
+		now:                  clock.Now,
+	})
+	defer fw.Close()
+
+	base := time.Minute
+	if _, err := fw.Write([]byte("busy\n")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	interval := fw.nextCheckInterval(base, base)
+	if interval != base {
+		t.Errorf("expected the base interval after a write, got %v", interval)
+	}
+	// idle checks back off exponentially up to the cap
+	for _, want := range []time.Duration{2 * time.Minute, 4 * time.Minute, 8 * time.Minute, 8 * time.Minute} {
+		interval = fw.nextCheckInterval(base, interval)
+		if interval != want {
+			t.Errorf("expected an idle interval of %v, got %v", want, interval)
+		}
+	}
+
+	// the first write after the quiet spell rotates by itself and tightens the checks again
+	clock.Set(start.Add(time.Hour))
+	if _, err := fw.Write([]byte("resumed\n")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(tmpDir, "idle-13.log")); err != nil || string(data) != "resumed\n" {
+		t.Errorf("expected the write in the new period's file, got %q, %v", data, err)
+	}
+	if interval = fw.nextCheckInterval(base, interval); interval != base {
+		t.Errorf("expected the base interval after the write, got %v", interval)
+	}
+}
+
+func TestFileWriter_CloseMarker(t *testing.T) {
+	tmpDir := t.TempDir()
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local)
+	clock := &fakeClock{t: start}
+	fw := NewFileWriterWithOptions(filepath.Join(tmpDir, "closed-15.log"), FileWriterOptions{
+		CloseMarker:   "#glog-closed",
+		FlushInterval: 1,
+		MaxSize:       64,
+		now:           clock.Now,
+	})
+
+	write := func(s string) {
+		t.Helper()
+		if _, err := fw.Write([]byte(s)); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	write("a1\n")
+	clock.Set(start.Add(time.Hour))
+	fw.checkAndRotate()
+	write("b1\n")
+	write(strings.Repeat("x", 63) + "\n") // rotates by size
+	if err := fw.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	for name, want := range map[string][]string{
+		"closed-12.log":   {"a1", "#glog-closed"},
+		"closed-13.1.log": {"b1", "#glog-closed"},
+		"closed-13.log":   {strings.Repeat("x", 63), "#glog-closed"},
+	} {
+		data, err := os.ReadFile(filepath.Join(tmpDir, name))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", name, err)
+		}
+		if got := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n"); !slices.Equal(got, want) {
+			t.Errorf("%s: expected lines %q, got %q", name, want, got)
+		}
+	}
+}
+
+func TestFileWriter_EpochMarker(t *testing.T) {
+	tmpDir := t.TempDir()
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local)
+	clock := &fakeClock{t: start}
+	timeFormat := filepath.Join(tmpDir, "epoch-15.log")
+	fw := NewFileWriterWithOptions(timeFormat, FileWriterOptions{EpochMarker: true, now: clock.Now})
+
+	write := func(w *FileWriter, s string) {
+		t.Helper()
+		if _, err := w.Write([]byte(s)); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	write(fw, "a1\n")
+	write(fw, "a2\n")
+	first := fw.current
+	clock.Set(start.Add(time.Hour))
+	fw.checkAndRotate()
+	write(fw, "b1\n")
+	second := fw.current
+	fw.Close()
+
+	// a restart appending to the same file starts a new epoch
+	fw = NewFileWriterWithOptions(timeFormat, FileWriterOptions{EpochMarker: true, now: clock.Now})
+	write(fw, "b2\n")
+	fw.Close()
+
+	readLines := func(path string) []string {
+		t.Helper()
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", path, err)
+		}
+		return strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	}
+
+	a := readLines(first)
+	if len(a) != 3 || a[1] != "a1" || a[2] != "a2" {
+		t.Fatalf("expected one marker followed by both records, got %q", a)
+	}
+	idA, ok := ParseEpochMarker(a[0])
+	if !ok {
+		t.Fatalf("expected the file to start with an epoch marker, got %q", a[0])
+	}
+	if len(idA) != 36 {
+		t.Errorf("expected a UUID, got %q", idA)
+	}
+
+	b := readLines(second)
+	if len(b) != 4 || b[1] != "b1" || b[3] != "b2" {
+		t.Fatalf("expected a marker before each session's records, got %q", b)
+	}
+	idB1, ok1 := ParseEpochMarker(b[0])
+	idB2, ok2 := ParseEpochMarker(b[2])
+	if !ok1 || !ok2 {
+		t.Fatalf("expected epoch markers in %q", b)
+	}
+	if idA == idB1 || idB1 == idB2 {
+		t.Errorf("expected a unique epoch per open, got %s, %s, %s", idA, idB1, idB2)
+	}
+}
+
+func TestParseEpochMarker(t *testing.T) {
+	if _, ok := ParseEpochMarker(`{"msg":"not a marker"}`); ok {
+		t.Error("expected a regular record not to parse as an epoch marker")
+	}
+	if id, ok := ParseEpochMarker(EpochPrefix + "id=abc\n"); !ok || id != "abc" {
+		t.Errorf("unexpected epoch %q (ok=%v)", id, ok)
+	}
+}
+
+// readGzip returns the decompressed content of a gzip file, reading all members.
+func readGzip(t *testing.T, path string) string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", path, err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("%s is not a gzip file: %v", path, err)
+	}
+	content, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("failed to decompress %s: %v", path, err)
+	}
+	return string(content)
+}
+
+func TestFileWriter_CompressOnClose(t *testing.T) {
+	tmpDir := t.TempDir()
+	logPath := filepath.Join(tmpDir, "job.log")
+
+	fw := NewFileWriterWithOptions(logPath, FileWriterOptions{Compress: true, FlushInterval: 1})
+	if _, err := fw.Write([]byte("step 1\nstep 2\n")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if err := fw.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if _, err := os.Stat(logPath); !os.IsNotExist(err) {
+		t.Errorf("expected the uncompressed file removed, got %v", err)
+	}
+	if got := readGzip(t, logPath+".gz"); got != "step 1\nstep 2\n" {
+		t.Errorf("unexpected decompressed content %q", got)
+	}
+
+	// a second run writing the same name extends the archive
+	fw = NewFileWriterWithOptions(logPath, FileWriterOptions{Compress: true})
+	if _, err := fw.Write([]byte("step 3\n")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if err := fw.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if got := readGzip(t, logPath+".gz"); got != "step 1\nstep 2\nstep 3\n" {
+		t.Errorf("expected both runs in the archive, got %q", got)
+	}
+}
+
+func TestFileWriter_CompressOnRotate(t *testing.T) {
+	tmpDir := t.TempDir()
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local)
+	clock := &fakeClock{t: start}
+	timeFormat := filepath.Join(tmpDir, "app-15.log")
+	fw := NewFileWriterWithOptions(timeFormat, FileWriterOptions{Compress: true, MaxFiles: 1, now: clock.Now})
+	defer fw.Close()
+
+	for i := 0; i < 3; i++ {
+		clock.Set(start.Add(time.Duration(i) * time.Hour))
+		fw.checkAndRotate()
+		if _, err := fw.Write([]byte(fmt.Sprintf("hour %d\n", i))); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		if i == 1 {
+			// make the first archive clearly the oldest for cleanup
+			old := start.Add(-time.Hour)
+			if err := os.Chtimes(filepath.Join(tmpDir, "app-12.log.gz"), old, old); err != nil {
+				t.Fatalf("Chtimes failed: %v", err)
+			}
+		}
+	}
+
+	if got := readGzip(t, filepath.Join(tmpDir, "app-13.log.gz")); got != "hour 1\n" {
+		t.Errorf("unexpected content of the rotated file %q", got)
+	}
+	entries, err := os.ReadDir(tmpDir)
+	if err != nil {
+		t.Fatalf("failed to read dir: %v", err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	// the oldest archive is cleaned up, keeping MaxFiles compressed files and the current one
+	if len(names) != 2 || names[0] != "app-13.log.gz" || names[1] != "app-14.log" {
+		t.Errorf("unexpected files %v", names)
+	}
+}
+
+func TestFileWriter_FlushOnNewline(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "tail.log")
+	fw := NewFileWriterWithOptions(logPath, FileWriterOptions{FlushInterval: 60, FlushOnNewline: true})
+	defer fw.Close()
+
+	onDisk := func() string {
+		t.Helper()
+		content, err := os.ReadFile(logPath)
+		if err != nil {
+			t.Fatalf("failed to read file: %v", err)
+		}
+		return string(content)
+	}
+
+	if _, err := fw.Write([]byte("line 1\nline 2\npart")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if got := onDisk(); got != "line 1\nline 2\n" {
+		t.Errorf("expected complete lines on disk and the partial one buffered, got %q", got)
+	}
+
+	if _, err := fw.Write([]byte("ial")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if got := onDisk(); got != "line 1\nline 2\n" {
+		t.Errorf("expected a write without newline to stay buffered, got %q", got)
+	}
+
+	if _, err := fw.Write([]byte(" line\n")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if got := onDisk(); got != "line 1\nline 2\npartial line\n" {
+		t.Errorf("expected the completed line on disk, got %q", got)
+	}
+}
+
+func TestFileWriter_RecentErrors(t *testing.T) {
+	tmpDir := t.TempDir()
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local)
+	clock := &fakeClock{t: start}
+	var failure error
+	fw := NewFileWriterWithOptions(filepath.Join(tmpDir, "errors.log"), FileWriterOptions{
+		now: clock.Now,
+		wrapFile: func(w io.Writer) io.Writer {
+			return funcWriter(func(p []byte) (int, error) {
+				if failure != nil {
+					return 0, failure
+				}
+				return w.Write(p)
+			})
+		},
+	})
+	defer fw.Close()
+
+	if errs := fw.RecentErrors(); len(errs) != 0 {
+		t.Fatalf("expected no errors yet, got %v", errs)
+	}
+	for i := range 20 {
+		clock.Set(start.Add(time.Duration(i) * time.Millisecond))
+		failure = fmt.Errorf("failure %d", i)
+		if _, err := fw.Write([]byte("lost\n")); !errors.Is(err, failure) {
+			t.Fatalf("expected write %d to fail, got %v", i, err)
+		}
+	}
+	failure = nil
+	if _, err := fw.Write([]byte("ok\n")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	// the ring keeps the latest 16, oldest first
+	errs := fw.RecentErrors()
+	if len(errs) != recentErrorsSize {
+		t.Fatalf("expected %d errors, got %d", recentErrorsSize, len(errs))
+	}
+	for i, e := range errs {
+		n := i + 20 - recentErrorsSize
+		if e.Err.Error() != fmt.Sprintf("failure %d", n) || !e.Time.Equal(start.Add(time.Duration(n)*time.Millisecond)) {
+			t.Errorf("entry %d: expected failure %d, got %v at %v", i, n, e.Err, e.Time)
+		}
+	}
+	out, err := json.Marshal(errs[0])
+	if err != nil || !strings.Contains(string(out), `"error":"failure 4"`) {
+		t.Errorf("expected the error message in the JSON, got %s (%v)", out, err)
+	}
+}
+
+// funcWriter adapts a function to io.Writer.
+type funcWriter func(p []byte) (int, error)
+
+func (w funcWriter) Write(p []byte) (int, error) { return w(p) }
+
+func TestFileWriter_State(t *testing.T) {
+	tmpDir := t.TempDir()
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local)
+	clock := &fakeClock{t: start}
+	timeFormat := filepath.Join(tmpDir, "state-15.log")
+	fw := NewFileWriterWithOptions(timeFormat, FileWriterOptions{FlushInterval: 60, now: clock.Now})
+	defer fw.Close()
+
+	if _, err := fw.Write([]byte("hello\n")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	st := fw.State()
+	want := WriterState{CurrentFile: filepath.Join(tmpDir, "state-12.log"), Size: 6, BufferedBytes: 6}
+	if st != want {
+		t.Errorf("expected %+v, got %+v", want, st)
+	}
+
+	clock.Set(start.Add(time.Hour))
+	fw.checkAndRotate()
+	if _, err := fw.Write([]byte("hi\n")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if err := fw.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	st = fw.State()
+	want = WriterState{CurrentFile: filepath.Join(tmpDir, "state-13.log"), Size: 3, LastRotation: start.Add(time.Hour)}
+	if st != want {
+		t.Errorf("expected %+v, got %+v", want, st)
+	}
+
+	// a failing write is reported
+	fw.mu.Lock()
+	fw.file.Close()
+	fw.mu.Unlock()
+	fw.Write([]byte("lost\n"))
+	fw.Flush()
+	if st := fw.State(); !strings.Contains(st.LastError, "closed") {
+		t.Errorf("expected the write error in the state, got %+v", st)
+	}
+}
+
+// diskFullWriter fails writes with ENOSPC while full reports true.
+type diskFullWriter struct {
+	w    io.Writer
+	full func() bool
+}
+
+func (d *diskFullWriter) Write(p []byte) (int, error) {
+	if d.full() {
+		return 0, &os.PathError{Op: "write", Path: "mock", Err: syscall.ENOSPC}
+	}
+	return d.w.Write(p)
+}
+
+func TestFileWriter_OnDiskFull(t *testing.T) {
+	newWriter := func(t *testing.T, policy DiskFullPolicy, fallback io.Writer) (*FileWriter, string) {
+		tmpDir := t.TempDir()
+		old := filepath.Join(tmpDir, "app-11.log")
+		if err := os.WriteFile(old, []byte("old records\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		// the disk has room again once the old file is gone
+		full := func() bool {
+			_, err := os.Stat(old)
+			return err == nil
+		}
+		clock := &fakeClock{t: time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local)}
+		fw := NewFileWriterWithOptions(filepath.Join(tmpDir, "app-15.log"), FileWriterOptions{
+			FlushInterval:    60,
+			OnDiskFull:       policy,
+			DiskFullFallback: fallback,
+			now:              clock.Now,
+			wrapFile: func(w io.Writer) io.Writer {
+				return &diskFullWriter{w: w, full: full}
+			},
+		})
+		t.Cleanup(func() { fw.Close() })
+		return fw, old
+	}
+	write := func(fw *FileWriter, s string) error {
+		if _, err := fw.Write([]byte(s)); err != nil {
+			return err
+		}
+		return fw.Flush()
+	}
+
+	t.Run("DropNew", func(t *testing.T) {
+		fw, old := newWriter(t, DiskFullDropNew, nil)
+		if err := write(fw, "record\n"); !errors.Is(err, syscall.ENOSPC) {
+			t.Fatalf("expected ENOSPC, got %v", err)
+		}
+		if _, err := os.Stat(old); err != nil {
+			t.Errorf("expected old files kept, got %v", err)
+		}
+		// the writer recovers from the failed flush once there is room
+		os.Remove(old)
+		if err := write(fw, "later\n"); err != nil {
+			t.Fatalf("expected writes to work again, got %v", err)
+		}
+	})
+
+	t.Run("DropNewBuffered", func(t *testing.T) {
+		fw, _ := newWriter(t, DiskFullDropNew, nil)
+		if _, err := fw.Write(bytes.Repeat([]byte("x"), 4000)); err != nil {
+			t.Fatalf("expected the write to be buffered, got %v", err)
+		}
+		// this write fills the buffer, and the flush it triggers fails
+		n, err := fw.Write(bytes.Repeat([]byte("y"), 200))
+		if !errors.Is(err, syscall.ENOSPC) || n != 0 {
+			t.Errorf("expected ENOSPC with nothing written, got %d, %v", n, err)
+		}
+		if st := fw.State(); st.Size != 0 || st.BufferedBytes != 0 {
+			t.Errorf("expected the dropped data gone from the state, got %+v", st)
+		}
+	})
+
+	for name, size := range map[string]int{"DropOldest": 8192, "DropOldestBuffered": 7} {
+		t.Run(name, func(t *testing.T) {
+			fw, old := newWriter(t, DiskFullDropOldest, nil)
+			if err := write(fw, strings.Repeat("x", size-1)+"\n"); err != nil {
+				t.Fatalf("expected the write to succeed after cleanup, got %v", err)
+			}
+			if _, err := os.Stat(old); !os.IsNotExist(err) {
+				t.Errorf("expected the old file removed, got %v", err)
+			}
+			data, err := os.ReadFile(fw.State().CurrentFile)
+			if err != nil || len(data) != size {
+				t.Errorf("expected the data in the current file, got %d bytes, %v", len(data), err)
+			}
+		})
+	}
+
+	for name, size := range map[string]int{"Fallback": 8192, "FallbackBuffered": 7} {
+		t.Run(name, func(t *testing.T) {
+			var fallback bytes.Buffer
+			fw, _ := newWriter(t, DiskFullFallback, &fallback)
+			if err := write(fw, strings.Repeat("y", size-1)+"\n"); err != nil {
+				t.Fatalf("expected the fallback to take the write, got %v", err)
+			}
+			if fallback.Len() != size {
+				t.Errorf("expected the data in the fallback writer, got %d bytes", fallback.Len())
+			}
+			if st := fw.State(); st.Size != 0 {
+				t.Errorf("expected nothing counted for the file, got %+v", st)
+			}
+		})
+	}
+}
+
+// simulatedCrash is the panic value rotateStep hooks use to abort a rotation.
+type simulatedCrash struct{ stage string }
+
+// crash abandons fw the way a killed process would: nothing is flushed, open files are just released.
+func crash(fw *FileWriter) {
+	fw.cancel()
+	<-fw.done
+	if fw.file != nil {
+		fw.file.Close()
+	}
+}
+
+// logLines returns every line stored in dir, decompressing .gz files, and fails on a truncated file.
+func logLines(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to read dir: %v", err)
+	}
+	var lines []string
+	for _, e := range entries {
+		path := filepath.Join(dir, e.Name())
+		var content string
+		if strings.HasSuffix(path, ".gz") {
+			content = readGzip(t, path)
+		} else {
+			b, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("failed to read %s: %v", path, err)
+			}
+			content = string(b)
+		}
+		if content == "" {
+			continue
+		}
+		if !strings.HasSuffix(content, "\n") {
+			t.Errorf("%s ends with a partial line: %q", e.Name(), content)
+		}
+		lines = append(lines, strings.Split(strings.TrimSuffix(content, "\n"), "\n")...)
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+func TestFileWriter_RotationCrash(t *testing.T) {
+	cases := []struct {
+		name   string
+		stages []string
+		opts   FileWriterOptions
+		rotate func(fw *FileWriter, clock *fakeClock) // triggers the rotation
+		want   []string                               // lines on disk after the crash and one write after restart
+	}{
+		{
+			name:   "time",
+			stages: []string{"flush", "close", "compress", "open", "clean"},
+			opts:   FileWriterOptions{MaxFiles: 5, Compress: true, FlushInterval: 60},
+			rotate: func(fw *FileWriter, clock *fakeClock) {
+				clock.Set(clock.Now().Add(time.Hour))
+				fw.checkAndRotate()
+			},
+			want: []string{"r0", "r1", "r2"},
+		},
+		{
+			name:   "size",
+			stages: []string{"flush", "close", "rename", "open", "clean"},
+			opts:   FileWriterOptions{MaxFiles: 5, MaxSize: 8},
+			rotate: func(fw *FileWriter, clock *fakeClock) {
+				fw.Write([]byte("lost\n")) // never acknowledged, so losing it is fine
+			},
+			want: []string{"r0", "r1", "r2"},
+		},
+	}
+
+	for _, tc := range cases {
+		for _, stage := range tc.stages {
+			t.Run(tc.name+"/"+stage, func(t *testing.T) {
+				dir := t.TempDir()
+				layout := filepath.Join(dir, "app-15.log")
+				clock := &fakeClock{t: time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local)}
+				armed := false
+				opts := tc.opts
+				opts.now = clock.Now
+				opts.rotateStep = func(s string) {
+					if armed && s == stage {
+						panic(simulatedCrash{stage: s})
+					}
+				}
+
+				fw := NewFileWriterWithOptions(layout, opts)
+				for _, line := range []string{"r0\n", "r1\n"} {
+					if _, err := fw.Write([]byte(line)); err != nil {
+						t.Fatalf("Write failed: %v", err)
+					}
+				}
+				if err := fw.Flush(); err != nil {
+					t.Fatalf("Flush failed: %v", err)
+				}
+
+				armed = true
+				func() {
+					defer func() {
+						if r := recover(); r != (simulatedCrash{stage: stage}) {
+							t.Fatalf("expected a simulated crash at %s, got %v", stage, r)
+						}
+					}()
+					tc.rotate(fw, clock)
+				}()
+				crash(fw)
+
+				// restart: the writer must pick up the files left behind and keep working
+				opts.rotateStep = nil
+				fw = NewFileWriterWithOptions(layout, opts)
+				if _, err := fw.Write([]byte("r2\n")); err != nil {
+					t.Fatalf("Write after restart failed: %v", err)
+				}
+				if err := fw.Close(); err != nil {
+					t.Fatalf("Close after restart failed: %v", err)
+				}
+
+				got := logLines(t, dir)
+				if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+					t.Errorf("expected lines %q on disk, got %q", tc.want, got)
+				}
+			})
+		}
+	}
+}
+
+func TestFileWriter_MaxBatchWrites(t *testing.T) {
+	for _, tc := range []struct {
+		maxBatch int
+		want     []int // queued writes seen by each write, in order
+	}{
+		{maxBatch: 1, want: []int{4, 3, 2, 1, 0}},
+		{maxBatch: 3, want: []int{4, 2, 2, 1, 0}},
+		{maxBatch: 8, want: []int{4, 0, 0, 0, 0}},
+	} {
+		t.Run(fmt.Sprintf("max=%d", tc.maxBatch), func(t *testing.T) {
+			var fw *FileWriter
+			gate := make(chan struct{})
+			var queued []int
+			fw = NewFileWriterWithOptions(filepath.Join(t.TempDir(), "batch.log"), FileWriterOptions{
+				MaxBatchWrites: tc.maxBatch,
+				wrapFile: func(w io.Writer) io.Writer {
+					return funcWriter(func(p []byte) (int, error) {
+						if string(p) == "first\n" {
+							<-gate
+						}
+						fw.queueMu.Lock()
+						queued = append(queued, len(fw.queue))
+						fw.queueMu.Unlock()
+						return w.Write(p)
+					})
+				},
+			})
+			defer fw.Close()
+
+			queueState := func() (bool, int) {
+				fw.queueMu.Lock()
+				defer fw.queueMu.Unlock()
+				return fw.leading, len(fw.queue)
+			}
+			var wg sync.WaitGroup
+			write := func(s string) {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if n, err := fw.Write([]byte(s)); err != nil || n != len(s) {
+						t.Errorf("Write(%q) = %d, %v", s, n, err)
+					}
+				}()
+			}
+			write("first\n")
+			for leading, _ := queueState(); !leading; leading, _ = queueState() {
+				runtime.Gosched()
+			}
+			for i, s := range []string{"a\n", "b\n", "c\n", "d\n"} {
+				write(s)
+				for _, n := queueState(); n != i+1; _, n = queueState() {
+					runtime.Gosched()
+				}
+			}
+			close(gate)
+			wg.Wait()
+
+			if !slices.Equal(queued, tc.want) {
+				t.Errorf("expected queue lengths %v, got %v", tc.want, queued)
+			}
+			if err := fw.Flush(); err != nil {
+				t.Fatalf("Flush failed: %v", err)
+			}
+			if data, _ := os.ReadFile(fw.State().CurrentFile); string(data) != "first\na\nb\nc\nd\n" {
+				t.Errorf("expected the writes in queue order, got %q", data)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package glogtest
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/lyuangg/glog"
+)
+
+// Expectation asserts that at least one record recorded by a glog.MemoryHandler matches all of its
+// constraints. Constraints are checked as they are added, so a chain such as
+//
+//	glogtest.Expect(t, mem).Level(slog.LevelError).Msg("boom").Attr("code", 500)
+//
+// needs no terminal call; the test fails (once) at the first constraint no record satisfies.
+type Expectation struct {
+	t      testing.TB
+	h      *glog.MemoryHandler
+	checks []expectCheck
+	failed bool
+}
+
+// expectCheck is one constraint; it returns "" when rec satisfies it, or the reason it does not.
+type expectCheck struct {
+	desc  string
+	match func(rec glog.MemoryRecord) string
+}
+
+// Expect starts an expectation over the records h has recorded so far.
+func Expect(t testing.TB, h *glog.MemoryHandler) *Expectation {
+	return &Expectation{t: t, h: h}
+}
+
+// Level requires the record level to equal level.
+func (e *Expectation) Level(level slog.Level) *Expectation {
+	e.t.Helper()
+	return e.add(fmt.Sprintf("level == %s", level), func(rec glog.MemoryRecord) string {
+		if rec.Level != level {
+			return fmt.Sprintf("level is %s", rec.Level)
+		}
+		return ""
+	})
+}
+
+// Msg requires the message to contain substr.
+func (e *Expectation) Msg(substr string) *Expectation {
+	e.t.Helper()
+	return e.add(fmt.Sprintf("msg contains %q", substr), func(rec glog.MemoryRecord) string {
+		if !strings.Contains(rec.Message, substr) {
+			return fmt.Sprintf("msg is %q", rec.Message)
+		}
+		return ""
+	})
+}
+
+// Attr requires the attribute key (group-qualified, e.g. "http.status") to equal value.
+// Values are compared as slog values, so Attr("code", 500) matches slog.Int64("code", 500).
+func (e *Expectation) Attr(key string, value any) *Expectation {
+	e.t.Helper()
+	want := slog.AnyValue(value).Resolve()
+	return e.add(fmt.Sprintf("%s == %v", key, want), func(rec glog.MemoryRecord) string {
+		got, ok := rec.Attrs[key]
+		if !ok {
+			return fmt.Sprintf("%s is missing", key)
+		}
+		if !got.Equal(want) {
+			return fmt.Sprintf("%s is %v", key, got)
+		}
+		return ""
+	})
+}
+
+// HasAttr requires the attribute key (group-qualified) to be present with any value.
+func (e *Expectation) HasAttr(key string) *Expectation {
+	e.t.Helper()
+	return e.add(fmt.Sprintf("has %s", key), func(rec glog.MemoryRecord) string {
+		if _, ok := rec.Attrs[key]; !ok {
+			return fmt.Sprintf("%s is missing", key)
+		}
+		return ""
+	})
+}
+
+// add appends a constraint and fails the test if no record satisfies all constraints so far.
+func (e *Expectation) add(desc string, match func(rec glog.MemoryRecord) string) *Expectation {
+	e.t.Helper()
+	e.checks = append(e.checks, expectCheck{desc: desc, match: match})
+	if e.failed {
+		return e
+	}
+
+	records := e.h.Records()
+	var report strings.Builder
+	for i, rec := range records {
+		var reasons []string
+		for _, c := range e.checks {
+			if reason := c.match(rec); reason != "" {
+				reasons = append(reasons, reason)
+			}
+		}
+		if len(reasons) == 0 {
+			return e
+		}
+		fmt.Fprintf(&report, "\n  #%d %s %q: %s", i, rec.Level, rec.Message, strings.Join(reasons, "; "))
+	}
+
+	descs := make([]string, len(e.checks))
+	for i, c := range e.checks {
+		descs[i] = c.desc
+	}
+	if len(records) == 0 {
+		report.WriteString("\n  (no records)")
+	}
+	e.failed = true
+	e.t.Errorf("no log record matches [%s]; recorded:%s", strings.Join(descs, ", "), report.String())
+	return e
+}
+
+// AssertNoneAbove fails t if any record h has recorded so far is at or above level, listing the
+// offending records. Use it to catch unexpected warnings or errors during a test:
+//
+//	defer glogtest.AssertNoneAbove(t, mem, slog.LevelWarn)
+func AssertNoneAbove(t testing.TB, h *glog.MemoryHandler, level slog.Level) {
+	t.Helper()
+
+	var report strings.Builder
+	for i, rec := range h.Records() {
+		if rec.Level < level {
+			continue
+		}
+		fmt.Fprintf(&report, "\n  #%d %s %q", i, rec.Level, rec.Message)
+		if len(rec.Attrs) > 0 {
+			keys := make([]string, 0, len(rec.Attrs))
+			for k := range rec.Attrs {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				fmt.Fprintf(&report, " %s=%v", k, rec.Attrs[k])
+			}
+		}
+	}
+	if report.Len() > 0 {
+		t.Errorf("unexpected log records at or above %s:%s", level, report.String())
+	}
+}
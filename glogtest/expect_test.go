@@ -0,0 +1,82 @@
+package glogtest
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/lyuangg/glog"
+)
+
+func TestExpectation_Match(t *testing.T) {
+	mem := glog.NewMemoryHandler(nil)
+	logger := slog.New(mem)
+	logger.Info("starting")
+	logger.WithGroup("http").Error("request boom", slog.Int("code", 500), slog.String("path", "/x"))
+
+	Expect(t, mem).Level(slog.LevelError).Msg("boom").Attr("http.code", 500).HasAttr("http.path")
+	Expect(t, mem).Msg("start")
+}
+
+func TestExpectation_Fail(t *testing.T) {
+	mem := glog.NewMemoryHandler(nil)
+	logger := slog.New(mem)
+	logger.Error("boom", slog.Int("code", 503))
+	logger.Info("boom", slog.Int("code", 500))
+
+	ft := &fakeTB{}
+	// each record matches some constraints, but none matches all of them
+	Expect(ft, mem).Level(slog.LevelError).Msg("boom").Attr("code", 500).HasAttr("never")
+
+	if len(ft.errors) != 1 {
+		t.Fatalf("expected exactly one failure, got %d: %v", len(ft.errors), ft.errors)
+	}
+	msg := ft.errors[0]
+	for _, want := range []string{"level == ERROR", "code == 500", "code is 503", "level is INFO"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("expected %q in failure message, got: %s", want, msg)
+		}
+	}
+}
+
+func TestExpectation_NoRecords(t *testing.T) {
+	mem := glog.NewMemoryHandler(nil)
+
+	ft := &fakeTB{}
+	Expect(ft, mem).Msg("anything")
+
+	if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], "(no records)") {
+		t.Fatalf("expected a failure mentioning no records, got %v", ft.errors)
+	}
+}
+
+func TestAssertNoneAbove(t *testing.T) {
+	mem := glog.NewMemoryHandler(nil)
+	logger := slog.New(mem)
+	logger.Debug("details")
+	logger.Info("progress")
+
+	AssertNoneAbove(t, mem, slog.LevelWarn)
+
+	logger.Warn("disk almost full", slog.Int("percent", 91))
+	logger.Info("still fine")
+
+	ft := &fakeTB{}
+	AssertNoneAbove(ft, mem, slog.LevelWarn)
+	if len(ft.errors) != 1 {
+		t.Fatalf("expected one failure, got %d: %v", len(ft.errors), ft.errors)
+	}
+	msg := ft.errors[0]
+	if !strings.Contains(msg, `#2 WARN "disk almost full" percent=91`) {
+		t.Errorf("expected the offending record in the failure, got: %s", msg)
+	}
+	if strings.Contains(msg, "still fine") {
+		t.Errorf("expected records below the level left out, got: %s", msg)
+	}
+
+	ft = &fakeTB{}
+	AssertNoneAbove(ft, mem, slog.LevelError)
+	if len(ft.errors) != 0 {
+		t.Errorf("expected no failure below LevelError, got: %v", ft.errors)
+	}
+}
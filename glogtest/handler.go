@@ -0,0 +1,62 @@
+// Package glogtest provides test helpers for code that logs through glog: a handler that writes to
+// the test log, and expectations over the records of a glog.MemoryHandler.
+package glogtest
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/lyuangg/glog"
+)
+
+// testWriter passes each write to t.Log until the test has finished.
+type testWriter struct {
+	mu   sync.Mutex
+	t    testing.TB
+	done bool
+}
+
+func (w *testWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// testing panics on Log after the test has completed, e.g. from a goroutine that outlives it
+	if !w.done {
+		w.t.Helper()
+		w.t.Log(strings.TrimSuffix(string(p), "\n"))
+	}
+	return len(p), nil
+}
+
+// stop makes later writes no-ops.
+func (w *testWriter) stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.done = true
+}
+
+// NewHandler returns a glog.Handler that writes each record to t.Log, so logs show up only for
+// failing (or verbose) tests, attributed to the test that produced them. opts controls format and
+// level as usual; Writer and LogPath are ignored. nil uses the defaults.
+//
+// When t finishes, records held for deduplication are flushed and later records are discarded, so
+// goroutines outliving the test cannot make it panic.
+func NewHandler(t testing.TB, opts *glog.Options) *glog.Handler {
+	t.Helper()
+
+	var o glog.Options
+	if opts != nil {
+		o = *opts
+	}
+	w := &testWriter{t: t}
+	o.Writer = w
+	o.LogPath = ""
+
+	h := glog.NewHandler(&o)
+	t.Cleanup(func() {
+		_ = h.Flush()
+		w.stop()
+	})
+	return h
+}
@@ -0,0 +1,84 @@
+package glogtest
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lyuangg/glog"
+)
+
+// fakeTB records failures, logs and cleanups instead of passing them to the real test, to test
+// test helpers themselves.
+type fakeTB struct {
+	testing.TB
+	errors   []string
+	logs     []string
+	cleanups []func()
+}
+
+func (f *fakeTB) Helper() {}
+
+func (f *fakeTB) Errorf(format string, args ...any) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeTB) Log(args ...any) {
+	f.logs = append(f.logs, fmt.Sprint(args...))
+}
+
+func (f *fakeTB) Cleanup(fn func()) {
+	f.cleanups = append(f.cleanups, fn)
+}
+
+// finish runs the registered cleanups, last registered first, as the testing package does.
+func (f *fakeTB) finish() {
+	for i := len(f.cleanups) - 1; i >= 0; i-- {
+		f.cleanups[i]()
+	}
+}
+
+func TestNewHandler(t *testing.T) {
+	ft := &fakeTB{}
+	handler := NewHandler(ft, &glog.Options{Format: glog.FormatJSON, Level: slog.LevelInfo})
+	logger := slog.New(handler)
+
+	logger.Debug("hidden")
+	logger.Info("visible", slog.Int("n", 1))
+
+	if len(ft.logs) != 1 {
+		t.Fatalf("expected one logged line, got %q", ft.logs)
+	}
+	if !strings.HasPrefix(ft.logs[0], "{") || !strings.Contains(ft.logs[0], `"msg":"visible"`) || strings.HasSuffix(ft.logs[0], "\n") {
+		t.Errorf("expected the JSON record without its newline, got %q", ft.logs[0])
+	}
+
+	ft.finish()
+	logger.Info("after the test")
+	if len(ft.logs) != 1 {
+		t.Errorf("expected no logging after the test finished, got %q", ft.logs)
+	}
+}
+
+func TestNewHandler_FlushesOnCleanup(t *testing.T) {
+	ft := &fakeTB{}
+	handler := NewHandler(ft, &glog.Options{Level: slog.LevelInfo, DedupWindow: time.Hour})
+	logger := slog.New(handler)
+	logger.Info("retrying")
+	logger.Info("retrying")
+
+	ft.finish()
+	if len(ft.logs) != 1 || !strings.Contains(ft.logs[0], `"repeated":2`) {
+		t.Errorf("expected the held record flushed on cleanup, got %q", ft.logs)
+	}
+}
+
+func TestNewHandler_Subtests(t *testing.T) {
+	for _, name := range []string{"a", "b"} {
+		t.Run(name, func(t *testing.T) {
+			slog.New(NewHandler(t, nil)).Info("from subtest", slog.String("name", name))
+		})
+	}
+}
@@ -4,10 +4,10 @@ go 1.25.3
 
 require (
 	github.com/sirupsen/logrus v1.9.3
+	go.opentelemetry.io/otel v1.46.0
 	go.uber.org/zap v1.27.1
+	golang.org/x/sys v0.43.0
+	google.golang.org/grpc v1.82.1
 )
 
-require (
-	go.uber.org/multierr v1.10.0 // indirect
-	golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
-)
+require go.uber.org/multierr v1.10.0 // indirect
@@ -0,0 +1,60 @@
+// Package grpctrace reads trace and span IDs for glog records from incoming gRPC metadata.
+package grpctrace
+
+import (
+	"context"
+
+	"github.com/lyuangg/glog"
+	"google.golang.org/grpc/metadata"
+)
+
+// Default metadata keys. gRPC metadata keys are lowercase.
+const (
+	DefaultTraceIDKey = "x-trace-id"
+	DefaultSpanIDKey  = "x-span-id"
+)
+
+// Options configures GRPCTraceExtractor.
+type Options struct {
+	// TraceIDKey is the metadata key holding the trace ID; default DefaultTraceIDKey.
+	TraceIDKey string
+	// SpanIDKey is the metadata key holding the span ID; default DefaultSpanIDKey.
+	SpanIDKey string
+}
+
+// GRPCTraceExtractor returns a glog.TraceExtractor reading the trace and span IDs from the metadata
+// of an incoming gRPC request (metadata.FromIncomingContext), for use in server handlers and
+// interceptors. It returns nil when the context has no incoming metadata or neither key is set.
+// opts may be nil.
+//
+//	handler := glog.NewHandler(&glog.Options{TraceExtractor: grpctrace.GRPCTraceExtractor(nil)})
+func GRPCTraceExtractor(opts *Options) glog.TraceExtractor {
+	traceKey, spanKey := DefaultTraceIDKey, DefaultSpanIDKey
+	if opts != nil {
+		if opts.TraceIDKey != "" {
+			traceKey = opts.TraceIDKey
+		}
+		if opts.SpanIDKey != "" {
+			spanKey = opts.SpanIDKey
+		}
+	}
+	return func(ctx context.Context) *glog.TraceInfo {
+		md, ok := metadata.FromIncomingContext(ctx)
+		if !ok {
+			return nil
+		}
+		info := &glog.TraceInfo{TraceID: first(md.Get(traceKey)), SpanID: first(md.Get(spanKey))}
+		if info.TraceID == "" && info.SpanID == "" {
+			return nil
+		}
+		return info
+	}
+}
+
+// first returns the first value, or "" if there is none.
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
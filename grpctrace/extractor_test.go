@@ -0,0 +1,60 @@
+package grpctrace
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/lyuangg/glog"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestGRPCTraceExtractor(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-trace-id", "t-1", "x-span-id", "s-1"))
+	info := GRPCTraceExtractor(nil)(ctx)
+	if info == nil || info.TraceID != "t-1" || info.SpanID != "s-1" {
+		t.Errorf("expected t-1/s-1 from the metadata, got %+v", info)
+	}
+}
+
+func TestGRPCTraceExtractor_CustomKeys(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("X-B3-TraceId", "t-2"))
+	info := GRPCTraceExtractor(&Options{TraceIDKey: "x-b3-traceid"})(ctx)
+	if info == nil || info.TraceID != "t-2" || info.SpanID != "" {
+		t.Errorf("expected t-2 from the custom key, got %+v", info)
+	}
+}
+
+func TestGRPCTraceExtractor_Absent(t *testing.T) {
+	extract := GRPCTraceExtractor(nil)
+	if info := extract(context.Background()); info != nil {
+		t.Errorf("expected nil without metadata, got %+v", info)
+	}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("other", "v"))
+	if info := extract(ctx); info != nil {
+		t.Errorf("expected nil without the keys, got %+v", info)
+	}
+	// outgoing metadata belongs to client calls, not the request being served
+	ctx = metadata.NewOutgoingContext(context.Background(), metadata.Pairs("x-trace-id", "t-3"))
+	if info := extract(ctx); info != nil {
+		t.Errorf("expected nil for outgoing metadata, got %+v", info)
+	}
+}
+
+func TestGRPCTraceExtractor_Handler(t *testing.T) {
+	var buf bytes.Buffer
+	handler := glog.NewHandler(&glog.Options{
+		Writer:         &buf,
+		Format:         glog.FormatJSON,
+		Level:          slog.LevelInfo,
+		TraceExtractor: GRPCTraceExtractor(nil),
+	})
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-trace-id", "t-1"))
+	slog.New(handler).InfoContext(ctx, "served")
+
+	if !strings.Contains(buf.String(), `"trace_id":"t-1"`) {
+		t.Errorf("expected the trace ID in the record, got: %s", buf.String())
+	}
+}
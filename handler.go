@@ -2,10 +2,20 @@ package glog
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"io"
 	"log/slog"
 	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 )
 
 // FormatType is the log output format type.
@@ -15,11 +25,18 @@ const (
 	FormatLine FormatType = iota // single-line text (Laravel-style)
 	FormatJSON                   // JSON (slog JSONHandler)
 	FormatText                   // text (slog TextHandler)
+	FormatDual                   // line format followed by a JSON copy on the same line, see Options.DualDelimiter
 )
 
 const (
-	defaultTraceIDFieldName = "trace_id"
-	defaultSpanIDFieldName  = "span_id"
+	defaultTraceIDFieldName   = "trace_id"
+	defaultSpanIDFieldName    = "span_id"
+	defaultRawLevelFieldName  = "level_raw"
+	defaultGroupPathFieldName = "group"
+	deadlineFieldName         = "deadline_in"
+	correlationIDFieldName    = "correlation_id"
+	defaultRedactMask         = "[REDACTED]"
+	attrCountFieldName        = "attr_count"
 )
 
 // TraceInfo holds trace/span identifiers for log records.
@@ -32,18 +49,101 @@ type TraceInfo struct {
 // TraceExtractor extracts trace info from context. If it returns nil, no trace fields are added.
 type TraceExtractor func(ctx context.Context) *TraceInfo
 
+// TracePlacement is where trace fields are written relative to attributes bound with WithAttrs.
+type TracePlacement int
+
+const (
+	// TraceAfterAttrs adds trace fields to the record, after WithAttrs attributes and inside any open group.
+	TraceAfterAttrs TracePlacement = iota
+	// TraceBeforeAttrs writes trace fields first, at the top level, ahead of StaticFields and WithAttrs
+	// attributes. A record whose trace fields differ from the previous record's re-derives the
+	// handler, which costs allocations; records of the same trace reuse it.
+	TraceBeforeAttrs
+)
+
+// AttrExtractor derives attributes from context (e.g. tenant, user, locale) to attach to each record.
+// Returning nil adds nothing.
+type AttrExtractor func(ctx context.Context) []slog.Attr
+
 // RecordHandler lets callers add or modify attributes on a log record before it is written.
 // ctx is the request context; r is the record (use r.AddAttrs() to add attributes).
 // Note: r is a pointer, so AddAttrs modifications take effect; each Handle call has its own Record, so passing &r is safe; protect shared state with your own locking if needed.
 type RecordHandler func(ctx context.Context, r *slog.Record)
 
-// defaultTimeReplaceAttr formats the top-level time attribute as "2006-01-02 15:04:05".
-func defaultTimeReplaceAttr(groups []string, a slog.Attr) slog.Attr {
-	// only handle top-level "time"
-	if len(groups) == 0 && a.Key == slog.TimeKey {
-		return slog.String(a.Key, a.Value.Time().Format("2006-01-02 15:04:05"))
+// defaultTimeLayout is the layout records are written with until SetTimeFormat changes it.
+const defaultTimeLayout = "2006-01-02 15:04:05"
+
+// timeLayout is a time layout that can be swapped while records are being written; see
+// Handler.SetTimeFormat. A nil timeLayout is defaultTimeLayout.
+type timeLayout struct {
+	layout atomic.Pointer[string]
+}
+
+// newTimeLayout returns a timeLayout holding defaultTimeLayout.
+func newTimeLayout() *timeLayout {
+	l := &timeLayout{}
+	l.set(defaultTimeLayout)
+	return l
+}
+
+func (l *timeLayout) get() string {
+	if l == nil {
+		return defaultTimeLayout
+	}
+	return *l.layout.Load()
+}
+
+func (l *timeLayout) set(layout string) {
+	l.layout.Store(&layout)
+}
+
+// layoutTimeReplaceAttr formats the top-level time attribute with the layout held by l, read for
+// each record.
+func layoutTimeReplaceAttr(l *timeLayout) func(groups []string, a slog.Attr) slog.Attr {
+	return func(groups []string, a slog.Attr) slog.Attr {
+		// only handle top-level "time"
+		if len(groups) == 0 && a.Key == slog.TimeKey {
+			return slog.String(a.Key, a.Value.Time().Format(l.get()))
+		}
+		return a
+	}
+}
+
+// TimeEncoding is how the time of a record is written.
+type TimeEncoding string
+
+const (
+	TimeEncodingLayout       TimeEncoding = "layout"        // "2006-01-02 15:04:05" or the SetTimeFormat layout (default)
+	TimeEncodingRFC3339      TimeEncoding = "rfc3339"       // time.RFC3339 string
+	TimeEncodingEpochSeconds TimeEncoding = "epoch_seconds" // Unix seconds as a number
+	TimeEncodingEpochMillis  TimeEncoding = "epoch_millis"  // Unix milliseconds as a number
+	TimeEncodingEpochNanos   TimeEncoding = "epoch_nanos"   // Unix nanoseconds as a number
+)
+
+// timeEncodingReplaceAttr returns a ReplaceAttr writing the top-level time, and every time-valued
+// attribute when attrs is set, with enc. Unknown encodings use the layout held by layout.
+func timeEncodingReplaceAttr(enc TimeEncoding, attrs bool, layout *timeLayout) func(groups []string, a slog.Attr) slog.Attr {
+	return func(groups []string, a slog.Attr) slog.Attr {
+		if a.Value.Kind() != slog.KindTime {
+			return a
+		}
+		if !attrs && (len(groups) != 0 || a.Key != slog.TimeKey) {
+			return a
+		}
+		t := a.Value.Time()
+		switch enc {
+		case TimeEncodingRFC3339:
+			return slog.String(a.Key, t.Format(time.RFC3339))
+		case TimeEncodingEpochSeconds:
+			return slog.Int64(a.Key, t.Unix())
+		case TimeEncodingEpochMillis:
+			return slog.Int64(a.Key, t.UnixMilli())
+		case TimeEncodingEpochNanos:
+			return slog.Int64(a.Key, t.UnixNano())
+		default:
+			return slog.String(a.Key, t.Format(layout.get()))
+		}
 	}
-	return a
 }
 
 // mergeReplaceAttr composes two ReplaceAttr funcs: defaultReplace first, then userReplace if non-nil.
@@ -60,12 +160,58 @@ func mergeReplaceAttr(defaultReplace, userReplace func(groups []string, a slog.A
 	}
 }
 
-// DefaultTraceExtractor reads trace_id and span_id from context. Supported keys:
+// traceContextKey is the type of the context keys used by SetTraceID and SetSpanID.
+type traceContextKey int
+
+const (
+	traceIDContextKey traceContextKey = iota
+	spanIDContextKey
+)
+
+// destinationContextKey is the context key used by WithDestination.
+type destinationContextKey struct{}
+
+// WithDestination returns a copy of ctx that routes records logged with it to the writer registered
+// under name in Options.Writers. Records with an unknown or no destination go to the primary writer.
+func WithDestination(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, destinationContextKey{}, name)
+}
+
+// GetDestination returns the destination set by WithDestination, or "" if there is none.
+func GetDestination(ctx context.Context) string {
+	name, _ := ctx.Value(destinationContextKey{}).(string)
+	return name
+}
+
+// SetTraceID returns a copy of ctx carrying the trace ID read by GetTraceID and DefaultTraceExtractor.
+func SetTraceID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, traceIDContextKey, id)
+}
+
+// GetTraceID returns the trace ID set by SetTraceID, or "" if there is none.
+func GetTraceID(ctx context.Context) string {
+	id, _ := ctx.Value(traceIDContextKey).(string)
+	return id
+}
+
+// SetSpanID returns a copy of ctx carrying the span ID read by GetSpanID and DefaultTraceExtractor.
+func SetSpanID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, spanIDContextKey, id)
+}
+
+// GetSpanID returns the span ID set by SetSpanID, or "" if there is none.
+func GetSpanID(ctx context.Context) string {
+	id, _ := ctx.Value(spanIDContextKey).(string)
+	return id
+}
+
+// DefaultTraceExtractor reads trace_id and span_id from context. It checks the values set by
+// SetTraceID/SetSpanID first, then these string keys:
 // trace_id, traceId, TraceID, TRACE_ID; span_id, spanId, SpanID, SPAN_ID.
 func DefaultTraceExtractor(ctx context.Context) *TraceInfo {
 	var traceID, spanID string
 
-	traceKeys := []interface{}{"trace_id", "traceId", "TraceID", "TRACE_ID"}
+	traceKeys := []interface{}{traceIDContextKey, "trace_id", "traceId", "TraceID", "TRACE_ID"}
 	for _, key := range traceKeys {
 		if val := ctx.Value(key); val != nil {
 			if tid, ok := val.(string); ok && tid != "" {
@@ -75,7 +221,7 @@ func DefaultTraceExtractor(ctx context.Context) *TraceInfo {
 		}
 	}
 
-	spanKeys := []interface{}{"span_id", "spanId", "SpanID", "SPAN_ID"}
+	spanKeys := []interface{}{spanIDContextKey, "span_id", "spanId", "SpanID", "SPAN_ID"}
 	for _, key := range spanKeys {
 		if val := ctx.Value(key); val != nil {
 			if sid, ok := val.(string); ok && sid != "" {
@@ -95,64 +241,349 @@ func DefaultTraceExtractor(ctx context.Context) *TraceInfo {
 	}
 }
 
-// ParseLevel parses a string into slog.Level. Supports "debug", "info", "warn", "error" (case-insensitive). Returns slog.LevelInfo for unknown values.
+// ParseLevel parses a string into slog.Level. Supports "debug", "info", "warn", "error" (case-insensitive),
+// optionally followed by an offset like "warn+1" or "info-2", and bare integers like "-8".
+// Returns slog.LevelInfo for unknown values; use ParseLevelStrict to detect them.
 func ParseLevel(s string) slog.Level {
-	switch strings.ToLower(strings.TrimSpace(s)) {
+	level, err := ParseLevelStrict(s)
+	if err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
+
+// ParseLevelStrict is like ParseLevel but returns an error for input it does not understand.
+func ParseLevelStrict(s string) (slog.Level, error) {
+	str := strings.ToLower(strings.TrimSpace(s))
+
+	if n, err := strconv.Atoi(str); err == nil {
+		return slog.Level(n), nil
+	}
+
+	name, offset := str, 0
+	if i := strings.IndexAny(str, "+-"); i > 0 {
+		n, err := strconv.Atoi(str[i:])
+		if err != nil {
+			return slog.LevelInfo, fmt.Errorf("glog: invalid level offset in %q", s)
+		}
+		name, offset = str[:i], n
+	}
+
+	var level slog.Level
+	switch name {
 	case "debug":
-		return slog.LevelDebug
+		level = slog.LevelDebug
 	case "info":
-		return slog.LevelInfo
+		level = slog.LevelInfo
 	case "warn", "warning":
-		return slog.LevelWarn
+		level = slog.LevelWarn
 	case "error":
-		return slog.LevelError
+		level = slog.LevelError
 	default:
-		return slog.LevelInfo
+		return slog.LevelInfo, fmt.Errorf("glog: unknown level %q", s)
 	}
+	return level + slog.Level(offset), nil
 }
 
 // Options configures the handler.
 type Options struct {
 	// Writer overrides LogPath when set (e.g. for tests or custom output). If nil, log goes to file when LogPath is set, otherwise stdout.
 	Writer io.Writer
+	// Writers registers secondary destinations by name. A record logged with a context from WithDestination
+	// goes to the named writer instead of the primary one, encoded the same way. Flush and Close apply to
+	// these writers too.
+	Writers map[string]io.Writer
+	// ConsoleLevel, when non-nil, mirrors records at or above this level to ConsoleWriter in line format,
+	// while the primary writer keeps getting every record in Format. Records sent to a WithDestination
+	// writer are mirrored too. nil disables the mirror.
+	ConsoleLevel slog.Leveler
+	// ConsoleWriter is where ConsoleLevel mirrors records; default os.Stderr. It is not closed by Close.
+	ConsoleWriter io.Writer
+	// BytesTransformer, when set, rewrites each formatted record, trailing newline included, just before
+	// it is written: e.g. to add a host prefix, change the delimiter, or encrypt. It runs once per record
+	// on the logging goroutine, so it must be fast, and it may modify and return its argument. It applies
+	// to the primary writer, Writers and Secondary, not to the ConsoleLevel mirror; Emit sees the record
+	// before the transform.
+	BytesTransformer func([]byte) []byte
+	// Secondary, when set, writes every record to a second output too, in its own format (e.g. a plain
+	// log file plus a compressed JSON one during a pipeline migration). Records sent to a WithDestination
+	// writer are not copied. Flush and Close apply to its writer as well.
+	Secondary *SecondaryOutput
 	// LogPath is the log file path; supports Go time layout (e.g. app-2006-01-02-15-04-05.log). Used when Writer is nil.
 	LogPath string
 	// MaxFiles is the max number of old log files to keep; 0 means no limit.
 	MaxFiles int
 	// FlushInterval is the buffer flush interval in seconds; 0 means flush on every write; >0 means periodic flush.
 	FlushInterval int
+	// FlushOnNewline, with FlushInterval, also flushes whenever a write ends a line, giving line-level latency
+	// for tailing without a syscall per write of a partial line.
+	FlushOnNewline bool
+	// PreallocateBytes reserves disk space for each new log file (Linux only); the unused tail is trimmed on rotation/close.
+	PreallocateBytes int64
+	// WriteFooter appends an integrity footer (SHA-256 and record count) to each log file on rotation.
+	// The footer is the last line of a rotated file and starts with FooterPrefix; see ParseFooter.
+	WriteFooter bool
+	// EpochMarker writes a marker line with a random ID as the first line each time a log file is opened
+	// (startup, rotation, reopen), so collectors can detect restarts; see EpochPrefix and ParseEpochMarker.
+	EpochMarker bool
+	// CloseMarker is written as the last line of each log file before it is closed, on rotation and on
+	// Close, so tailers know the file is complete. Empty writes none.
+	CloseMarker string
+	// Compress gzips each log file to "<name>.gz" once it is finished: on rotation and on Close, so jobs that
+	// never rotate still leave a compressed file. Restarts writing the same name append to the archive.
+	Compress bool
+	// RenameOnRotate writes the current log file as "<name>.active" and renames it to its final name on
+	// rotation and Close, so file watchers never pick up a partly written file.
+	RenameOnRotate bool
+	// OnDiskFull decides what the log file writer does when the disk is full: drop the new data (default),
+	// delete old log files to make room, or write to DiskFullFallback.
+	OnDiskFull DiskFullPolicy
+	// DiskFullFallback receives the data that did not fit with OnDiskFull set to DiskFullFallback.
+	DiskFullFallback io.Writer
+	// SwallowWriteErrors makes Handle return nil even when writing the record failed; by default it
+	// returns the writer's error. slog's Logger ignores Handle errors either way, so this matters to code
+	// calling Handle directly. With a log file, a write the DiskFullFallback writer took counts as
+	// successful in both modes, and the last failure stays visible in WriterState.
+	SwallowWriteErrors bool
+	// OnFileError is called with log file errors no write can return, such as old files that rotation
+	// cleanup could not remove; see FileWriterOptions.OnError.
+	OnFileError func(error)
+	// MinRotateInterval is the minimum time between two file rotations, guarding against a jumpy clock
+	// creating many tiny files; a rotation due sooner is skipped. 0 means no limit.
+	MinRotateInterval time.Duration
+	// MaxSize rotates the log file once it would exceed this many bytes (buffered data included), renaming
+	// it to "<name>.<N><ext>"; 0 means rotation by time layout only.
+	MaxSize int64
+	// CoalesceRotations keeps rotations that fire back to back (clock and MaxSize together) from leaving
+	// empty log files: a trigger reuses a file nothing has been logged to yet instead of creating another.
+	CoalesceRotations bool
+	// MaxBatchWrites caps how many concurrent log file writes one goroutine performs per hold of the
+	// file's lock, trading throughput against the worst-case wait of a write; 0 means
+	// DefaultMaxBatchWrites. See FileWriterOptions.MaxBatchWrites.
+	MaxBatchWrites int
+	// SizeCheckInterval checks MaxSize in the background this often instead of on every write, trading
+	// rotation precision for a cheaper write path; 0 checks on every write.
+	SizeCheckInterval time.Duration
+	// MaxIdleCheckInterval lets the log file's rotation check back off while nothing is logged, doubling
+	// its interval up to this cap to save idle wakeups; 0 keeps a fixed interval.
+	MaxIdleCheckInterval time.Duration
+	// ExclusiveLock takes an advisory lock on "<LogPath>.lock" so two processes cannot share the log files
+	// (Unix only). When another process holds it, LockFallback picks per-PID file names or failing writes.
+	ExclusiveLock bool
+	// LockFallback is what happens when ExclusiveLock cannot take the lock; default LockFallbackPID.
+	LockFallback LockFallback
 	// Level filters out log records below this level.
 	Level slog.Level
+	// LevelSet marks Level as chosen even when it is slog.LevelInfo, its zero value, so MergeOptions
+	// lets an override lower or raise base's Level to Info. NewHandler ignores it.
+	LevelSet bool
 	// Format is the output format (text or JSON).
 	Format FormatType
 	// AddSource adds source file/line to log records when true.
 	AddSource bool
+	// SourceLevel, when non-nil, adds source file/line only to records at or above this level, regardless
+	// of AddSource (e.g. slog.LevelWarn keeps info and debug lines short). nil leaves it to AddSource.
+	SourceLevel slog.Leveler
+	// SourcePlaceholder is written as the source of records that have no program counter (PC 0, as some
+	// logger bridges produce) when source is on, e.g. "unknown". Empty omits the source for such records.
+	SourcePlaceholder string
+	// TimeEncoding is how the record time is written: a layout string (default), RFC 3339, or an epoch
+	// number. FormatLine always shows a readable time and falls back to the layout for epoch encodings.
+	TimeEncoding TimeEncoding
+	// EncodeTimeAttrs applies TimeEncoding to time-valued attributes as well as the record time.
+	EncodeTimeAttrs bool
+	// LevelFormatter renders the level string in every format (e.g. single-letter codes); the user's
+	// ReplaceAttr still sees and may override its result. nil keeps slog's level names.
+	LevelFormatter func(slog.Level) string
 	// ReplaceAttr replaces or modifies log attributes; nil means no replacement.
 	ReplaceAttr func(groups []string, a slog.Attr) slog.Attr
+	// RedactValuePatterns masks every match in string attribute values, whatever their key (e.g. card
+	// numbers or emails), after ReplaceAttr. The message and built-in fields are not redacted.
+	RedactValuePatterns []*regexp.Regexp
+	// RedactMask replaces each RedactValuePatterns match; default "[REDACTED]".
+	RedactMask string
 	// TraceExtractor extracts trace info from context; nil means no trace injection.
 	TraceExtractor TraceExtractor
+	// TraceMinLevel, when non-nil, runs TraceExtractor only for records at or above this level; records
+	// below it get no trace fields and cost no context lookups. nil extracts for every record.
+	TraceMinLevel slog.Leveler
+	// TracePlacement is where trace fields go relative to StaticFields and WithAttrs attributes; the
+	// default TraceAfterAttrs writes them with the record's own attributes.
+	TracePlacement TracePlacement
 	// TraceIDFieldName is the log field name for trace_id; default "trace_id".
 	TraceIDFieldName string
 	// SpanIDFieldName is the log field name for span_id; default "span_id".
 	SpanIDFieldName string
+	// IncludeDeadline adds a "deadline_in" duration field with the time left until the context's deadline
+	// when the record was logged (negative once it has passed). Contexts without a deadline add nothing.
+	IncludeDeadline bool
+	// DuplicateKeys decides what happens to attributes of one record that share a key, such as
+	// logger.Info("m", "k", 1, "k", 2) or a field glog injects under a key the call also used: written
+	// as they are (default), only the last or first kept, or repeats renamed "k_2", "k_3". It applies
+	// to the record's top-level attributes, after RecordHandler; attributes bound with WithAttrs are
+	// not checked.
+	DuplicateKeys DuplicateKeyPolicy
+	// IncludeCheckpoints adds an "elapsed_since_<name>" duration field for each checkpoint set on the
+	// context with Checkpoint, oldest first, measured to the record's time. With AllowKeys, list the
+	// fields to keep.
+	IncludeCheckpoints bool
+	// AttrExtractor is called after trace injection and before RecordHandler; the attributes it returns
+	// are added to the record. nil means no extra context fields.
+	AttrExtractor AttrExtractor
+	// Emit, when set, is called with each record's formatted bytes (including the trailing newline) and the
+	// enriched record, after it has been written to Writer or LogPath. Without either of those, Emit replaces
+	// stdout as the output. Calls are made outside glog's locks and may come from several goroutines.
+	Emit func(ctx context.Context, formatted []byte, r slog.Record)
 	// RecordHandler is called after trace injection and before writing; nil means no extra processing.
 	RecordHandler RecordHandler
+	// RequireMessage handles records with an empty message: they get EmptyMessagePlaceholder as their
+	// message, or are dropped when it is empty. The check runs after RecordHandler.
+	RequireMessage bool
+	// EmptyMessagePlaceholder is the message used for empty-message records with RequireMessage,
+	// e.g. "(no message)"; empty drops such records.
+	EmptyMessagePlaceholder string
+	// AllowKeys, when non-empty, drops every attribute whose key is not listed. Built-in keys (time, level,
+	// msg, source) and fields glog injects itself (trace/span IDs, raw level, deadline) are always kept. Inside groups,
+	// an attribute is kept when its dotted key (e.g. "http.method") or any enclosing group path is listed.
+	// It runs after ReplaceAttr, so it matches the final key names.
+	AllowKeys []string
+	// IncludeRawLevel adds the canonical slog level string (e.g. "ERROR") as an extra field, so machines
+	// get a stable value even when ReplaceAttr remaps the displayed level.
+	IncludeRawLevel bool
+	// IncludeAttrCount adds an "attr_count" field with the number of attributes the record was logged
+	// with plus those bound with WithAttrs (including StaticFields), not counting fields glog injects.
+	// A group counts as one attribute. Use it to find records carrying too many attributes.
+	IncludeAttrCount bool
+	// StackTraceLevel, when non-nil, adds the stack of the logging goroutine to records at or above this
+	// level (e.g. slog.LevelError), starting at the function that logged the record.
+	StackTraceLevel slog.Leveler
+	// StackTraceKey is the key of the StackTraceLevel field; default "stacktrace", or
+	// "error.stack_trace" with ECS. Like other fields, ReplaceAttr sees it under this key.
+	StackTraceKey string
+	// IncludeRecordID adds a "record_id" field with an ID unique to each record, for pipelines that
+	// deduplicate on it. Unlike trace and correlation IDs, no two records share one.
+	IncludeRecordID bool
+	// RecordIDGenerator returns the IDs IncludeRecordID writes; it must be safe for concurrent use.
+	// nil generates ULIDs, which sort by the time they were generated.
+	RecordIDGenerator func() string
+	// RawLevelFieldName is the field name used by IncludeRawLevel; default "level_raw".
+	RawLevelFieldName string
+	// SortFields sorts the structured fields by key in FormatLine output; otherwise they keep insertion
+	// order. Before FormatLine kept insertion order, its fields were always sorted; set SortFields to
+	// keep that output.
+	SortFields bool
+	// MaxLineBytes caps the size of each FormatLine line, including the newline; 0 means no limit.
+	MaxLineBytes int
+	// OverflowStrategy decides whether FormatLine lines over MaxLineBytes are truncated or split.
+	OverflowStrategy OverflowStrategy
+	// GroupSeparator joins group names and keys in FormatLine field keys; default ".".
+	GroupSeparator string
+	// MaxGroupDepth caps how deeply nested group values are flattened in FormatLine; deeper groups are
+	// written as a truncation marker. 0 uses a default of 16.
+	MaxGroupDepth int
+	// MessagePosition, for FormatLine, writes the message after the level (default) or, with
+	// MessageLast, after the fields: "[time] LEVEL: {fields} message".
+	MessagePosition MessagePosition
+	// IncludeGroupPath adds the open WithGroup path, joined with "." (e.g. "request.db"), as a field to records
+	// logged inside a group. FormatLine writes it as a top-level field; other formats get it added to the
+	// record, so it is nested in the open group like the trace fields.
+	IncludeGroupPath bool
+	// GroupPathFieldName is the field name used by IncludeGroupPath; default "group".
+	GroupPathFieldName string
+	// ECS writes Elastic Common Schema field names in JSON and text output: @timestamp (RFC 3339 unless
+	// TimeEncoding is set), log.level, message, and error.message for a top-level error under the "err"
+	// or "error" key; other error attributes keep their keys. Trace and span IDs default to trace.id and
+	// span.id in every format. Dotted keys are expanded by Elasticsearch. The mapping runs last, so
+	// ReplaceAttr and AllowKeys see slog's key names.
+	ECS bool
+	// StringifyValues renders bool and numeric attribute values as JSON strings (e.g. "count":"42"),
+	// including inside groups, for ingestion schemas that require string values. JSON output only.
+	StringifyValues bool
+	// JSONEnvelope nests each FormatJSON record under this key, so every line reads {"<key>":{...}},
+	// for ingestion endpoints expecting a fixed wrapper. Empty writes records unwrapped.
+	JSONEnvelope string
+	// DualDelimiter separates the line and JSON representations in FormatDual; default "\t".
+	DualDelimiter string
+	// FormatByLevel overrides Format for level ranges: a record is encoded in the format of the highest
+	// listed level at or below its own level, e.g. {slog.LevelError: FormatJSON} writes errors as JSON and
+	// everything below in Format. Enrichment runs once per record; only the encoding differs.
+	FormatByLevel map[slog.Level]FormatType
+	// StaticFields are attached to every record at the root, regardless of WithGroup (e.g. version,
+	// git commit, build time). They are resolved once, at construction, and written in key order.
+	StaticFields map[string]any
+	// LogDropSummary makes Close write a final info record ("glog drop summary") with how many records
+	// each subsystem (deduplication, RequireMessage, Sampler, ...) kept from being written, and their total.
+	LogDropSummary bool
+	// Sampler, when set, decides which records are written; see Sampler for when it runs.
+	Sampler Sampler
+	// SampleAfterEnrichment runs the Sampler after TraceExtractor, AttrExtractor and RecordHandler, for
+	// samplers that decide on the fields they add. By default it runs before them, so dropped records
+	// skip enrichment.
+	SampleAfterEnrichment bool
+	// AttrOrder moves the listed record attribute keys (e.g. "trace_id", "span_id") to the front of the
+	// record's attributes, in the listed order; the remaining attributes follow in the order they were added.
+	// It applies to attributes on the record, including injected trace fields, but not to those added
+	// via WithAttrs, which handlers emit first. Empty keeps insertion order. It has no effect on
+	// FormatLine output with SortFields.
+	AttrOrder []string
+	// DedupWindow collapses consecutive records with the same level and message logged within this window
+	// into one record carrying a "repeated" count. Each record is held until its streak ends, so output is
+	// delayed by up to DedupWindow. 0 disables deduplication.
+	DedupWindow time.Duration
+	// DedupKeys lists record attributes (e.g. "user_id") whose values must match too for DedupWindow to
+	// collapse two records; a record without one of them differs from a record that has it. Empty
+	// compares level and message only.
+	DedupKeys []string
+	// AlertCooldown throttles identical records (same level and message) at or above AlertLevel: the first
+	// is written at once, repeats within the cooldown are suppressed, and when it ends the record is written
+	// again with a "recurred" count if any were suppressed. Use it to keep a flapping dependency from paging
+	// on-call thousands of times. 0 disables it.
+	AlertCooldown time.Duration
+	// AlertLevel is the lowest level AlertCooldown applies to; nil means slog.LevelError.
+	AlertLevel slog.Leveler
 }
 
 // defaultOptions returns default Options.
 func defaultOptions() *Options {
 	return &Options{
-		LogPath:          "",
-		MaxFiles:         0,
-		FlushInterval:    0,
-		Level:            slog.LevelInfo,
-		Format:           FormatLine,
-		AddSource:        false,
-		ReplaceAttr:      nil,
-		TraceExtractor:   nil,
-		TraceIDFieldName: defaultTraceIDFieldName,
-		SpanIDFieldName:  defaultSpanIDFieldName,
-		RecordHandler:    nil,
+		LogPath:           "",
+		MaxFiles:          0,
+		FlushInterval:     0,
+		Level:             slog.LevelInfo,
+		Format:            FormatLine,
+		AddSource:         false,
+		ReplaceAttr:       nil,
+		TraceExtractor:    nil,
+		TraceIDFieldName:  defaultTraceIDFieldName,
+		SpanIDFieldName:   defaultSpanIDFieldName,
+		RawLevelFieldName: defaultRawLevelFieldName,
+		RecordHandler:     nil,
+	}
+}
+
+// fileWriterOptions returns the FileWriter configuration derived from opts.
+func fileWriterOptions(opts *Options) FileWriterOptions {
+	return FileWriterOptions{
+		MaxFiles:             opts.MaxFiles,
+		FlushInterval:        opts.FlushInterval,
+		FlushOnNewline:       opts.FlushOnNewline,
+		PreallocateBytes:     opts.PreallocateBytes,
+		WriteFooter:          opts.WriteFooter,
+		MinRotateInterval:    opts.MinRotateInterval,
+		MaxSize:              opts.MaxSize,
+		SizeCheckInterval:    opts.SizeCheckInterval,
+		CoalesceRotations:    opts.CoalesceRotations,
+		MaxBatchWrites:       opts.MaxBatchWrites,
+		MaxIdleCheckInterval: opts.MaxIdleCheckInterval,
+		ExclusiveLock:        opts.ExclusiveLock,
+		LockFallback:         opts.LockFallback,
+		EpochMarker:          opts.EpochMarker,
+		CloseMarker:          opts.CloseMarker,
+		Compress:             opts.Compress,
+		RenameOnRotate:       opts.RenameOnRotate,
+		OnDiskFull:           opts.OnDiskFull,
+		DiskFullFallback:     opts.DiskFullFallback,
+		OnError:              opts.OnFileError,
 	}
 }
 
@@ -160,65 +591,423 @@ func defaultOptions() *Options {
 type Handler struct {
 	opts             *Options
 	writer           io.Writer
+	secondaryWriter  io.Writer               // Options.Secondary's writer; nil when unset
+	destinations     map[string]slog.Handler // encoder chains for Options.Writers, by name; nil when unset
 	handler          slog.Handler
 	traceExtractor   TraceExtractor
 	traceIDFieldName string
 	spanIDFieldName  string
+	attrExtractor    AttrExtractor
+	rawLevelField    string // field for the canonical level; empty when IncludeRawLevel is false
 	recordHandle     RecordHandler
+	attrRank         map[string]int // position of each AttrOrder key; nil when AttrOrder is empty
+	dedup            *dedupState    // shared with derived handlers; nil when DedupWindow is 0
+	alerts           *alertState    // shared with derived handlers; nil when AlertCooldown is 0
+	drops            *dropCounters  // shared with derived handlers
+	correlationID    string         // set by WithCorrelationID; inherited by derived handlers
+	groupPathField   string         // field added by Handle for IncludeGroupPath; empty when the encoder adds it
+	groupPath        string         // open WithGroup path, joined with "."
+	boundAttrs       int            // number of attributes added by WithAttrs, for IncludeAttrCount
+	recordID         func() string  // IncludeRecordID generator; nil when it is off
+	stackTraceKey    string         // key of the StackTraceLevel field; empty when StackTraceLevel is nil
+	timeLayout       *timeLayout    // shared with derived handlers; swapped by SetTimeFormat
+	// set with TraceBeforeAttrs: the chains before any WithAttrs or WithGroup, and the calls made since
+	root             slog.Handler
+	rootDestinations map[string]slog.Handler
+	derivations      []derivation
+	traceChain       *atomic.Pointer[traceChain] // last chain derived for trace fields; one per derivation
+}
+
+// traceChain is a chain derived from a root with trace fields, reused while records carry the same ones.
+type traceChain struct {
+	destination string
+	attrs       []slog.Attr
+	handler     slog.Handler
 }
 
-// NewHandler creates a new Handler.
+// derivation is one WithAttrs (group empty) or WithGroup call, replayed by TraceBeforeAttrs.
+type derivation struct {
+	group string
+	attrs []slog.Attr
+}
+
+// NewHandler creates a new Handler. An unknown Format falls back to FormatLine; use
+// NewHandlerWithError to detect it.
 func NewHandler(opts *Options) *Handler {
 	if opts == nil {
 		opts = defaultOptions()
 	}
+	h := newHandler(opts)
 
+	// Writer takes precedence; else use file when LogPath is set, else stdout unless Emit takes the output
+	if opts.Writer != nil {
+		h.writer = opts.Writer
+	} else if opts.LogPath != "" {
+		h.writer = NewFileWriterWithOptions(opts.LogPath, fileWriterOptions(opts))
+	} else if opts.Emit != nil {
+		h.writer = io.Discard
+	} else {
+		h.writer = os.Stdout
+	}
+	replaceAttr := h.buildReplaceAttr()
+	handlerOpts := &slog.HandlerOptions{
+		Level:       opts.Level,
+		AddSource:   opts.AddSource || opts.SourceLevel != nil,
+		ReplaceAttr: replaceAttr,
+	}
+	lineOpts := &LineHandlerOptions{
+		HandlerOptions:   *handlerOpts,
+		SortFields:       opts.SortFields,
+		MaxLineBytes:     opts.MaxLineBytes,
+		OverflowStrategy: opts.OverflowStrategy,
+		LevelFormatter:   opts.LevelFormatter,
+		GroupSeparator:   opts.GroupSeparator,
+		MaxGroupDepth:    opts.MaxGroupDepth,
+		MessagePosition:  opts.MessagePosition,
+		timeLayout:       h.timeLayout,
+	}
+	if opts.IncludeGroupPath {
+		field := groupPathFieldName(opts)
+		if opts.Format == FormatLine && len(opts.FormatByLevel) == 0 {
+			// the LineHandler writes it at the top level rather than inside the group
+			lineOpts.GroupPathKey = field
+		} else {
+			h.groupPathField = field
+		}
+	}
+	if opts.LevelFormatter != nil {
+		// the LineHandler applies LevelFormatter itself; JSON and text get it as a ReplaceAttr layer
+		handlerOpts.ReplaceAttr = mergeReplaceAttr(levelFormatterReplaceAttr(opts.LevelFormatter), replaceAttr)
+	}
+
+	h.handler = h.newChain(h.writer, handlerOpts, lineOpts)
+	if opts.Secondary != nil {
+		h.secondaryWriter = opts.Secondary.writer()
+		h.handler = &mirrorHandler{primary: h.handler, mirror: h.newSecondaryChain(handlerOpts, lineOpts)}
+	}
+	var console slog.Handler
+	if opts.ConsoleLevel != nil {
+		console = h.newConsoleEncoder(lineOpts)
+		h.handler = &mirrorHandler{primary: h.handler, mirror: console}
+	}
+	if len(opts.Writers) > 0 {
+		h.destinations = make(map[string]slog.Handler, len(opts.Writers))
+		for name, w := range opts.Writers {
+			h.destinations[name] = h.newChain(w, handlerOpts, lineOpts)
+			if console != nil {
+				h.destinations[name] = &mirrorHandler{primary: h.destinations[name], mirror: console}
+			}
+		}
+	}
+	if opts.TracePlacement == TraceBeforeAttrs {
+		h.root, h.rootDestinations = h.handler, h.destinations
+		h.traceChain = new(atomic.Pointer[traceChain])
+	}
+	if len(opts.StaticFields) > 0 {
+		return h.WithAttrs(staticAttrs(opts.StaticFields)).(*Handler)
+	}
+	return h
+}
+
+// newHandler creates a Handler with the record processing state for opts and no encoder chain.
+func newHandler(opts *Options) *Handler {
 	h := &Handler{
 		opts:             opts,
 		traceExtractor:   opts.TraceExtractor,
 		traceIDFieldName: opts.TraceIDFieldName,
 		spanIDFieldName:  opts.SpanIDFieldName,
+		attrExtractor:    opts.AttrExtractor,
+		drops:            &dropCounters{},
 		recordHandle:     opts.RecordHandler,
+		timeLayout:       newTimeLayout(),
+	}
+	if opts.IncludeRawLevel {
+		h.rawLevelField = opts.RawLevelFieldName
+		if h.rawLevelField == "" {
+			h.rawLevelField = defaultRawLevelFieldName
+		}
+	}
+	if len(opts.AttrOrder) > 0 {
+		h.attrRank = attrRank(opts.AttrOrder)
+	}
+	if opts.StackTraceLevel != nil {
+		h.stackTraceKey = opts.StackTraceKey
+		if h.stackTraceKey == "" {
+			h.stackTraceKey = defaultStackTraceKey
+			if opts.ECS {
+				h.stackTraceKey = ecsStackTraceKey
+			}
+		}
+	}
+	if opts.IncludeRecordID {
+		h.recordID = opts.RecordIDGenerator
+		if h.recordID == nil {
+			h.recordID = newULID
+		}
+	}
+	if opts.ECS {
+		if h.traceIDFieldName == "" {
+			h.traceIDFieldName = ecsTraceIDKey
+		}
+		if h.spanIDFieldName == "" {
+			h.spanIDFieldName = ecsSpanIDKey
+		}
+	}
+	if opts.DedupWindow > 0 {
+		h.dedup = newDedupState(opts.DedupWindow, opts.DedupKeys, &h.drops.dedup)
 	}
+	if opts.AlertCooldown > 0 {
+		level := opts.AlertLevel
+		if level == nil {
+			level = slog.LevelError
+		}
+		h.alerts = newAlertState(level, opts.AlertCooldown, &h.drops.alert)
+	}
+	return h
+}
 
-	// Writer takes precedence; else use file when LogPath is set, else stdout
-	if opts.Writer != nil {
-		h.writer = opts.Writer
-	} else if opts.LogPath != "" {
-		h.writer = NewFileWriterWithFlushInterval(opts.LogPath, opts.MaxFiles, opts.FlushInterval)
-	} else {
-		h.writer = os.Stdout
+// groupPathFieldName returns the field name used by IncludeGroupPath.
+func groupPathFieldName(opts *Options) string {
+	if opts.GroupPathFieldName == "" {
+		return defaultGroupPathFieldName
 	}
+	return opts.GroupPathFieldName
+}
 
-	replaceAttr := mergeReplaceAttr(defaultTimeReplaceAttr, opts.ReplaceAttr)
-	handlerOpts := &slog.HandlerOptions{
-		Level:       opts.Level,
-		AddSource:   opts.AddSource,
-		ReplaceAttr: replaceAttr,
+// newConsoleEncoder creates the line encoder ConsoleLevel mirrors records to.
+func (h *Handler) newConsoleEncoder(lineOpts *LineHandlerOptions) slog.Handler {
+	opts := h.opts
+	w := opts.ConsoleWriter
+	if w == nil {
+		w = os.Stderr
 	}
+	consoleOpts := *lineOpts
+	consoleOpts.Level = opts.ConsoleLevel
+	consoleOpts.AddSource = opts.AddSource
+	return NewLineHandlerWithOptions(w, &consoleOpts)
+}
+
+// newSecondaryChain creates the handler chain for Options.Secondary: the options of h with the
+// secondary format, without FormatByLevel, and without Emit, which sees each record once.
+func (h *Handler) newSecondaryChain(handlerOpts *slog.HandlerOptions, lineOpts *LineHandlerOptions) slog.Handler {
+	o := *h.opts
+	o.Format = o.Secondary.Format
+	o.FormatByLevel = nil
+	o.Emit = nil
+	s := h.clone()
+	s.opts = &o
+	return s.newChain(h.secondaryWriter, handlerOpts, lineOpts)
+}
 
-	switch opts.Format {
+// newChain creates the full handler chain writing to w: the encoders, the SourceLevel switch, the
+// Emit hook, and passing levels to a LevelWriter.
+func (h *Handler) newChain(w io.Writer, handlerOpts *slog.HandlerOptions, lineOpts *LineHandlerOptions) slog.Handler {
+	opts := h.opts
+	var levels *levelCapture
+	if lw, ok := w.(LevelWriter); ok {
+		levels = &levelCapture{w: lw}
+		w = levels
+	}
+	if opts.BytesTransformer != nil {
+		w = &transformWriter{w: w, fn: opts.BytesTransformer}
+	}
+	encodeTo := w
+	var capture *emitCapture
+	if opts.Emit != nil {
+		capture = &emitCapture{w: w}
+		encodeTo = capture
+	}
+
+	handler := h.newEncoder(encodeTo, handlerOpts, lineOpts)
+	if opts.SourceLevel != nil {
+		// records below SourceLevel go to encoders without AddSource, so they carry no source at all
+		noSourceOpts, noSourceLineOpts := *handlerOpts, *lineOpts
+		noSourceOpts.AddSource, noSourceLineOpts.AddSource = false, false
+		handler = newLevelSwitchHandler(h.newEncoder(encodeTo, &noSourceOpts, &noSourceLineOpts), []levelBand{
+			{level: opts.SourceLevel, handler: handler},
+		})
+	}
+	if capture != nil {
+		handler = &emitHandler{next: handler, capture: capture, emit: opts.Emit}
+	}
+	if levels != nil {
+		handler = &levelHandler{next: handler, capture: levels}
+	}
+	return handler
+}
+
+// NewHandlerWithError is like NewHandler but rejects invalid options instead of falling back
+// silently: an unknown Format (or FormatByLevel value) returns an error.
+func NewHandlerWithError(opts *Options) (*Handler, error) {
+	if opts != nil {
+		if err := opts.Format.validate(); err != nil {
+			return nil, err
+		}
+		for _, format := range opts.FormatByLevel {
+			if err := format.validate(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return NewHandler(opts), nil
+}
+
+// validate returns an error if f is not one of the defined formats.
+func (f FormatType) validate() error {
+	switch f {
+	case FormatLine, FormatJSON, FormatText, FormatDual:
+		return nil
+	}
+	return fmt.Errorf("glog: unknown format %d", int(f))
+}
+
+// newEncoder creates the handler chain that encodes records to w: the format handler (or one per
+// level range with FormatByLevel).
+func (h *Handler) newEncoder(w io.Writer, handlerOpts *slog.HandlerOptions, lineOpts *LineHandlerOptions) slog.Handler {
+	opts := h.opts
+	handler := h.newFormatHandler(opts.Format, w, handlerOpts, lineOpts)
+	if len(opts.FormatByLevel) > 0 {
+		handler = newFormatByLevelHandler(handler, opts.FormatByLevel, func(format FormatType) slog.Handler {
+			return h.newFormatHandler(format, w, handlerOpts, lineOpts)
+		})
+	}
+	return handler
+}
+
+// newFormatHandler creates the slog.Handler that encodes records in format to w.
+func (h *Handler) newFormatHandler(format FormatType, w io.Writer, handlerOpts *slog.HandlerOptions, lineOpts *LineHandlerOptions) slog.Handler {
+	switch format {
 	case FormatJSON:
-		h.handler = slog.NewJSONHandler(h.writer, handlerOpts)
+		if h.opts.JSONEnvelope != "" {
+			w = newEnvelopeWriter(w, h.opts.JSONEnvelope)
+		}
+		return slog.NewJSONHandler(w, h.structuredHandlerOptions(handlerOpts, true))
 	case FormatText:
-		h.handler = slog.NewTextHandler(h.writer, handlerOpts)
-	case FormatLine:
-		h.handler = NewLineHandler(h.writer, handlerOpts)
+		return slog.NewTextHandler(w, h.structuredHandlerOptions(handlerOpts, false))
+	case FormatDual:
+		return newDualHandler(w, h.opts.DualDelimiter, h.structuredHandlerOptions(handlerOpts, true), lineOpts)
 	default:
-		h.handler = NewLineHandler(h.writer, handlerOpts)
+		return NewLineHandlerWithOptions(w, lineOpts)
 	}
+}
 
-	return h
+// structuredHandlerOptions returns handlerOpts with the ReplaceAttr layers that only apply to slog's
+// JSON and text handlers: the ECS key mapping, and for JSON, StringifyValues.
+func (h *Handler) structuredHandlerOptions(handlerOpts *slog.HandlerOptions, json bool) *slog.HandlerOptions {
+	o := *handlerOpts
+	if json && h.opts.StringifyValues {
+		o.ReplaceAttr = mergeReplaceAttr(o.ReplaceAttr, stringifyReplaceAttr)
+	}
+	if h.opts.ECS {
+		o.ReplaceAttr = mergeReplaceAttr(o.ReplaceAttr, ecsReplaceAttr)
+	}
+	return &o
+}
+
+// staticAttrs converts fields to attributes sorted by key, so every handler writes them in the same order.
+func staticAttrs(fields map[string]any) []slog.Attr {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	attrs := make([]slog.Attr, 0, len(keys))
+	for _, k := range keys {
+		attrs = append(attrs, slog.Any(k, fields[k]))
+	}
+	return attrs
+}
+
+// buildReplaceAttr composes the ReplaceAttr layers implied by the options: the time encoding,
+// then the user's ReplaceAttr, then value redaction, then the key allowlist (so it matches the final
+// key names), and finally the handling of records without a source location.
+func (h *Handler) buildReplaceAttr() func(groups []string, a slog.Attr) slog.Attr {
+	opts := h.opts
+	timeEncoding := opts.TimeEncoding
+	if timeEncoding == "" && opts.ECS {
+		timeEncoding = TimeEncodingRFC3339
+	}
+	timeReplace := layoutTimeReplaceAttr(h.timeLayout)
+	if timeEncoding != "" || opts.EncodeTimeAttrs {
+		timeReplace = timeEncodingReplaceAttr(timeEncoding, opts.EncodeTimeAttrs, h.timeLayout)
+	}
+	replace := mergeReplaceAttr(timeReplace, opts.ReplaceAttr)
+	if len(opts.RedactValuePatterns) > 0 {
+		mask := opts.RedactMask
+		if mask == "" {
+			mask = defaultRedactMask
+		}
+		replace = mergeReplaceAttr(replace, redactValuesReplaceAttr(opts.RedactValuePatterns, mask))
+	}
+	if len(opts.AllowKeys) > 0 {
+		keys := append([]string{}, opts.AllowKeys...)
+		if h.traceExtractor != nil {
+			keys = append(keys, h.traceIDFieldName, h.spanIDFieldName, defaultTraceIDFieldName, defaultSpanIDFieldName)
+		}
+		if h.rawLevelField != "" {
+			keys = append(keys, h.rawLevelField)
+		}
+		if opts.IncludeDeadline {
+			keys = append(keys, deadlineFieldName)
+		}
+		if opts.IncludeGroupPath {
+			keys = append(keys, groupPathFieldName(opts))
+		}
+		if opts.IncludeAttrCount {
+			keys = append(keys, attrCountFieldName)
+		}
+		if opts.IncludeRecordID {
+			keys = append(keys, recordIDFieldName)
+		}
+		if h.stackTraceKey != "" {
+			keys = append(keys, h.stackTraceKey)
+		}
+		// WithCorrelationID may be called on any derived handler, after the allowlist is built
+		keys = append(keys, correlationIDFieldName)
+		replace = mergeReplaceAttr(replace, allowKeysReplaceAttr(keys))
+	}
+	if opts.AddSource || opts.SourceLevel != nil {
+		replace = mergeReplaceAttr(replace, emptySourceReplaceAttr(opts.SourcePlaceholder))
+	}
+	return replace
 }
 
 // Enabled reports whether the given level is enabled.
 func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
-	return h.handler.Enabled(ctx, level)
+	if h.handler.Enabled(ctx, level) {
+		return true
+	}
+	// records below the level are held by a debug ring
+	return ctx != nil && ctx.Value(debugRingContextKey{}) != nil
+}
+
+// EnabledLevels reports which of slog's standard levels (debug, info, warn, error) h writes, for
+// status pages. It runs the same checks as Enabled, so a level is on when Level or ConsoleLevel lets
+// it through, and a slog.LevelVar given as ConsoleLevel is read at its current value.
+func (h *Handler) EnabledLevels() map[slog.Level]bool {
+	ctx := context.Background()
+	levels := make(map[slog.Level]bool, 4)
+	for _, level := range []slog.Level{slog.LevelDebug, slog.LevelInfo, slog.LevelWarn, slog.LevelError} {
+		levels[level] = h.Enabled(ctx, level)
+	}
+	return levels
 }
 
 // Handle processes a log record.
 func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
-	if h.traceExtractor != nil {
+	r = gateAttrs(r)
+	if h.opts.Sampler != nil && !h.opts.SampleAfterEnrichment && !h.sample(ctx, r) {
+		return nil
+	}
+
+	// Injected fields are collected and added with one AddAttrs call, so a record whose inline
+	// attribute slots are full grows its overflow slice once rather than once per field.
+	var buf [8]slog.Attr
+	injected := buf[:0]
+	attrCount := h.boundAttrs + r.NumAttrs()
+	var traceAttrs []slog.Attr // trace fields held back for TraceBeforeAttrs
+	if h.traceExtractor != nil && (h.opts.TraceMinLevel == nil || r.Level >= h.opts.TraceMinLevel.Level()) {
 		if traceInfo := h.traceExtractor(ctx); traceInfo != nil {
 			traceKey := h.traceIDFieldName
 			if traceKey == "" {
@@ -229,49 +1018,284 @@ func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
 				spanKey = defaultSpanIDFieldName
 			}
 			if traceInfo.TraceID != "" {
-				r.AddAttrs(slog.String(traceKey, traceInfo.TraceID))
+				injected = append(injected, slog.String(traceKey, traceInfo.TraceID))
 			}
 			if traceInfo.SpanID != "" {
-				r.AddAttrs(slog.String(spanKey, traceInfo.SpanID))
+				injected = append(injected, slog.String(spanKey, traceInfo.SpanID))
 			}
+			if h.root != nil && len(injected) > 0 {
+				traceAttrs, injected = append(traceAttrs, injected...), injected[:0]
+			}
+		}
+	}
+	if h.correlationID != "" {
+		injected = append(injected, slog.String(correlationIDFieldName, h.correlationID))
+	}
+	if h.opts.IncludeDeadline {
+		if deadline, ok := ctx.Deadline(); ok {
+			at := r.Time
+			if at.IsZero() {
+				at = time.Now()
+			}
+			injected = append(injected, slog.Duration(deadlineFieldName, deadline.Sub(at)))
+		}
+	}
+	if h.opts.IncludeCheckpoints {
+		at := r.Time
+		if at.IsZero() {
+			at = time.Now()
 		}
+		injected = append(injected, checkpointAttrs(ctx, at)...)
+	}
+	if h.attrExtractor != nil {
+		injected = append(injected, h.attrExtractor(ctx)...)
+	}
+	if h.rawLevelField != "" {
+		injected = append(injected, slog.String(h.rawLevelField, r.Level.String()))
+	}
+	if h.groupPathField != "" && h.groupPath != "" {
+		injected = append(injected, slog.String(h.groupPathField, h.groupPath))
+	}
+	if h.opts.IncludeAttrCount {
+		injected = append(injected, slog.Int(attrCountFieldName, attrCount))
+	}
+	if h.recordID != nil {
+		injected = append(injected, slog.String(recordIDFieldName, h.recordID()))
+	}
+	if h.stackTraceKey != "" && r.Level >= h.opts.StackTraceLevel.Level() {
+		injected = append(injected, slog.String(h.stackTraceKey, stackTrace(r.PC)))
+	}
+	if len(injected) > 0 {
+		r.AddAttrs(injected...)
 	}
 	if h.recordHandle != nil {
 		h.recordHandle(ctx, &r)
 	}
-	return h.handler.Handle(ctx, r)
+	if h.opts.Sampler != nil && h.opts.SampleAfterEnrichment && !h.sample(ctx, r) {
+		return nil
+	}
+	if h.opts.RequireMessage && r.Message == "" {
+		if h.opts.EmptyMessagePlaceholder == "" {
+			h.drops.emptyMessage.Add(1)
+			return nil
+		}
+		r.Message = h.opts.EmptyMessagePlaceholder
+	}
+	if h.opts.DuplicateKeys != DuplicateKeysAllow {
+		r = dedupAttrs(r, h.opts.DuplicateKeys)
+	}
+	if h.attrRank != nil {
+		r = orderAttrs(r, h.attrRank)
+	}
+	handler, root, destination := h.handler, h.root, ""
+	if h.destinations != nil {
+		if d, ok := h.destinations[GetDestination(ctx)]; ok {
+			destination = GetDestination(ctx)
+			handler, root = d, h.rootDestinations[destination]
+		}
+	}
+	if len(traceAttrs) > 0 {
+		handler = h.traceHandler(destination, root, traceAttrs)
+	}
+	var dumpErr error
+	if ring, _ := ctx.Value(debugRingContextKey{}).(*debugRing); ring != nil {
+		if !handler.Enabled(ctx, r.Level) {
+			ring.add(handler, ctx, r)
+			return nil
+		}
+		if r.Level >= slog.LevelError {
+			// the held records lead up to the error, so they go first
+			dumpErr = ring.flush()
+		}
+	}
+	if h.alerts != nil && h.alerts.applies(r.Level) && !h.alerts.allow(ctx, handler, r) {
+		return nil
+	}
+	var err error
+	if h.dedup != nil {
+		err = h.dedup.handle(ctx, handler, r)
+	} else {
+		err = handler.Handle(ctx, r)
+	}
+	if h.opts.SwallowWriteErrors {
+		return nil
+	}
+	if dumpErr != nil {
+		return errors.Join(dumpErr, err)
+	}
+	return err
+}
+
+// clone returns a shallow copy of h; shared state (writer, dedup) stays shared.
+func (h *Handler) clone() *Handler {
+	c := *h
+	return &c
+}
+
+// SetTimeFormat changes the Go time layout records are written with (e.g. "2006-01-02 15:04:05.000"
+// to add milliseconds during an incident). It takes effect for subsequent records of h and every
+// handler sharing its output (derived with WithAttrs, WithGroup or WithCorrelationID, or the one it
+// was derived from), and is safe to call while logging. It has no effect with the RFC 3339 and
+// epoch TimeEncodings, or when the user's ReplaceAttr rewrites the time itself.
+func (h *Handler) SetTimeFormat(layout string) {
+	h.timeLayout.set(layout)
+}
+
+// WithCorrelationID returns a new Handler that adds a "correlation_id" field with id to every record.
+// Handlers derived from it with WithAttrs or WithGroup share the same ID; like trace fields, it is
+// added to the record, so it is written inside any open group. An empty id generates a random one.
+func (h *Handler) WithCorrelationID(id string) *Handler {
+	if id == "" {
+		id = newCorrelationID()
+	}
+	c := h.clone()
+	c.correlationID = id
+	return c
+}
+
+// CorrelationID returns the ID set by WithCorrelationID, or "" if there is none.
+func (h *Handler) CorrelationID() string {
+	return h.correlationID
+}
+
+// newCorrelationID returns a random 128-bit ID in hex.
+func newCorrelationID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:]) // crypto/rand.Read never fails
+	return hex.EncodeToString(b[:])
 }
 
 // WithAttrs returns a new Handler with the given attributes.
 func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
-	return &Handler{
-		opts:             h.opts,
-		writer:           h.writer,
-		handler:          h.handler.WithAttrs(attrs),
-		traceExtractor:   h.traceExtractor,
-		traceIDFieldName: h.traceIDFieldName,
-		spanIDFieldName:  h.spanIDFieldName,
-		recordHandle:     h.recordHandle,
+	c := h.clone()
+	c.handler = h.handler.WithAttrs(attrs)
+	c.boundAttrs += len(attrs)
+	c.destinations = deriveDestinations(h.destinations, func(d slog.Handler) slog.Handler { return d.WithAttrs(attrs) })
+	if h.root != nil {
+		c.derivations = append(h.derivations[:len(h.derivations):len(h.derivations)], derivation{attrs: attrs})
+		c.traceChain = new(atomic.Pointer[traceChain])
 	}
+	return c
 }
 
 // WithGroup returns a new Handler with the given group name.
 func (h *Handler) WithGroup(name string) slog.Handler {
-	return &Handler{
-		opts:             h.opts,
-		writer:           h.writer,
-		handler:          h.handler.WithGroup(name),
-		traceExtractor:   h.traceExtractor,
-		traceIDFieldName: h.traceIDFieldName,
-		spanIDFieldName:  h.spanIDFieldName,
-		recordHandle:     h.recordHandle,
+	c := h.clone()
+	c.handler = h.handler.WithGroup(name)
+	if name != "" {
+		if c.groupPath != "" {
+			c.groupPath += "."
+		}
+		c.groupPath += name
+	}
+	c.destinations = deriveDestinations(h.destinations, func(d slog.Handler) slog.Handler { return d.WithGroup(name) })
+	if h.root != nil {
+		c.derivations = append(h.derivations[:len(h.derivations):len(h.derivations)], derivation{group: name})
+		c.traceChain = new(atomic.Pointer[traceChain])
 	}
+	return c
+}
+
+// traceHandler returns the chain for records of destination carrying the trace fields attrs: root
+// with attrs, then the WithAttrs and WithGroup calls made on h. The records of one request share
+// their trace fields, so the last chain is kept and reused until they change.
+func (h *Handler) traceHandler(destination string, root slog.Handler, attrs []slog.Attr) slog.Handler {
+	if c := h.traceChain.Load(); c != nil && c.destination == destination && slices.EqualFunc(c.attrs, attrs, slog.Attr.Equal) {
+		return c.handler
+	}
+	attrs = slices.Clone(attrs)
+	handler := h.replay(root.WithAttrs(attrs))
+	h.traceChain.Store(&traceChain{destination: destination, attrs: attrs, handler: handler})
+	return handler
+}
+
+// replay applies the WithAttrs and WithGroup calls made on h, in order, to handler.
+func (h *Handler) replay(handler slog.Handler) slog.Handler {
+	for _, d := range h.derivations {
+		if d.attrs != nil {
+			handler = handler.WithAttrs(d.attrs)
+		} else {
+			handler = handler.WithGroup(d.group)
+		}
+	}
+	return handler
+}
+
+// deriveDestinations applies derive to every destination chain.
+func deriveDestinations(destinations map[string]slog.Handler, derive func(slog.Handler) slog.Handler) map[string]slog.Handler {
+	if destinations == nil {
+		return nil
+	}
+	derived := make(map[string]slog.Handler, len(destinations))
+	for name, d := range destinations {
+		derived[name] = derive(d)
+	}
+	return derived
+}
+
+// Flush writes records held for deduplication and flushes the writer's buffer, if it has a
+// Flush method (as FileWriter does). The handler remains usable afterwards.
+func (h *Handler) Flush() error {
+	var err error
+	if h.dedup != nil {
+		err = h.dedup.flush()
+	}
+	for _, w := range h.writers() {
+		if flusher, ok := w.(interface{ Flush() error }); ok {
+			err = errors.Join(err, flusher.Flush())
+		}
+	}
+	return err
+}
+
+// writers returns the primary writer followed by the Secondary output's writer and the
+// Options.Writers destinations in use.
+func (h *Handler) writers() []io.Writer {
+	writers := []io.Writer{h.writer}
+	if h.secondaryWriter != nil {
+		writers = append(writers, h.secondaryWriter)
+	}
+	if h.destinations == nil {
+		return writers
+	}
+	names := make([]string, 0, len(h.opts.Writers))
+	for name := range h.opts.Writers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		writers = append(writers, h.opts.Writers[name])
+	}
+	return writers
+}
+
+// WriterState returns a snapshot of the writer's state (see FileWriter.State). It reports false
+// if the writer does not provide one, e.g. when Options.Writer is a plain io.Writer.
+func (h *Handler) WriterState() (WriterState, bool) {
+	if sw, ok := h.writer.(interface{ State() WriterState }); ok {
+		return sw.State(), true
+	}
+	return WriterState{}, false
 }
 
 // Close closes the Handler and releases resources.
+// Records held for deduplication, pending AlertCooldown summaries, and the drop summary with
+// LogDropSummary are written before the writer is closed.
 func (h *Handler) Close() error {
-	if closer, ok := h.writer.(io.Closer); ok {
-		return closer.Close()
+	var err error
+	if h.dedup != nil {
+		err = h.dedup.flush()
+	}
+	if h.alerts != nil {
+		err = errors.Join(err, h.alerts.flush())
+	}
+	if h.opts.LogDropSummary {
+		err = errors.Join(err, h.writeDropSummary())
+	}
+	for _, w := range h.writers() {
+		if closer, ok := w.(io.Closer); ok {
+			err = errors.Join(err, closer.Close())
+		}
 	}
-	return nil
+	return err
 }
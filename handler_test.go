@@ -4,12 +4,17 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"log/slog"
+	"maps"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
 func TestNewHandler_DefaultOptions(t *testing.T) {
@@ -540,6 +545,13 @@ func TestParseLevel(t *testing.T) {
 		{"trim space", "  info  ", slog.LevelInfo},
 		{"unknown default", "unknown", slog.LevelInfo},
 		{"empty default", "", slog.LevelInfo},
+		{"positive offset", "warn+1", slog.LevelWarn + 1},
+		{"negative offset", "info-2", slog.LevelInfo - 2},
+		{"offset upper case", "ERROR+4", slog.LevelError + 4},
+		{"numeric", "-8", slog.Level(-8)},
+		{"numeric positive", "12", slog.Level(12)},
+		{"bad offset default", "warn+x", slog.LevelInfo},
+		{"unknown with offset default", "loud+1", slog.LevelInfo},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -550,6 +562,33 @@ func TestParseLevel(t *testing.T) {
 	}
 }
 
+func TestParseLevelStrict(t *testing.T) {
+	valid := map[string]slog.Level{
+		"debug":    slog.LevelDebug,
+		"Warning":  slog.LevelWarn,
+		"info+2":   slog.LevelInfo + 2,
+		"error-1":  slog.LevelError - 1,
+		" -4 ":     slog.LevelDebug,
+		"DEBUG-10": slog.LevelDebug - 10,
+	}
+	for input, want := range valid {
+		got, err := ParseLevelStrict(input)
+		if err != nil {
+			t.Errorf("ParseLevelStrict(%q) unexpected error: %v", input, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("ParseLevelStrict(%q) = %v, want %v", input, got, want)
+		}
+	}
+
+	for _, input := range []string{"", "unknown", "warn+", "info+two", "+", "fatal", "1.5"} {
+		if _, err := ParseLevelStrict(input); err == nil {
+			t.Errorf("ParseLevelStrict(%q) expected error", input)
+		}
+	}
+}
+
 func TestHandler_Enabled(t *testing.T) {
 	var buf bytes.Buffer
 	opts := &Options{
@@ -675,6 +714,135 @@ func TestHandler_TraceExtractorReturnsNil(t *testing.T) {
 	}
 }
 
+func TestHandler_TraceMinLevel(t *testing.T) {
+	var buf bytes.Buffer
+	var calls int
+	opts := &Options{
+		Writer: &buf,
+		Format: FormatJSON,
+		Level:  slog.LevelDebug,
+		TraceExtractor: func(ctx context.Context) *TraceInfo {
+			calls++
+			return DefaultTraceExtractor(ctx)
+		},
+		TraceMinLevel: slog.LevelInfo,
+	}
+	handler := NewHandler(opts)
+	defer handler.Close()
+
+	logger := slog.New(handler)
+	ctx := SetTraceID(context.Background(), "t-1")
+	logger.DebugContext(ctx, "details")
+	logger.InfoContext(ctx, "progress")
+	logger.ErrorContext(ctx, "failed")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d: %s", len(lines), buf.String())
+	}
+	if strings.Contains(lines[0], "trace_id") {
+		t.Errorf("expected no trace fields below TraceMinLevel, got: %s", lines[0])
+	}
+	for _, line := range lines[1:] {
+		if !strings.Contains(line, `"trace_id":"t-1"`) {
+			t.Errorf("expected trace fields at or above TraceMinLevel, got: %s", line)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("expected the extractor called only for info and error, got %d calls", calls)
+	}
+}
+
+func TestHandler_TracePlacement(t *testing.T) {
+	for _, format := range []FormatType{FormatJSON, FormatLine} {
+		var buf bytes.Buffer
+		handler := NewHandler(&Options{
+			Writer:         &buf,
+			Format:         format,
+			Level:          slog.LevelInfo,
+			TraceExtractor: DefaultTraceExtractor,
+			TracePlacement: TraceBeforeAttrs,
+			StaticFields:   map[string]any{"service": "api"},
+		})
+
+		logger := slog.New(handler).With("user", "u-1").WithGroup("req").With("path", "/x")
+		ctx := SetSpanID(SetTraceID(context.Background(), "t-1"), "s-1")
+		logger.InfoContext(ctx, "handled", "status", 200)
+		logger.Info("no trace", "status", 500)
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("format %d: expected 2 lines, got %d: %s", format, len(lines), buf.String())
+		}
+		order := []string{`"trace_id":"t-1"`, `"span_id":"s-1"`, `"service":"api"`, `"user":"u-1"`, "path", "status"}
+		last := -1
+		for _, field := range order {
+			i := strings.Index(lines[0], field)
+			if i <= last {
+				t.Errorf("format %d: expected %s after the preceding fields, got: %s", format, field, lines[0])
+			}
+			last = i
+		}
+		if strings.Contains(lines[0], `"req":{"trace_id"`) || strings.Contains(lines[0], "req.trace_id") {
+			t.Errorf("format %d: expected trace fields at the top level, got: %s", format, lines[0])
+		}
+		if strings.Contains(lines[1], "trace_id") || !strings.Contains(lines[1], `"user":"u-1"`) {
+			t.Errorf("format %d: expected the bound attrs and no trace fields, got: %s", format, lines[1])
+		}
+	}
+}
+
+func TestHandler_TracePlacementReusesChain(t *testing.T) {
+	var buf bytes.Buffer
+	handler := NewHandler(&Options{
+		Writer:         &buf,
+		Format:         FormatJSON,
+		Level:          slog.LevelInfo,
+		TraceExtractor: DefaultTraceExtractor,
+		TracePlacement: TraceBeforeAttrs,
+	})
+	h := handler.WithAttrs([]slog.Attr{slog.String("user", "u-1")}).(*Handler)
+	logger := slog.New(h)
+
+	first := SetTraceID(context.Background(), "t-1")
+	logger.InfoContext(first, "a")
+	chain := h.traceChain.Load()
+	logger.InfoContext(first, "b")
+	if h.traceChain.Load() != chain {
+		t.Error("expected records of the same trace to reuse the derived chain")
+	}
+	logger.InfoContext(SetTraceID(context.Background(), "t-2"), "c")
+	if h.traceChain.Load() == chain {
+		t.Error("expected a new trace to derive a new chain")
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	for i, want := range []string{"t-1", "t-1", "t-2"} {
+		if !strings.Contains(lines[i], `"trace_id":"`+want+`","user":"u-1"`) {
+			t.Errorf("line %d: expected trace %s ahead of the bound attrs, got: %s", i, want, lines[i])
+		}
+	}
+}
+
+// errWriter fails every write with err.
+type errWriter struct{ err error }
+
+func (w errWriter) Write([]byte) (int, error) { return 0, w.err }
+
+func TestHandler_SwallowWriteErrors(t *testing.T) {
+	failed := errors.New("write failed")
+	for _, swallow := range []bool{false, true} {
+		handler := NewHandler(&Options{Writer: errWriter{failed}, Format: FormatJSON, Level: slog.LevelInfo, SwallowWriteErrors: swallow})
+		err := handler.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "lost", 0))
+		if swallow && err != nil {
+			t.Errorf("expected nil with SwallowWriteErrors, got %v", err)
+		}
+		if !swallow && !errors.Is(err, failed) {
+			t.Errorf("expected the writer's error by default, got %v", err)
+		}
+	}
+}
+
 func TestHandler_FormatLine_Output(t *testing.T) {
 	var buf bytes.Buffer
 	opts := &Options{
@@ -710,3 +878,691 @@ func TestHandler_Close_NonCloserWriter(t *testing.T) {
 		t.Errorf("Close with non-Closer writer should return nil, got %v", err)
 	}
 }
+
+func TestHandler_SortFields(t *testing.T) {
+	var buf bytes.Buffer
+
+	handler := NewHandler(&Options{
+		Writer:     &buf,
+		Format:     FormatLine,
+		SortFields: true,
+	})
+	defer handler.Close()
+
+	slog.New(handler).Info("sorted", slog.String("b", "2"), slog.String("c", "3"), slog.String("a", "1"))
+
+	out := strings.TrimSpace(buf.String())
+	if !strings.HasSuffix(out, `{"a":"1","b":"2","c":"3"}`) {
+		t.Fatalf("expected alphabetical fields, got: %s", out)
+	}
+}
+
+func TestHandler_MaxLineBytes(t *testing.T) {
+	var buf bytes.Buffer
+
+	handler := NewHandler(&Options{
+		Writer:           &buf,
+		Format:           FormatLine,
+		MaxLineBytes:     80,
+		OverflowStrategy: OverflowSplit,
+	})
+	defer handler.Close()
+
+	slog.New(handler).Info("oversized", slog.String("data", strings.Repeat("y", 300)))
+
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		if len(line)+1 > 80 {
+			t.Fatalf("line exceeds cap: %d bytes: %q", len(line)+1, line)
+		}
+	}
+}
+
+func TestHandler_IncludeAttrCount(t *testing.T) {
+	var buf bytes.Buffer
+	handler := NewHandler(&Options{
+		Writer:           &buf,
+		Format:           FormatJSON,
+		Level:            slog.LevelInfo,
+		StaticFields:     map[string]any{"service": "api"},
+		TraceExtractor:   DefaultTraceExtractor,
+		IncludeAttrCount: true,
+	})
+
+	logger := slog.New(handler)
+	ctx := SetTraceID(context.Background(), "t-1")
+	logger.InfoContext(ctx, "none")
+	logger.With("user", "u-1").InfoContext(ctx, "two", "status", 200)
+	logger.WithGroup("req").With("a", 1, "b", 2).Info("group", slog.Group("db", "rows", 7, "table", "users"))
+
+	want := []float64{1, 3, 4}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("expected %d lines, got %d: %s", len(want), len(lines), buf.String())
+	}
+	for i, line := range lines {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("failed to parse JSON: %v", err)
+		}
+		count, ok := entry["attr_count"]
+		if !ok {
+			count = entry["req"].(map[string]any)["attr_count"]
+		}
+		if count != want[i] {
+			t.Errorf("line %d: expected attr_count %v, got %v: %s", i, want[i], count, line)
+		}
+	}
+}
+
+func TestHandler_IncludeRawLevel(t *testing.T) {
+	lower := func(groups []string, a slog.Attr) slog.Attr {
+		if len(groups) == 0 && a.Key == slog.LevelKey {
+			return slog.String(a.Key, strings.ToLower(a.Value.String()))
+		}
+		return a
+	}
+
+	var jsonBuf bytes.Buffer
+	handler := NewHandler(&Options{
+		Writer:          &jsonBuf,
+		Format:          FormatJSON,
+		ReplaceAttr:     lower,
+		IncludeRawLevel: true,
+	})
+	slog.New(handler).Error("failed")
+
+	var entry map[string]any
+	if err := json.Unmarshal(jsonBuf.Bytes(), &entry); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	if entry["level"] != "error" {
+		t.Errorf("expected display level error, got %v", entry["level"])
+	}
+	if entry["level_raw"] != "ERROR" {
+		t.Errorf("expected level_raw ERROR, got %v", entry["level_raw"])
+	}
+
+	var lineBuf bytes.Buffer
+	handler = NewHandler(&Options{
+		Writer:            &lineBuf,
+		Format:            FormatLine,
+		ReplaceAttr:       lower,
+		IncludeRawLevel:   true,
+		RawLevelFieldName: "severity",
+	})
+	slog.New(handler).Warn("careful")
+
+	out := strings.TrimSpace(lineBuf.String())
+	if !strings.Contains(out, "] warn: careful") || !strings.Contains(out, `"severity":"WARN"`) {
+		t.Errorf("expected lowercase level and canonical severity field, got: %s", out)
+	}
+}
+
+func TestHandler_AttrExtractor(t *testing.T) {
+	var buf bytes.Buffer
+
+	attrExtractor := func(ctx context.Context) []slog.Attr {
+		var attrs []slog.Attr
+		if tenant, ok := ctx.Value("tenant").(string); ok {
+			attrs = append(attrs, slog.String("tenant", tenant))
+		}
+		if user, ok := ctx.Value("user").(string); ok {
+			attrs = append(attrs, slog.String("user", user))
+		}
+		return attrs
+	}
+
+	var seenByRecordHandler bool
+	recordHandler := func(ctx context.Context, r *slog.Record) {
+		// RecordHandler runs after AttrExtractor, so it can see the extracted fields
+		r.Attrs(func(a slog.Attr) bool {
+			if a.Key == "tenant" {
+				seenByRecordHandler = true
+			}
+			return true
+		})
+	}
+
+	handler := NewHandler(&Options{
+		Writer:         &buf,
+		Format:         FormatJSON,
+		Level:          slog.LevelInfo,
+		TraceExtractor: DefaultTraceExtractor,
+		AttrExtractor:  attrExtractor,
+		RecordHandler:  recordHandler,
+	})
+	defer handler.Close()
+	logger := slog.New(handler)
+
+	ctx := context.WithValue(context.Background(), "tenant", "acme")
+	ctx = context.WithValue(ctx, "user", "u-42")
+	ctx = context.WithValue(ctx, "trace_id", "trace-123")
+	logger.InfoContext(ctx, "with context fields")
+
+	var logEntry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &logEntry); err != nil {
+		t.Fatalf("failed to parse JSON: %v, output: %s", err, buf.String())
+	}
+	if logEntry["tenant"] != "acme" || logEntry["user"] != "u-42" {
+		t.Errorf("expected tenant and user from context, got %v", logEntry)
+	}
+	if logEntry["trace_id"] != "trace-123" {
+		t.Errorf("expected trace_id alongside extracted attrs, got %v", logEntry["trace_id"])
+	}
+	if !seenByRecordHandler {
+		t.Error("expected RecordHandler to see the extracted attrs")
+	}
+
+	buf.Reset()
+	logger.Info("without context fields")
+	if strings.Contains(buf.String(), "tenant") {
+		t.Errorf("expected no extracted attrs for an empty context, got: %s", buf.String())
+	}
+}
+
+func TestHandler_SourceLevel(t *testing.T) {
+	for _, format := range []FormatType{FormatLine, FormatJSON, FormatText} {
+		var buf bytes.Buffer
+		handler := NewHandler(&Options{
+			Writer:      &buf,
+			Format:      format,
+			Level:       slog.LevelInfo,
+			SourceLevel: slog.LevelWarn,
+		})
+		logger := slog.New(handler)
+
+		logger.Info("quiet")
+		logger.Error("loud")
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("format %v: expected 2 lines, got %d: %s", format, len(lines), buf.String())
+		}
+		if strings.Contains(lines[0], "source") {
+			t.Errorf("format %v: expected no source below SourceLevel, got: %s", format, lines[0])
+		}
+		if !strings.Contains(lines[1], "source") || !strings.Contains(lines[1], "handler_test.go") {
+			t.Errorf("format %v: expected source at SourceLevel, got: %s", format, lines[1])
+		}
+	}
+}
+
+func TestHandler_LevelFormatter(t *testing.T) {
+	wants := map[FormatType]string{
+		FormatLine: "] W: slow",
+		FormatJSON: `"level":"W"`,
+		FormatText: "level=W",
+	}
+	for format, want := range wants {
+		var buf bytes.Buffer
+		handler := NewHandler(&Options{
+			Writer:         &buf,
+			Format:         format,
+			Level:          slog.LevelInfo,
+			LevelFormatter: letterLevel,
+		})
+		slog.New(handler).Warn("slow")
+
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("format %v: expected %q, got: %s", format, want, buf.String())
+		}
+	}
+}
+
+func TestHandler_StaticFields(t *testing.T) {
+	var buf bytes.Buffer
+
+	handler := NewHandler(&Options{
+		Writer: &buf,
+		Format: FormatJSON,
+		Level:  slog.LevelInfo,
+		StaticFields: map[string]any{
+			"version":    "1.2.3",
+			"commit":     "abc123",
+			"build_time": "2024-01-01T00:00:00Z",
+		},
+	})
+	defer handler.Close()
+	logger := slog.New(handler)
+
+	logger.Info("first")
+	logger.WithGroup("req").Info("second", slog.String("id", "r1"))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d: %s", len(lines), buf.String())
+	}
+	for i, line := range lines {
+		var logEntry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &logEntry); err != nil {
+			t.Fatalf("failed to parse JSON: %v, output: %s", err, line)
+		}
+		if logEntry["version"] != "1.2.3" || logEntry["commit"] != "abc123" || logEntry["build_time"] != "2024-01-01T00:00:00Z" {
+			t.Errorf("line %d: expected static fields at the root, got %v", i, logEntry)
+		}
+	}
+	if !strings.Contains(lines[0], `"build_time":"2024-01-01T00:00:00Z","commit":"abc123","version":"1.2.3"`) {
+		t.Errorf("expected static fields in key order, got: %s", lines[0])
+	}
+	if !strings.Contains(lines[1], `"req":{"id":"r1"}`) {
+		t.Errorf("expected grouped record attrs, got: %s", lines[1])
+	}
+}
+
+func TestHandler_SourceWithoutPC(t *testing.T) {
+	record := slog.NewRecord(time.Now(), slog.LevelInfo, "bridged", 0)
+
+	for _, placeholder := range []string{"", "unknown"} {
+		wants := map[FormatType]string{
+			FormatLine: `INFO: bridged {"source":"unknown"}`,
+			FormatJSON: `"source":"unknown"`,
+			FormatText: "source=unknown",
+		}
+		for format, want := range wants {
+			var buf bytes.Buffer
+			handler := NewHandler(&Options{
+				Writer:            &buf,
+				Format:            format,
+				Level:             slog.LevelInfo,
+				AddSource:         true,
+				SourcePlaceholder: placeholder,
+			})
+			if err := handler.Handle(context.Background(), record); err != nil {
+				t.Fatalf("Handle failed: %v", err)
+			}
+
+			out := buf.String()
+			if placeholder == "" {
+				if strings.Contains(out, "source") {
+					t.Errorf("format %v: expected source omitted for PC 0, got: %s", format, out)
+				}
+				continue
+			}
+			if !strings.Contains(out, want) {
+				t.Errorf("format %v: expected %q for PC 0, got: %s", format, want, out)
+			}
+		}
+	}
+}
+
+func TestHandler_RequireMessage(t *testing.T) {
+	var buf bytes.Buffer
+
+	dropping := slog.New(NewHandler(&Options{Writer: &buf, Level: slog.LevelInfo, RequireMessage: true}))
+	dropping.Info("", slog.String("k", "v"))
+	if buf.Len() != 0 {
+		t.Fatalf("expected empty-message record dropped, got: %s", buf.String())
+	}
+	dropping.Info("kept")
+	if !strings.Contains(buf.String(), "INFO: kept") {
+		t.Fatalf("expected non-empty message written, got: %s", buf.String())
+	}
+
+	buf.Reset()
+	substituting := slog.New(NewHandler(&Options{
+		Writer:                  &buf,
+		Level:                   slog.LevelInfo,
+		RequireMessage:          true,
+		EmptyMessagePlaceholder: "(no message)",
+	}))
+	substituting.Info("", slog.String("k", "v"))
+	if !strings.Contains(buf.String(), `INFO: (no message) {"k":"v"}`) {
+		t.Fatalf("expected placeholder message, got: %s", buf.String())
+	}
+}
+
+func TestTraceContextHelpers(t *testing.T) {
+	ctx := context.Background()
+	if GetTraceID(ctx) != "" || GetSpanID(ctx) != "" {
+		t.Fatal("expected no IDs on an empty context")
+	}
+
+	ctx = SetTraceID(ctx, "trace-abc")
+	ctx = SetSpanID(ctx, "span-def")
+
+	if got := GetTraceID(ctx); got != "trace-abc" {
+		t.Errorf("expected trace-abc from GetTraceID, got %q", got)
+	}
+	if got := GetSpanID(ctx); got != "span-def" {
+		t.Errorf("expected span-def from GetSpanID, got %q", got)
+	}
+
+	info := DefaultTraceExtractor(ctx)
+	if info == nil || info.TraceID != "trace-abc" || info.SpanID != "span-def" {
+		t.Errorf("expected DefaultTraceExtractor to read helper values, got %+v", info)
+	}
+}
+
+func TestHandler_IncludeDeadline(t *testing.T) {
+	var buf bytes.Buffer
+
+	handler := NewHandler(&Options{
+		Writer:          &buf,
+		Format:          FormatJSON,
+		Level:           slog.LevelInfo,
+		IncludeDeadline: true,
+	})
+	logger := slog.New(handler)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	logger.InfoContext(ctx, "with deadline")
+
+	var logEntry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &logEntry); err != nil {
+		t.Fatalf("failed to parse JSON: %v, output: %s", err, buf.String())
+	}
+	ns, ok := logEntry["deadline_in"].(float64)
+	if !ok {
+		t.Fatalf("expected deadline_in duration, got %v", logEntry["deadline_in"])
+	}
+	if left := time.Duration(ns); left <= 4*time.Second || left > 5*time.Second {
+		t.Errorf("expected deadline_in close to 5s, got %v", left)
+	}
+
+	buf.Reset()
+	logger.InfoContext(context.Background(), "no deadline")
+	if strings.Contains(buf.String(), "deadline_in") {
+		t.Errorf("expected no deadline_in without a deadline, got: %s", buf.String())
+	}
+}
+
+func TestHandler_StringifyValues(t *testing.T) {
+	var buf bytes.Buffer
+
+	handler := NewHandler(&Options{Writer: &buf, Format: FormatJSON, Level: slog.LevelInfo, StringifyValues: true})
+	slog.New(handler).Info("stats",
+		slog.Int("count", 42),
+		slog.Bool("ok", true),
+		slog.Float64("ratio", 0.5),
+		slog.Group("db", slog.Uint64("rows", 7), slog.String("name", "main")),
+	)
+
+	out := buf.String()
+	for _, want := range []string{`"count":"42"`, `"ok":"true"`, `"ratio":"0.5"`, `"db":{"rows":"7","name":"main"}`, `"level":"INFO"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %s, got: %s", want, out)
+		}
+	}
+
+	// line output keeps native types
+	buf.Reset()
+	line := NewHandler(&Options{Writer: &buf, Format: FormatLine, Level: slog.LevelInfo, StringifyValues: true})
+	slog.New(line).Info("stats", slog.Int("count", 42))
+	if !strings.Contains(buf.String(), `{"count":42}`) {
+		t.Errorf("expected line format unaffected, got: %s", buf.String())
+	}
+}
+
+func TestHandler_TimeEncoding(t *testing.T) {
+	ts := time.Date(2024, 5, 6, 7, 8, 9, 123456789, time.UTC)
+	cases := map[TimeEncoding]string{
+		TimeEncodingEpochSeconds: `"time":1714979289,`,
+		TimeEncodingEpochMillis:  `"time":1714979289123,`,
+		TimeEncodingEpochNanos:   `"time":1714979289123456789,`,
+		TimeEncodingRFC3339:      `"time":"2024-05-06T07:08:09Z",`,
+		TimeEncodingLayout:       `"time":"2024-05-06 07:08:09",`,
+	}
+	for enc, want := range cases {
+		var buf bytes.Buffer
+		handler := NewHandler(&Options{Writer: &buf, Format: FormatJSON, Level: slog.LevelInfo, TimeEncoding: enc})
+		r := slog.NewRecord(ts, slog.LevelInfo, "encoded", 0)
+		r.AddAttrs(slog.Time("at", ts))
+		if err := handler.Handle(context.Background(), r); err != nil {
+			t.Fatalf("Handle failed: %v", err)
+		}
+		out := buf.String()
+		if !strings.Contains(out, want) {
+			t.Errorf("%s: expected %s, got: %s", enc, want, out)
+		}
+		if !strings.Contains(out, `"at":"2024-05-06T07:08:09.123456789Z"`) {
+			t.Errorf("%s: expected time attrs untouched without EncodeTimeAttrs, got: %s", enc, out)
+		}
+	}
+}
+
+func TestHandler_EnabledLevels(t *testing.T) {
+	handler := NewHandler(&Options{Writer: io.Discard, Level: slog.LevelInfo})
+	want := map[slog.Level]bool{slog.LevelDebug: false, slog.LevelInfo: true, slog.LevelWarn: true, slog.LevelError: true}
+	if got := handler.EnabledLevels(); !maps.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	var console slog.LevelVar
+	console.Set(slog.LevelWarn)
+	mirrored := NewHandler(&Options{Writer: io.Discard, Level: slog.LevelError, ConsoleLevel: &console, ConsoleWriter: io.Discard})
+	want = map[slog.Level]bool{slog.LevelDebug: false, slog.LevelInfo: false, slog.LevelWarn: true, slog.LevelError: true}
+	if got := mirrored.EnabledLevels(); !maps.Equal(got, want) {
+		t.Errorf("expected ConsoleLevel to enable warn, %v, got %v", want, got)
+	}
+	console.Set(slog.LevelDebug)
+	want[slog.LevelDebug], want[slog.LevelInfo] = true, true
+	if got := mirrored.EnabledLevels(); !maps.Equal(got, want) {
+		t.Errorf("expected the ConsoleLevel change reflected, %v, got %v", want, got)
+	}
+}
+
+func TestHandler_SetTimeFormat(t *testing.T) {
+	const millis = "2006-01-02 15:04:05.000"
+	buf := &syncBuffer{}
+	handler := NewHandler(&Options{Writer: buf, Format: FormatLine, Level: slog.LevelInfo})
+	// derived handlers share the layout with the one SetTimeFormat is called on
+	logger := slog.New(handler.WithAttrs([]slog.Attr{slog.String("worker", "w")}))
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 200 {
+				logger.Info("tick")
+			}
+		}()
+	}
+	for range 50 {
+		logger.Info("tick")
+	}
+	handler.SetTimeFormat(millis)
+	wg.Wait()
+	logger.Info("tick")
+
+	var seconds, withMillis int
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		end := strings.Index(line, "]")
+		if !strings.HasPrefix(line, "[") || end < 0 {
+			t.Fatalf("unexpected line: %q", line)
+		}
+		ts := line[1:end]
+		if _, err := time.Parse(millis, ts); err == nil && len(ts) == len(millis) {
+			withMillis++
+		} else if _, err := time.Parse(defaultTimeLayout, ts); err == nil && len(ts) == len(defaultTimeLayout) {
+			seconds++
+		} else {
+			t.Errorf("timestamp %q matches neither layout", ts)
+		}
+	}
+	if seconds < 50 || withMillis < 1 {
+		t.Errorf("expected both layouts, got %d with seconds and %d with millis", seconds, withMillis)
+	}
+}
+
+func TestHandler_TimeEncodingAttrsAndLine(t *testing.T) {
+	ts := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+
+	var buf bytes.Buffer
+	handler := NewHandler(&Options{
+		Writer:          &buf,
+		Format:          FormatJSON,
+		Level:           slog.LevelInfo,
+		TimeEncoding:    TimeEncodingEpochSeconds,
+		EncodeTimeAttrs: true,
+	})
+	r := slog.NewRecord(ts, slog.LevelInfo, "encoded", 0)
+	r.AddAttrs(slog.Group("job", slog.Time("started", ts)))
+	if err := handler.Handle(context.Background(), r); err != nil {
+		t.Fatalf("Handle failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"job":{"started":1714979289}`) {
+		t.Errorf("expected encoded time attr, got: %s", buf.String())
+	}
+
+	buf.Reset()
+	line := NewHandler(&Options{Writer: &buf, Format: FormatLine, Level: slog.LevelInfo, TimeEncoding: TimeEncodingEpochMillis})
+	if err := line.Handle(context.Background(), slog.NewRecord(ts, slog.LevelInfo, "readable", 0)); err != nil {
+		t.Fatalf("Handle failed: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "[2024-05-06 07:08:09] INFO: readable") {
+		t.Errorf("expected a readable time in line format, got: %s", buf.String())
+	}
+}
+
+func TestNewHandlerWithError(t *testing.T) {
+	var buf bytes.Buffer
+
+	if _, err := NewHandlerWithError(&Options{Writer: &buf, Format: FormatType(42)}); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+	if _, err := NewHandlerWithError(&Options{
+		Writer:        &buf,
+		FormatByLevel: map[slog.Level]FormatType{slog.LevelError: FormatType(-1)},
+	}); err == nil {
+		t.Error("expected an error for an unknown FormatByLevel format")
+	}
+
+	handler, err := NewHandlerWithError(&Options{Writer: &buf, Format: FormatJSON})
+	if err != nil || handler == nil {
+		t.Fatalf("expected a valid handler, got %v", err)
+	}
+
+	// NewHandler keeps the silent fallback
+	buf.Reset()
+	slog.New(NewHandler(&Options{Writer: &buf, Format: FormatType(42)})).Info("fallback")
+	if !strings.Contains(buf.String(), "INFO: fallback") {
+		t.Errorf("expected line format fallback, got: %s", buf.String())
+	}
+}
+
+func TestHandler_WithCorrelationID(t *testing.T) {
+	var buf bytes.Buffer
+
+	base := NewHandler(&Options{Writer: &buf, Format: FormatJSON, Level: slog.LevelInfo})
+	handler := base.WithCorrelationID("")
+	id := handler.CorrelationID()
+	if len(id) != 32 {
+		t.Fatalf("expected a generated 128-bit hex ID, got %q", id)
+	}
+
+	logger := slog.New(handler)
+	db := logger.With(slog.String("subsystem", "db"))
+	cache := logger.With(slog.String("subsystem", "cache")).WithGroup("cache")
+	db.Info("query")
+	cache.Info("miss", slog.String("key", "k1"))
+	slog.New(base).Info("unrelated")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d: %s", len(lines), buf.String())
+	}
+	if !strings.Contains(lines[0], `"correlation_id":"`+id+`"`) {
+		t.Errorf("expected db child to carry the correlation ID, got: %s", lines[0])
+	}
+	if !strings.Contains(lines[1], `"correlation_id":"`+id+`"`) {
+		t.Errorf("expected cache child to carry the same correlation ID, got: %s", lines[1])
+	}
+	if strings.Contains(lines[2], "correlation_id") {
+		t.Errorf("expected the original handler unchanged, got: %s", lines[2])
+	}
+
+	if got := base.WithCorrelationID("req-7").CorrelationID(); got != "req-7" {
+		t.Errorf("expected assigned ID req-7, got %q", got)
+	}
+}
+
+func TestHandler_WithDestination(t *testing.T) {
+	var primary, forensics bytes.Buffer
+
+	handler := NewHandler(&Options{
+		Writer:  &primary,
+		Writers: map[string]io.Writer{"forensics": &forensics},
+		Format:  FormatJSON,
+		Level:   slog.LevelInfo,
+	})
+	logger := slog.New(handler).With(slog.String("app", "demo"))
+	ctx := context.Background()
+
+	logger.InfoContext(ctx, "normal")
+	logger.WarnContext(WithDestination(ctx, "forensics"), "suspicious login", slog.String("user", "u1"))
+	logger.InfoContext(WithDestination(ctx, "unknown"), "unrouted")
+
+	if out := primary.String(); !strings.Contains(out, `"msg":"normal"`) || !strings.Contains(out, `"msg":"unrouted"`) {
+		t.Errorf("expected unflagged records in the primary writer, got: %s", out)
+	}
+	if strings.Contains(primary.String(), "suspicious") {
+		t.Errorf("expected the flagged record kept out of the primary writer, got: %s", primary.String())
+	}
+	out := strings.TrimSpace(forensics.String())
+	if strings.Count(out, "\n") != 0 || !strings.Contains(out, `"msg":"suspicious login"`) || !strings.Contains(out, `"app":"demo"`) {
+		t.Errorf("expected only the flagged record, with handler attrs, in the forensics writer, got: %s", out)
+	}
+}
+
+func TestHandler_WriterState(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "app.log")
+	handler := NewHandler(&Options{LogPath: logPath, Format: FormatLine, Level: slog.LevelInfo})
+	defer handler.Close()
+
+	slog.New(handler).Info("ready")
+	st, ok := handler.WriterState()
+	if !ok || st.CurrentFile != logPath || st.Size == 0 {
+		t.Errorf("expected the file writer's state, got %+v (ok=%v)", st, ok)
+	}
+
+	if _, ok := NewHandler(&Options{Writer: io.Discard}).WriterState(); ok {
+		t.Error("expected no state for a plain writer")
+	}
+}
+
+func TestHandler_IncludeGroupPath(t *testing.T) {
+	var buf bytes.Buffer
+
+	handler := NewHandler(&Options{Writer: &buf, Format: FormatLine, Level: slog.LevelInfo, IncludeGroupPath: true})
+	logger := slog.New(handler)
+	logger.Info("top")
+	logger.WithGroup("request").WithGroup("db").Info("query", slog.String("table", "users"))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d: %s", len(lines), buf.String())
+	}
+	if strings.Contains(lines[0], `"group"`) {
+		t.Errorf("expected no group path outside groups, got: %s", lines[0])
+	}
+	if !strings.Contains(lines[1], `{"group":"request.db","request.db.table":"users"}`) {
+		t.Errorf("expected a top-level group path, got: %s", lines[1])
+	}
+
+	buf.Reset()
+	handler = NewHandler(&Options{Writer: &buf, Format: FormatJSON, Level: slog.LevelInfo, IncludeGroupPath: true, GroupPathFieldName: "gp"})
+	slog.New(handler).WithGroup("request").WithGroup("db").Info("query")
+	if !strings.Contains(buf.String(), `"request":{"db":{"gp":"request.db"}}`) {
+		t.Errorf("expected the group path added to the record, got: %s", buf.String())
+	}
+}
+
+func TestHandler_CloseMarker(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "app.log")
+	handler := NewHandler(&Options{LogPath: logPath, Format: FormatLine, Level: slog.LevelInfo, CloseMarker: "#glog-closed"})
+
+	slog.New(handler).Info("last")
+	if err := handler.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+	if !strings.HasSuffix(string(data), "#glog-closed\n") {
+		t.Errorf("expected the close marker as the last line, got: %q", data)
+	}
+}
@@ -0,0 +1,19 @@
+package glog
+
+import "log/slog"
+
+// lazyValue is a thunk evaluated when a record is written.
+type lazyValue func() any
+
+// LogValue calls the thunk.
+func (f lazyValue) LogValue() slog.Value {
+	return slog.AnyValue(f())
+}
+
+// Lazy wraps an expensive computation as a slog.LogValuer. f is called only when a record
+// carrying the value is actually written, never for records filtered out by level:
+//
+//	logger.Debug("state", slog.Any("dump", glog.Lazy(func() any { return expensiveDump() })))
+func Lazy(f func() any) slog.LogValuer {
+	return lazyValue(f)
+}
@@ -0,0 +1,46 @@
+package glog
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLazy(t *testing.T) {
+	for _, format := range []FormatType{FormatLine, FormatJSON, FormatText} {
+		var buf bytes.Buffer
+		handler := NewHandler(&Options{Writer: &buf, Format: format, Level: slog.LevelInfo})
+		logger := slog.New(handler)
+
+		calls := 0
+		lazy := Lazy(func() any {
+			calls++
+			return "computed"
+		})
+
+		logger.Debug("filtered", slog.Any("dump", lazy))
+		if calls != 0 {
+			t.Errorf("format %v: thunk called %d times for a filtered record", format, calls)
+		}
+
+		logger.Info("emitted", slog.Any("dump", lazy))
+		if calls != 1 {
+			t.Errorf("format %v: expected thunk called once, got %d", format, calls)
+		}
+		if !strings.Contains(buf.String(), "computed") {
+			t.Errorf("format %v: expected resolved value in output, got: %s", format, buf.String())
+		}
+	}
+}
+
+func TestLineHandler_ResolvesLogValuerGroups(t *testing.T) {
+	var buf bytes.Buffer
+
+	h := NewLineHandler(&buf, nil)
+	slog.New(h).Info("resolved", slog.Any("n", Lazy(func() any { return 42 })))
+
+	if out := strings.TrimSpace(buf.String()); !strings.HasSuffix(out, `{"n":42}`) {
+		t.Fatalf("expected resolved number, got: %s", out)
+	}
+}
@@ -0,0 +1,85 @@
+package glog
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+)
+
+// levelBand is the handler used for records at or above level (up to the next band).
+type levelBand struct {
+	level   slog.Leveler
+	handler slog.Handler
+}
+
+// levelSwitchHandler dispatches each record to a handler chosen by the record's level, so
+// different level ranges can be encoded differently (FormatByLevel) or with and without source
+// (SourceLevel).
+type levelSwitchHandler struct {
+	base  slog.Handler // for records below every band; it also decides Enabled
+	bands []levelBand  // sorted by level, ascending
+}
+
+// newLevelSwitchHandler creates a levelSwitchHandler using base below the lowest band.
+func newLevelSwitchHandler(base slog.Handler, bands []levelBand) *levelSwitchHandler {
+	sort.SliceStable(bands, func(i, j int) bool {
+		return bands[i].level.Level() < bands[j].level.Level()
+	})
+	return &levelSwitchHandler{base: base, bands: bands}
+}
+
+// newFormatByLevelHandler creates a levelSwitchHandler using base below the lowest level in formats
+// and newHandler(format) from each listed level upwards.
+func newFormatByLevelHandler(base slog.Handler, formats map[slog.Level]FormatType, newHandler func(FormatType) slog.Handler) *levelSwitchHandler {
+	bands := make([]levelBand, 0, len(formats))
+	for level, format := range formats {
+		bands = append(bands, levelBand{level: level, handler: newHandler(format)})
+	}
+	return newLevelSwitchHandler(base, bands)
+}
+
+// Enabled reports whether the base handler is enabled; all bands share its level threshold.
+func (h *levelSwitchHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.base.Enabled(ctx, level)
+}
+
+// Handle passes r to the handler of the highest band at or below its level.
+func (h *levelSwitchHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.handlerFor(r.Level).Handle(ctx, r)
+}
+
+func (h *levelSwitchHandler) handlerFor(level slog.Level) slog.Handler {
+	handler := h.base
+	for _, b := range h.bands {
+		if level < b.level.Level() {
+			break
+		}
+		handler = b.handler
+	}
+	return handler
+}
+
+// WithAttrs returns a new levelSwitchHandler with attrs added to every band.
+func (h *levelSwitchHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.derive(func(handler slog.Handler) slog.Handler {
+		return handler.WithAttrs(attrs)
+	})
+}
+
+// WithGroup returns a new levelSwitchHandler with the group opened in every band.
+func (h *levelSwitchHandler) WithGroup(name string) slog.Handler {
+	return h.derive(func(handler slog.Handler) slog.Handler {
+		return handler.WithGroup(name)
+	})
+}
+
+func (h *levelSwitchHandler) derive(f func(slog.Handler) slog.Handler) *levelSwitchHandler {
+	c := &levelSwitchHandler{
+		base:  f(h.base),
+		bands: make([]levelBand, len(h.bands)),
+	}
+	for i, b := range h.bands {
+		c.bands[i] = levelBand{level: b.level, handler: f(b.handler)}
+	}
+	return c
+}
@@ -0,0 +1,75 @@
+package glog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestHandler_FormatByLevel(t *testing.T) {
+	var buf bytes.Buffer
+
+	handler := NewHandler(&Options{
+		Writer:        &buf,
+		Format:        FormatLine,
+		Level:         slog.LevelDebug,
+		FormatByLevel: map[slog.Level]FormatType{slog.LevelError: FormatJSON},
+	})
+	defer handler.Close()
+	logger := slog.New(handler).With(slog.String("app", "demo")).WithGroup("req")
+
+	logger.Info("compact", slog.String("id", "r1"))
+	logger.Error("verbose", slog.String("id", "r2"))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d: %s", len(lines), buf.String())
+	}
+	if !strings.Contains(lines[0], `INFO: compact {"app":"demo","req.id":"r1"}`) {
+		t.Errorf("expected info record in line format, got: %s", lines[0])
+	}
+
+	var logEntry map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[1]), &logEntry); err != nil {
+		t.Fatalf("expected error record as JSON: %v, output: %s", err, lines[1])
+	}
+	if logEntry["msg"] != "verbose" || logEntry["app"] != "demo" {
+		t.Errorf("unexpected JSON record: %v", logEntry)
+	}
+	if req, _ := logEntry["req"].(map[string]interface{}); req["id"] != "r2" {
+		t.Errorf("expected grouped attr in JSON record, got: %v", logEntry["req"])
+	}
+}
+
+func TestLevelSwitchHandler_Bands(t *testing.T) {
+	var buf bytes.Buffer
+
+	h := NewHandler(&Options{
+		Writer: &buf,
+		Format: FormatLine,
+		Level:  slog.LevelDebug,
+		FormatByLevel: map[slog.Level]FormatType{
+			slog.LevelWarn:  FormatText,
+			slog.LevelError: FormatJSON,
+		},
+	})
+	logger := slog.New(h)
+	logger.Debug("d")
+	logger.Warn("w")
+	logger.Log(context.Background(), slog.LevelWarn+2, "w2")
+	logger.Error("e")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	wants := []string{"DEBUG: d", "level=WARN msg=w", "level=WARN+2 msg=w2", `"msg":"e"`}
+	if len(lines) != len(wants) {
+		t.Fatalf("expected %d lines, got %d: %s", len(wants), len(lines), buf.String())
+	}
+	for i, want := range wants {
+		if !strings.Contains(lines[i], want) {
+			t.Errorf("line %d: expected %q, got: %s", i, want, lines[i])
+		}
+	}
+}
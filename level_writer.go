@@ -0,0 +1,58 @@
+package glog
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+)
+
+// LevelWriter is a writer that wants the level of each record along with its formatted bytes, such as
+// EventLogWriter. A Handler writing to a LevelWriter (as Writer, in Writers or as Secondary) calls
+// WriteLevel with the record's level for every write an encoder makes for that record.
+type LevelWriter interface {
+	io.Writer
+	WriteLevel(level slog.Level, p []byte) (int, error)
+}
+
+// levelCapture is the writer the encoders write into when the real writer is a LevelWriter: it
+// passes each write on with the level of the record being encoded.
+type levelCapture struct {
+	mu    sync.Mutex // held for the whole encoding of one record
+	w     LevelWriter
+	level slog.Level
+}
+
+func (c *levelCapture) Write(p []byte) (int, error) {
+	return c.w.WriteLevel(c.level, p)
+}
+
+// levelHandler encodes each record through next with the record's level set on the capture.
+type levelHandler struct {
+	next    slog.Handler
+	capture *levelCapture // shared with derived handlers
+}
+
+// Enabled reports whether next is enabled.
+func (h *levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle encodes r with its level set for the LevelWriter.
+func (h *levelHandler) Handle(ctx context.Context, r slog.Record) error {
+	c := h.capture
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.level = r.Level
+	return h.next.Handle(ctx, r)
+}
+
+// WithAttrs returns a new levelHandler sharing this handler's capture.
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{next: h.next.WithAttrs(attrs), capture: h.capture}
+}
+
+// WithGroup returns a new levelHandler sharing this handler's capture.
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{next: h.next.WithGroup(name), capture: h.capture}
+}
@@ -0,0 +1,57 @@
+package glog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// levelRecorder is a LevelWriter that records the level of each write.
+type levelRecorder struct {
+	levels []slog.Level
+	lines  []string
+}
+
+func (w *levelRecorder) Write(p []byte) (int, error) {
+	return w.WriteLevel(slog.LevelInfo-100, p)
+}
+
+func (w *levelRecorder) WriteLevel(level slog.Level, p []byte) (int, error) {
+	w.levels = append(w.levels, level)
+	w.lines = append(w.lines, string(p))
+	return len(p), nil
+}
+
+func TestHandler_LevelWriter(t *testing.T) {
+	letters := func(l slog.Level) string { return l.String()[:1] }
+	for _, format := range []FormatType{FormatLine, FormatJSON, FormatText} {
+		w := &levelRecorder{}
+		logger := slog.New(NewHandler(&Options{
+			Writer:           w,
+			Format:           format,
+			Level:            slog.LevelDebug,
+			LevelFormatter:   letters,
+			BytesTransformer: bytes.ToUpper,
+		}))
+		// neither the attribute nor the message may be taken for the level
+		logger.Info("ERROR in message", "level", "ERROR")
+		logger.Log(context.Background(), slog.LevelError+2, "failed")
+		logger.Warn("slow")
+		logger.Debug("detail")
+
+		want := []slog.Level{slog.LevelInfo, slog.LevelError + 2, slog.LevelWarn, slog.LevelDebug}
+		if len(w.levels) != len(want) {
+			t.Fatalf("format %v: expected %d writes, got %d: %q", format, len(want), len(w.levels), w.lines)
+		}
+		for i := range want {
+			if w.levels[i] != want[i] {
+				t.Errorf("format %v: write %d (%q): expected level %v, got %v", format, i, w.lines[i], want[i], w.levels[i])
+			}
+		}
+		if !strings.Contains(w.lines[1], "FAILED") {
+			t.Errorf("format %v: expected the transformed record, got %q", format, w.lines[1])
+		}
+	}
+}
@@ -6,26 +6,172 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"sort"
 	"strings"
 	"sync"
+	"unicode/utf8"
 )
 
 // LineHandler implements slog.Handler and writes single-line text logs in the form:
 // [2024-01-01 12:00:00] LEVEL: message {"key":"val",...}
 //
 // Time uses "2006-01-02 15:04:05"; level is string (INFO, ERROR, etc.); structured
-// fields are collected as a JSON object at the end. Supports Level, ReplaceAttr, WithAttrs, WithGroup.
+// fields are collected as a JSON object at the end, in the order they were added (sorted by key with
+// SortFields); group values are flattened into keys joined with the group separator, like groups
+// opened with WithGroup. A repeated key keeps its first position and its last value.
+// Supports Level, AddSource, ReplaceAttr, WithAttrs, WithGroup. With AddSource, the
+// source location is the first field, as "source":"file:line".
 type LineHandler struct {
 	w      io.Writer
-	opts   slog.HandlerOptions
+	opts   LineHandlerOptions
 	mu     sync.Mutex  // guards concurrent writes
-	attrs  []slog.Attr // attributes from WithAttrs
+	attrs  []groupAttr // attributes from WithAttrs
 	groups []string    // group prefix from WithGroup
 }
 
+// groupAttr is an attribute from WithAttrs together with the groups that were open when it was added.
+type groupAttr struct {
+	groups []string
+	prefix string // groups joined with the group separator
+	attr   slog.Attr
+}
+
+// LineHandlerOptions configures a LineHandler beyond the standard slog.HandlerOptions.
+type LineHandlerOptions struct {
+	slog.HandlerOptions
+	// SortFields sorts the structured fields by key before serialization; otherwise they keep the order
+	// they were added in.
+	SortFields bool
+	// MaxLineBytes caps the size of each written line, including the trailing newline; 0 means no limit.
+	MaxLineBytes int
+	// OverflowStrategy decides what happens to lines longer than MaxLineBytes.
+	OverflowStrategy OverflowStrategy
+	// LevelFormatter renders the level string (e.g. "I" for info); it runs before ReplaceAttr.
+	// nil uses slog.Level's String.
+	LevelFormatter func(slog.Level) string
+	// GroupSeparator joins group names and keys into field keys (e.g. "_" for "http_method"); default ".".
+	GroupSeparator string
+	// GroupPathKey, when set, adds a top-level field with this key holding the open WithGroup path
+	// joined with "." (e.g. "request.db"). Records logged outside any group do not get it.
+	GroupPathKey string
+	// MaxGroupDepth caps how deeply nested group values are flattened into keys; a group nested deeper
+	// is written under its key as lineTruncatedMarker. 0 uses defaultMaxGroupDepth.
+	MaxGroupDepth int
+	// MessagePosition places the message right after the level (default) or after the fields.
+	MessagePosition MessagePosition
+
+	timeLayout *timeLayout // set by Handler for SetTimeFormat; nil uses defaultTimeLayout
+}
+
+// MessagePosition is where the LineHandler writes the message relative to the fields.
+type MessagePosition int
+
+const (
+	// MessageAfterLevel writes "[time] LEVEL: message {fields}".
+	MessageAfterLevel MessagePosition = iota
+	// MessageLast writes "[time] LEVEL: {fields} message", so long field lists do not push the message
+	// far to the right. With MaxLineBytes, truncation then cuts the message first.
+	MessageLast
+)
+
+// defaultMaxGroupDepth is the MaxGroupDepth used when it is 0.
+const defaultMaxGroupDepth = 16
+
+// OverflowStrategy is how the LineHandler handles a line longer than MaxLineBytes.
+type OverflowStrategy int
+
+const (
+	// OverflowTruncate cuts the line and ends it with lineTruncatedMarker.
+	OverflowTruncate OverflowStrategy = iota
+	// OverflowSplit writes the rest of the line as continuation lines, each starting with
+	// "[time] LEVEL: " followed by lineContinuedMarker.
+	OverflowSplit
+)
+
+const (
+	lineTruncatedMarker = "...(truncated)"
+	lineContinuedMarker = "(continued) "
+)
+
+// lineField is one key/value pair of the trailing JSON object.
+type lineField struct {
+	key   string
+	value any
+}
+
+// lineFieldsIndexMin is the field count from which lineFields keeps an index of its keys; below it a
+// scan is cheaper than building the map.
+const lineFieldsIndexMin = 8
+
+// lineFields is an ordered set of fields; setting an existing key replaces its value in place.
+type lineFields struct {
+	list  []lineField
+	index map[string]int // position of each key in list; nil until list reaches lineFieldsIndexMin
+}
+
+func (fs *lineFields) set(key string, value any) {
+	if i, ok := fs.lookup(key); ok {
+		fs.list[i].value = value
+		return
+	}
+	fs.list = append(fs.list, lineField{key: key, value: value})
+	switch {
+	case fs.index != nil:
+		fs.index[key] = len(fs.list) - 1
+	case len(fs.list) >= lineFieldsIndexMin:
+		fs.index = make(map[string]int, 2*len(fs.list))
+		for i, f := range fs.list {
+			fs.index[f.key] = i
+		}
+	}
+}
+
+// lookup returns the position of key in the list.
+func (fs *lineFields) lookup(key string) (int, bool) {
+	if fs.index != nil {
+		i, ok := fs.index[key]
+		return i, ok
+	}
+	for i := range fs.list {
+		if fs.list[i].key == key {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// marshal encodes the fields as a JSON object in list order.
+func (fs *lineFields) marshal() []byte {
+	b := make([]byte, 0, 64*len(fs.list))
+	b = append(b, '{')
+	for i, f := range fs.list {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		k, _ := json.Marshal(f.key) // marshaling a string cannot fail
+		b = append(b, k...)
+		b = append(b, ':')
+		v, err := json.Marshal(f.value)
+		if err != nil {
+			v, _ = json.Marshal(fmt.Sprintf("%+v", f.value))
+		}
+		b = append(b, v...)
+	}
+	return append(b, '}')
+}
+
 // NewLineHandler creates a new LineHandler.
 func NewLineHandler(w io.Writer, opts *slog.HandlerOptions) *LineHandler {
-	var o slog.HandlerOptions
+	var o LineHandlerOptions
+	if opts != nil {
+		o.HandlerOptions = *opts
+	}
+	return NewLineHandlerWithOptions(w, &o)
+}
+
+// NewLineHandlerWithOptions creates a new LineHandler with LineHandler-specific options.
+func NewLineHandlerWithOptions(w io.Writer, opts *LineHandlerOptions) *LineHandler {
+	var o LineHandlerOptions
 	if opts != nil {
 		o = *opts
 	}
@@ -49,21 +195,73 @@ func (h *LineHandler) Handle(_ context.Context, r slog.Record) error {
 	if h.opts.ReplaceAttr != nil {
 		timeAttr = h.opts.ReplaceAttr(nil, timeAttr)
 	}
-	timeStr := r.Time.Format("2006-01-02 15:04:05")
+	timeStr := r.Time.Format(h.opts.timeLayout.get())
 	if timeAttr.Value.Kind() == slog.KindString {
 		timeStr = timeAttr.Value.String()
 	}
 
 	levelAttr := slog.String(slog.LevelKey, r.Level.String())
+	if h.opts.LevelFormatter != nil {
+		levelAttr = slog.String(slog.LevelKey, h.opts.LevelFormatter(r.Level))
+	}
 	if h.opts.ReplaceAttr != nil {
 		levelAttr = h.opts.ReplaceAttr(nil, levelAttr)
 	}
 	levelStr := levelAttr.Value.String()
 
-	fields := make(map[string]any, r.NumAttrs()+len(h.attrs))
+	fields := lineFields{list: make([]lineField, 0, r.NumAttrs()+len(h.attrs)+1)}
+
+	if h.opts.AddSource {
+		// like slog's built-in handlers, pass an empty source for records without a PC
+		src := r.Source()
+		if src == nil {
+			src = &slog.Source{}
+		}
+		srcAttr := slog.Any(slog.SourceKey, src)
+		if h.opts.ReplaceAttr != nil {
+			srcAttr = h.opts.ReplaceAttr(nil, srcAttr)
+		}
+		if srcAttr.Key != "" {
+			if v, ok := lineSource(srcAttr.Value); ok {
+				fields.set(srcAttr.Key, v)
+			}
+		}
+	}
 
-	prefix := strings.Join(h.groups, ".")
-	addAttr := func(groups []string, a slog.Attr) {
+	var addAttr func(groups []string, prefix string, a slog.Attr, depth int)
+	addAttr = func(groups []string, prefix string, a slog.Attr, depth int) {
+		if size, ok := a.Value.Any().(ByteSize); ok {
+			// human-readable here; JSON and text get the raw number from LogValue
+			a.Value = slog.StringValue(size.String())
+		}
+		// resolve LogValuers (e.g. Lazy) only now that the record is known to be written
+		a.Value = a.Value.Resolve()
+		if a.Value.Kind() == slog.KindGroup {
+			attrs := a.Value.Group()
+			if len(attrs) == 0 {
+				return
+			}
+			if a.Key == "" {
+				// like slog's handlers, a group with an empty key is inlined
+				for _, ga := range attrs {
+					addAttr(groups, prefix, ga, depth)
+				}
+				return
+			}
+			key := a.Key
+			if prefix != "" {
+				key = prefix + h.groupSeparator() + key
+			}
+			if depth >= h.maxGroupDepth() {
+				fields.set(key, lineTruncatedMarker)
+				return
+			}
+			groups = append(groups[:len(groups):len(groups)], a.Key)
+			for _, ga := range attrs {
+				addAttr(groups, key, ga, depth+1)
+			}
+			return
+		}
 		if h.opts.ReplaceAttr != nil {
 			a = h.opts.ReplaceAttr(groups, a)
 		}
@@ -72,28 +270,49 @@ func (h *LineHandler) Handle(_ context.Context, r slog.Record) error {
 		}
 		key := a.Key
 		if prefix != "" {
-			key = prefix + "." + key
+			key = prefix + h.groupSeparator() + key
 		}
-		fields[key] = a.Value.Any()
+		fields.set(key, a.Value.Any())
 	}
 
-	for _, a := range h.attrs {
-		addAttr(h.groups, a)
+	if h.opts.GroupPathKey != "" && len(h.groups) > 0 {
+		fields.set(h.opts.GroupPathKey, strings.Join(h.groups, "."))
 	}
 
+	// attrs from WithAttrs only carry the groups that were open when they were added
+	for _, ga := range h.attrs {
+		addAttr(ga.groups, ga.prefix, ga.attr, 0)
+	}
+
+	prefix := strings.Join(h.groups, h.groupSeparator())
 	r.Attrs(func(a slog.Attr) bool {
-		addAttr(nil, a)
+		addAttr(h.groups, prefix, a, 0)
 		return true
 	})
 
 	var contextJSON string
-	if len(fields) > 0 {
-		if b, err := json.Marshal(fields); err == nil {
-			contextJSON = " " + string(b)
+	if len(fields.list) > 0 {
+		if h.opts.SortFields {
+			sort.Slice(fields.list, func(i, j int) bool {
+				return fields.list[i].key < fields.list[j].key
+			})
 		}
+		contextJSON = " " + string(fields.marshal())
 	}
 
-	line := fmt.Sprintf("[%s] %s: %s%s\n", timeStr, levelStr, r.Message, contextJSON)
+	var line string
+	if h.opts.MessagePosition == MessageLast && contextJSON != "" {
+		line = fmt.Sprintf("[%s] %s:%s", timeStr, levelStr, contextJSON)
+		if r.Message != "" {
+			line += " " + r.Message
+		}
+		line += "\n"
+	} else {
+		line = fmt.Sprintf("[%s] %s: %s%s\n", timeStr, levelStr, r.Message, contextJSON)
+	}
+	if h.opts.MaxLineBytes > 0 && len(line) > h.opts.MaxLineBytes {
+		line = h.capLine(line, timeStr, levelStr)
+	}
 
 	h.mu.Lock()
 	defer h.mu.Unlock()
@@ -101,22 +320,105 @@ func (h *LineHandler) Handle(_ context.Context, r slog.Record) error {
 	return err
 }
 
-// WithAttrs returns a new LineHandler with the given attributes.
+// groupSeparator returns the separator between group names and keys.
+func (h *LineHandler) groupSeparator() string {
+	if h.opts.GroupSeparator == "" {
+		return "."
+	}
+	return h.opts.GroupSeparator
+}
+
+// maxGroupDepth returns the deepest group value nesting that is flattened.
+func (h *LineHandler) maxGroupDepth() int {
+	if h.opts.MaxGroupDepth <= 0 {
+		return defaultMaxGroupDepth
+	}
+	return h.opts.MaxGroupDepth
+}
+
+// lineSource renders a source location as "file:line"; other values are kept as they are.
+// It reports false for an empty source.
+func lineSource(v slog.Value) (any, bool) {
+	switch src := v.Any().(type) {
+	case *slog.Source:
+		if src == nil || *src == (slog.Source{}) {
+			return nil, false
+		}
+		return fmt.Sprintf("%s:%d", src.File, src.Line), true
+	case sourceValue:
+		return fmt.Sprintf("%s:%d", src.File, src.Line), true
+	}
+	return v.Any(), true
+}
+
+// capLine applies the overflow strategy to a line longer than MaxLineBytes.
+func (h *LineHandler) capLine(line, timeStr, levelStr string) string {
+	limit := h.opts.MaxLineBytes
+	body := strings.TrimSuffix(line, "\n")
+
+	if h.opts.OverflowStrategy == OverflowSplit {
+		prefix := fmt.Sprintf("[%s] %s: %s", timeStr, levelStr, lineContinuedMarker)
+		// a continuation line must have room for at least one more byte of content
+		if len(prefix)+utf8.UTFMax < limit {
+			var sb strings.Builder
+			first := truncateUTF8(body, limit-1)
+			sb.WriteString(first)
+			sb.WriteByte('\n')
+			for rest := body[len(first):]; rest != ""; {
+				chunk := truncateUTF8(rest, limit-1-len(prefix))
+				sb.WriteString(prefix)
+				sb.WriteString(chunk)
+				sb.WriteByte('\n')
+				rest = rest[len(chunk):]
+			}
+			return sb.String()
+		}
+	}
+
+	if limit <= len(lineTruncatedMarker)+1 {
+		return truncateUTF8(body, limit-1) + "\n"
+	}
+	return truncateUTF8(body, limit-1-len(lineTruncatedMarker)) + lineTruncatedMarker + "\n"
+}
+
+// truncateUTF8 returns the longest prefix of s that is at most n bytes and does not split a rune.
+func truncateUTF8(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// WithAttrs returns a new LineHandler with the given attributes. The attributes are
+// prefixed with the groups open at this point only, matching slog's built-in handlers.
 func (h *LineHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	groups := append([]string{}, h.groups...)
+	prefix := strings.Join(groups, h.groupSeparator())
+	newAttrs := append([]groupAttr{}, h.attrs...)
+	for _, a := range attrs {
+		newAttrs = append(newAttrs, groupAttr{groups: groups, prefix: prefix, attr: a})
+	}
 	return &LineHandler{
 		w:      h.w,
 		opts:   h.opts,
-		attrs:  append(append([]slog.Attr{}, h.attrs...), attrs...),
-		groups: append([]string{}, h.groups...),
+		attrs:  newAttrs,
+		groups: groups,
 	}
 }
 
 // WithGroup returns a new LineHandler with the given group name prefix.
+// It applies to record attributes and to attributes added by later WithAttrs calls.
 func (h *LineHandler) WithGroup(name string) slog.Handler {
 	return &LineHandler{
 		w:      h.w,
 		opts:   h.opts,
-		attrs:  append([]slog.Attr{}, h.attrs...),
+		attrs:  append([]groupAttr{}, h.attrs...),
 		groups: append(append([]string{}, h.groups...), name),
 	}
 }
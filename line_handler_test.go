@@ -3,6 +3,8 @@ package glog
 import (
 	"bytes"
 	"context"
+	"fmt"
+	"io"
 	"log/slog"
 	"strings"
 	"testing"
@@ -91,12 +93,337 @@ func TestLineHandler_WithAttrsAndWithGroup(t *testing.T) {
 
 	out := strings.TrimSpace(buf.String())
 
-	// app gets http group prefix
-	if !strings.Contains(out, `"http.app":"demo"`) {
-		t.Fatalf("expected http.app field in output, got: %s", out)
+	// app was added before the group, so it is not prefixed (same as slog's JSON handler)
+	if !strings.Contains(out, `"app":"demo"`) || strings.Contains(out, `"http.app"`) {
+		t.Fatalf("expected unprefixed app field in output, got: %s", out)
 	}
 	// http group prefix for method
 	if !strings.Contains(out, `"http.method":"GET"`) {
 		t.Fatalf("expected http.method field in output, got: %s", out)
 	}
 }
+
+func TestLineHandler_WithAttrsBeforeAndAfterGroups(t *testing.T) {
+	var buf bytes.Buffer
+
+	h := NewLineHandler(&buf, &slog.HandlerOptions{}).
+		WithAttrs([]slog.Attr{slog.String("app", "demo")}).
+		WithGroup("http").
+		WithAttrs([]slog.Attr{slog.String("route", "/users")}).
+		WithGroup("req").
+		WithAttrs([]slog.Attr{slog.Int("attempt", 2)})
+
+	slog.New(h).Info("req", slog.String("method", "GET"))
+
+	out := strings.TrimSpace(buf.String())
+	for _, want := range []string{
+		`"app":"demo"`,
+		`"http.route":"/users"`,
+		`"http.req.attempt":2`,
+		`"http.req.method":"GET"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %s in output, got: %s", want, out)
+		}
+	}
+}
+
+func TestLineHandler_GroupSeparator(t *testing.T) {
+	var buf bytes.Buffer
+
+	h := NewLineHandlerWithOptions(&buf, &LineHandlerOptions{GroupSeparator: "_"}).
+		WithGroup("http").
+		WithAttrs([]slog.Attr{slog.String("route", "/users")}).
+		WithGroup("req")
+
+	slog.New(h).Info("req", slog.String("method", "GET"))
+
+	out := strings.TrimSpace(buf.String())
+	for _, want := range []string{`"http_route":"/users"`, `"http_req_method":"GET"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %s in output, got: %s", want, out)
+		}
+	}
+	if strings.Contains(out, "http.") {
+		t.Errorf("expected no default separator, got: %s", out)
+	}
+}
+
+func TestLineHandler_WithAttrsReplaceAttrGroups(t *testing.T) {
+	var buf bytes.Buffer
+
+	seen := map[string][]string{}
+	replace := func(groups []string, a slog.Attr) slog.Attr {
+		if a.Key != slog.TimeKey && a.Key != slog.LevelKey {
+			seen[a.Key] = append([]string{}, groups...)
+		}
+		return a
+	}
+
+	h := NewLineHandler(&buf, &slog.HandlerOptions{ReplaceAttr: replace}).
+		WithAttrs([]slog.Attr{slog.String("app", "demo")}).
+		WithGroup("http")
+	slog.New(h).Info("req", slog.String("method", "GET"))
+
+	if len(seen["app"]) != 0 {
+		t.Errorf("expected no groups for app, got %v", seen["app"])
+	}
+	if strings.Join(seen["method"], ".") != "http" {
+		t.Errorf("expected groups [http] for method, got %v", seen["method"])
+	}
+}
+
+func TestLineHandler_FieldOrder(t *testing.T) {
+	var buf bytes.Buffer
+
+	h := NewLineHandler(&buf, &slog.HandlerOptions{})
+	slog.New(h).Info("msg", slog.String("zeta", "z"), slog.Int("alpha", 1), slog.Bool("mid", true))
+
+	out := strings.TrimSpace(buf.String())
+	if !strings.HasSuffix(out, `{"zeta":"z","alpha":1,"mid":true}`) {
+		t.Fatalf("expected fields in insertion order, got: %s", out)
+	}
+}
+
+func TestLineHandler_SortFields(t *testing.T) {
+	var buf bytes.Buffer
+
+	h := NewLineHandlerWithOptions(&buf, &LineHandlerOptions{SortFields: true})
+	logger := slog.New(h.WithAttrs([]slog.Attr{slog.String("service", "api")}))
+	logger.Info("msg", slog.String("zeta", "z"), slog.Int("alpha", 1), slog.Bool("mid", true))
+
+	out := strings.TrimSpace(buf.String())
+	if !strings.HasSuffix(out, `{"alpha":1,"mid":true,"service":"api","zeta":"z"}`) {
+		t.Fatalf("expected fields sorted by key, got: %s", out)
+	}
+}
+
+func TestLineHandler_ManyFieldsDuplicateKeys(t *testing.T) {
+	var buf bytes.Buffer
+
+	h := NewLineHandler(&buf, nil)
+	var args []any
+	for i := range 2 * lineFieldsIndexMin {
+		args = append(args, fmt.Sprintf("k%d", i%lineFieldsIndexMin+1), i)
+	}
+	slog.New(h).Info("msg", args...)
+
+	out := strings.TrimSpace(buf.String())
+	if !strings.HasSuffix(out, `{"k1":8,"k2":9,"k3":10,"k4":11,"k5":12,"k6":13,"k7":14,"k8":15}`) {
+		t.Fatalf("expected each key once, in first position with the last value, got: %s", out)
+	}
+}
+
+func TestLineHandler_DuplicateKeyLastWins(t *testing.T) {
+	var buf bytes.Buffer
+
+	h := NewLineHandler(&buf, &slog.HandlerOptions{})
+	slog.New(h).Info("msg", slog.Int("k", 1), slog.String("other", "x"), slog.Int("k", 2))
+
+	out := strings.TrimSpace(buf.String())
+	if !strings.HasSuffix(out, `{"k":2,"other":"x"}`) {
+		t.Fatalf("expected a single k with the last value, got: %s", out)
+	}
+}
+
+func TestLineHandler_MaxLineBytes_Truncate(t *testing.T) {
+	var buf bytes.Buffer
+
+	const limit = 120
+	h := NewLineHandlerWithOptions(&buf, &LineHandlerOptions{MaxLineBytes: limit})
+	slog.New(h).Info("big", slog.String("payload", strings.Repeat("x", 500)))
+
+	out := buf.String()
+	if len(out) > limit {
+		t.Fatalf("expected line within %d bytes, got %d: %q", limit, len(out), out)
+	}
+	if !strings.HasSuffix(out, lineTruncatedMarker+"\n") {
+		t.Fatalf("expected truncation marker at end of line, got: %q", out)
+	}
+	if !strings.Contains(out, "INFO: big {") {
+		t.Fatalf("expected header and start of fields to be kept, got: %q", out)
+	}
+}
+
+func TestLineHandler_MaxLineBytes_Split(t *testing.T) {
+	var buf bytes.Buffer
+
+	const limit = 120
+	payload := strings.Repeat("0123456789", 50)
+	h := NewLineHandlerWithOptions(&buf, &LineHandlerOptions{
+		MaxLineBytes:     limit,
+		OverflowStrategy: OverflowSplit,
+	})
+	slog.New(h).Info("big", slog.String("payload", payload))
+
+	lines := strings.SplitAfter(buf.String(), "\n")
+	lines = lines[:len(lines)-1] // drop the empty string after the final newline
+	if len(lines) < 2 {
+		t.Fatalf("expected continuation lines, got: %q", buf.String())
+	}
+
+	var joined strings.Builder
+	for i, line := range lines {
+		if len(line) > limit {
+			t.Errorf("line %d exceeds %d bytes: %d", i, limit, len(line))
+		}
+		body := strings.TrimSuffix(line, "\n")
+		if i > 0 {
+			idx := strings.Index(body, lineContinuedMarker)
+			if idx < 0 || !strings.Contains(body[:idx], "] INFO: ") {
+				t.Fatalf("line %d lacks continuation prefix: %q", i, line)
+			}
+			body = body[idx+len(lineContinuedMarker):]
+		}
+		joined.WriteString(body)
+	}
+	if !strings.HasSuffix(joined.String(), `INFO: big {"payload":"`+payload+`"}`) {
+		t.Fatalf("reassembled line mismatch: %q", joined.String())
+	}
+}
+
+func TestLineHandler_MaxLineBytes_ShortLineUntouched(t *testing.T) {
+	var buf bytes.Buffer
+
+	h := NewLineHandlerWithOptions(&buf, &LineHandlerOptions{MaxLineBytes: 1024})
+	slog.New(h).Info("small", slog.Int("n", 1))
+
+	if out := buf.String(); !strings.HasSuffix(out, `INFO: small {"n":1}`+"\n") {
+		t.Fatalf("unexpected output: %q", out)
+	}
+}
+
+func TestTruncateUTF8(t *testing.T) {
+	s := "ab世界"
+	if got := truncateUTF8(s, 4); got != "ab" {
+		t.Errorf("expected cut before a split rune, got %q", got)
+	}
+	if got := truncateUTF8(s, 5); got != "ab世" {
+		t.Errorf("expected ab世, got %q", got)
+	}
+	if got := truncateUTF8(s, 100); got != s {
+		t.Errorf("expected unchanged string, got %q", got)
+	}
+}
+
+func TestLineHandler_AddSource(t *testing.T) {
+	var buf bytes.Buffer
+
+	h := NewLineHandler(&buf, &slog.HandlerOptions{AddSource: true})
+	slog.New(h).Info("with source", slog.String("k", "v"))
+
+	out := buf.String()
+	if !strings.Contains(out, `INFO: with source {"source":"`) || !strings.Contains(out, `line_handler_test.go:`) {
+		t.Fatalf("expected source as the first field, got: %s", out)
+	}
+
+	// a record without a PC has no source to report
+	buf.Reset()
+	if err := h.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "no pc", 0)); err != nil {
+		t.Fatalf("Handle failed: %v", err)
+	}
+	if strings.Contains(buf.String(), "source") {
+		t.Fatalf("expected no source for a record without PC, got: %s", buf.String())
+	}
+}
+
+func TestLineHandler_LevelFormatter(t *testing.T) {
+	var buf bytes.Buffer
+
+	h := NewLineHandlerWithOptions(&buf, &LineHandlerOptions{LevelFormatter: letterLevel})
+	logger := slog.New(h)
+	logger.Info("started")
+	logger.Warn("slow")
+	logger.Error("failed")
+
+	out := buf.String()
+	for _, want := range []string{"] I: started", "] W: slow", "] E: failed"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output, got: %s", want, out)
+		}
+	}
+}
+
+func TestLineHandler_ReplaceAttr_LevelIsString(t *testing.T) {
+	var kind slog.Kind
+	h := NewLineHandler(io.Discard, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.LevelKey {
+				kind = a.Value.Kind()
+			}
+			return a
+		},
+	})
+	slog.New(h).Warn("slow")
+	if kind != slog.KindString {
+		t.Errorf("expected ReplaceAttr to get the level as a string, got kind %s", kind)
+	}
+}
+
+func TestLineHandler_GroupValues(t *testing.T) {
+	var buf bytes.Buffer
+
+	slog.New(NewLineHandler(&buf, nil)).WithGroup("req").Info("handled",
+		slog.Group("http", slog.String("method", "GET"), slog.Group("tls", slog.Bool("resumed", true))),
+		slog.Group("", slog.Int("inlined", 1)),
+		slog.Group("empty"),
+	)
+
+	want := `{"req.http.method":"GET","req.http.tls.resumed":true,"req.inlined":1}`
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("expected group values flattened into keys %s, got: %s", want, buf.String())
+	}
+}
+
+func TestLineHandler_MaxGroupDepth(t *testing.T) {
+	var buf bytes.Buffer
+
+	h := NewLineHandlerWithOptions(&buf, &LineHandlerOptions{MaxGroupDepth: 2})
+	slog.New(h).Info("nested",
+		slog.Group("a", slog.Group("b", slog.Group("c", slog.Group("d", slog.Int("x", 1))))),
+		slog.Group("shallow", slog.Group("ok", slog.Int("y", 2))),
+	)
+
+	want := `{"a.b.c":"...(truncated)","shallow.ok.y":2}`
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("expected groups deeper than 2 truncated and shallow ones kept %s, got: %s", want, buf.String())
+	}
+}
+
+func TestLineHandler_MessagePosition(t *testing.T) {
+	cases := map[MessagePosition][]string{
+		MessageAfterLevel: {`INFO: served {"status":200,"path":"/a"}`, `INFO: bare`, `INFO:  {"k":1}`},
+		MessageLast:       {`INFO: {"status":200,"path":"/a"} served`, `INFO: bare`, `INFO: {"k":1}`},
+	}
+	for position, want := range cases {
+		var buf bytes.Buffer
+		logger := slog.New(NewLineHandlerWithOptions(&buf, &LineHandlerOptions{MessagePosition: position}))
+		logger.Info("served", "status", 200, "path", "/a")
+		logger.Info("bare")
+		logger.Info("", "k", 1)
+
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		if len(lines) != len(want) {
+			t.Fatalf("position %d: expected %d lines, got: %s", position, len(want), buf.String())
+		}
+		for i, line := range lines {
+			if !strings.HasSuffix(line, "] "+want[i]) {
+				t.Errorf("position %d: expected a line ending with %q, got %q", position, want[i], line)
+			}
+		}
+	}
+}
+
+// letterLevel maps levels to single-letter codes.
+func letterLevel(l slog.Level) string {
+	switch {
+	case l >= slog.LevelError:
+		return "E"
+	case l >= slog.LevelWarn:
+		return "W"
+	case l >= slog.LevelInfo:
+		return "I"
+	default:
+		return "D"
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package glog
+
+import "os"
+
+// tryLock always succeeds on platforms without flock; ExclusiveLock has no effect there.
+func tryLock(f *os.File) error {
+	return nil
+}
+
+// unlock is a no-op on platforms without flock.
+func unlock(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,126 @@
+//go:build unix
+
+package glog
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileWriter_ExclusiveLock(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "glog_test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	logPath := filepath.Join(tmpDir, "app.log")
+	owner := NewFileWriterWithOptions(logPath, FileWriterOptions{ExclusiveLock: true})
+	defer owner.Close()
+	if _, err := owner.Write([]byte("owner\n")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+
+	pidFile := NewFileWriterWithOptions(logPath, FileWriterOptions{ExclusiveLock: true, LockFallback: LockFallbackPID})
+	if _, err := pidFile.Write([]byte("redirected\n")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if err := pidFile.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	redirected := filepath.Join(tmpDir, "app.pid"+strconv.Itoa(os.Getpid())+".log")
+	if content, err := os.ReadFile(redirected); err != nil || string(content) != "redirected\n" {
+		t.Errorf("expected second writer redirected to %s, got %q (%v)", redirected, content, err)
+	}
+
+	failing := NewFileWriterWithOptions(logPath, FileWriterOptions{ExclusiveLock: true, LockFallback: LockFallbackError})
+	if _, err := failing.Write([]byte("rejected\n")); !errors.Is(err, ErrLogFileLocked) {
+		t.Errorf("expected ErrLogFileLocked, got %v", err)
+	}
+	failing.Close()
+
+	content, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("failed to read log file: %v", err)
+	}
+	if string(content) != "owner\n" {
+		t.Errorf("expected only the owner's writes in the shared file, got %q", content)
+	}
+	if pid, _ := os.ReadFile(logPath + ".lock"); strings.TrimSpace(string(pid)) != strconv.Itoa(os.Getpid()) {
+		t.Errorf("expected lock file to hold the PID, got %q", pid)
+	}
+
+	// once released, the lock can be taken again
+	if err := owner.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	next := NewFileWriterWithOptions(logPath, FileWriterOptions{ExclusiveLock: true, LockFallback: LockFallbackError})
+	defer next.Close()
+	if _, err := next.Write([]byte("next\n")); err != nil {
+		t.Errorf("expected lock taken after release, got %v", err)
+	}
+}
+
+func TestFileWriter_ExclusiveLockCleanup(t *testing.T) {
+	tmpDir := t.TempDir()
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local)
+	clock := &fakeClock{t: start}
+	logPath := filepath.Join(tmpDir, "app-15.log")
+	opts := FileWriterOptions{ExclusiveLock: true, MaxFiles: 1, now: clock.Now}
+	owner := NewFileWriterWithOptions(logPath, opts)
+	defer owner.Close()
+	other := NewFileWriterWithOptions(logPath, opts)
+	defer other.Close()
+
+	// each writer rotates through three hours, keeping one old file of its own
+	for hour := range 3 {
+		clock.Set(start.Add(time.Duration(hour) * time.Hour))
+		for _, fw := range []*FileWriter{owner, other} {
+			fw.checkAndRotate()
+			if _, err := fw.Write([]byte("record\n")); err != nil {
+				t.Fatalf("Write failed: %v", err)
+			}
+		}
+	}
+
+	pid := strconv.Itoa(os.Getpid())
+	for _, name := range []string{"app-13.log", "app-14.log", "app-13.pid" + pid + ".log", "app-14.pid" + pid + ".log"} {
+		if _, err := os.Stat(filepath.Join(tmpDir, name)); err != nil {
+			t.Errorf("expected %s kept, got %v", name, err)
+		}
+	}
+	for _, name := range []string{"app-12.log", "app-12.pid" + pid + ".log"} {
+		if _, err := os.Stat(filepath.Join(tmpDir, name)); !os.IsNotExist(err) {
+			t.Errorf("expected %s removed, got %v", name, err)
+		}
+	}
+}
+
+func TestFileWriter_ExclusiveLockReleasedOnCloseError(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "app.log")
+	owner := NewFileWriterWithOptions(logPath, FileWriterOptions{
+		ExclusiveLock: true,
+		FlushInterval: 60,
+		wrapFile: func(io.Writer) io.Writer {
+			return funcWriter(func([]byte) (int, error) { return 0, errors.New("write failed") })
+		},
+	})
+	if _, err := owner.Write([]byte("buffered\n")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if err := owner.Close(); err == nil {
+		t.Fatal("expected the final flush to fail")
+	}
+
+	next := NewFileWriterWithOptions(logPath, FileWriterOptions{ExclusiveLock: true, LockFallback: LockFallbackError})
+	defer next.Close()
+	if _, err := next.Write([]byte("next\n")); err != nil {
+		t.Errorf("expected the lock released despite the failed Close, got %v", err)
+	}
+}
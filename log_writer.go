@@ -0,0 +1,103 @@
+package glog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// WriterOptions configures a LogWriter.
+type WriterOptions struct {
+	// Level is the level of the records written. Defaults to slog.LevelInfo.
+	Level slog.Level
+	// NoSplit makes each Write a single record, newlines included, instead of one record per line.
+	NoSplit bool
+}
+
+// LogWriter is an io.Writer that logs what is written to it, for libraries and subprocesses that
+// only know how to write to an io.Writer:
+//
+//	cmd.Stdout = glog.AsWriter(handler.WithAttrs([]slog.Attr{slog.String("cmd", "backup")}), nil)
+//
+// By default each newline-terminated line becomes one record; a trailing partial line is held
+// until a later Write completes it, or until Flush or Close. A trailing "\r" is trimmed, and empty
+// lines are skipped. A LogWriter is safe for concurrent use.
+type LogWriter struct {
+	mu      sync.Mutex
+	handler slog.Handler
+	level   slog.Level
+	noSplit bool
+	partial []byte // unterminated tail of the last Write
+}
+
+// AsWriter returns a LogWriter writing records to h. opts may be nil.
+func AsWriter(h slog.Handler, opts *WriterOptions) *LogWriter {
+	if opts == nil {
+		opts = &WriterOptions{}
+	}
+	return &LogWriter{handler: h, level: opts.Level, noSplit: opts.NoSplit}
+}
+
+// Write logs the complete lines in p. It always reports len(p) bytes written; the error is the
+// first error returned by the handler.
+func (w *LogWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.noSplit {
+		return len(p), w.logLocked(bytes.TrimSuffix(p, []byte("\n")))
+	}
+
+	n := len(p)
+	var firstErr error
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			w.partial = append(w.partial, p...)
+			break
+		}
+		line := p[:i]
+		if len(w.partial) > 0 {
+			line = append(w.partial, line...)
+			w.partial = w.partial[:0]
+		}
+		if err := w.logLocked(line); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		p = p[i+1:]
+	}
+	return n, firstErr
+}
+
+// Flush logs a held partial line, if any.
+func (w *LogWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.partial) == 0 {
+		return nil
+	}
+	err := w.logLocked(w.partial)
+	w.partial = w.partial[:0]
+	return err
+}
+
+// Close flushes the writer. It does not close the underlying handler.
+func (w *LogWriter) Close() error {
+	return w.Flush()
+}
+
+// logLocked writes line as one record. Caller must hold w.mu.
+func (w *LogWriter) logLocked(line []byte) error {
+	line = bytes.TrimSuffix(line, []byte("\r"))
+	if len(line) == 0 {
+		return nil
+	}
+	ctx := context.Background()
+	if !w.handler.Enabled(ctx, w.level) {
+		return nil
+	}
+	return w.handler.Handle(ctx, slog.NewRecord(time.Now(), w.level, string(line), 0))
+}
@@ -0,0 +1,80 @@
+package glog
+
+import (
+	"log/slog"
+	"testing"
+)
+
+func messages(mem *MemoryHandler) []string {
+	var msgs []string
+	for _, rec := range mem.Records() {
+		msgs = append(msgs, rec.Message)
+	}
+	return msgs
+}
+
+func TestLogWriter_SplitLines(t *testing.T) {
+	mem := NewMemoryHandler(nil)
+	w := AsWriter(mem, &WriterOptions{Level: slog.LevelWarn})
+
+	// a subprocess stream arriving in arbitrary chunks
+	for _, chunk := range []string{"first line\nsec", "ond line\r\n\nthi", "rd", " line\npartial"} {
+		n, err := w.Write([]byte(chunk))
+		if err != nil || n != len(chunk) {
+			t.Fatalf("Write(%q) = %d, %v", chunk, n, err)
+		}
+	}
+
+	got := messages(mem)
+	want := []string{"first line", "second line", "third line"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("record %d: expected %q, got %q", i, want[i], got[i])
+		}
+	}
+	if rec := mem.Records()[0]; rec.Level != slog.LevelWarn {
+		t.Errorf("expected WARN records, got %s", rec.Level)
+	}
+
+	// the partial line is held until it is completed or flushed
+	if _, err := w.Write([]byte(" line")); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(mem.Records()); n != 3 {
+		t.Fatalf("expected the partial line held, got %d records", n)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := messages(mem); len(got) != 4 || got[3] != "partial line" {
+		t.Errorf("expected the partial line written on Close, got %q", got)
+	}
+}
+
+func TestLogWriter_NoSplit(t *testing.T) {
+	mem := NewMemoryHandler(nil)
+	w := AsWriter(mem, &WriterOptions{NoSplit: true})
+
+	if _, err := w.Write([]byte("panic: boom\n\ngoroutine 1:\n")); err != nil {
+		t.Fatal(err)
+	}
+	got := messages(mem)
+	if len(got) != 1 || got[0] != "panic: boom\n\ngoroutine 1:" {
+		t.Errorf("expected one record per Write, got %q", got)
+	}
+}
+
+func TestLogWriter_Disabled(t *testing.T) {
+	mem := NewMemoryHandler(slog.LevelWarn)
+	w := AsWriter(mem, nil)
+
+	if _, err := w.Write([]byte("dropped\n")); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(mem.Records()); n != 0 {
+		t.Errorf("expected records below the handler level dropped, got %d", n)
+	}
+}
@@ -0,0 +1,138 @@
+package glog
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryRecord is a record captured by a MemoryHandler. Attrs holds every attribute
+// (from WithAttrs and the record) resolved and flattened; keys inside groups are
+// qualified with the group names joined by ".", e.g. "http.status".
+type MemoryRecord struct {
+	Time    time.Time
+	Level   slog.Level
+	Message string
+	Attrs   map[string]slog.Value
+}
+
+// memoryStore is the record list shared by a MemoryHandler and the handlers derived from it.
+type memoryStore struct {
+	mu      sync.Mutex
+	records []MemoryRecord
+}
+
+// MemoryHandler is a slog.Handler that keeps records in memory instead of writing them,
+// for asserting on log output in tests. It is safe for concurrent use.
+type MemoryHandler struct {
+	store  *memoryStore
+	level  slog.Leveler
+	attrs  []groupAttr
+	groups []string
+}
+
+// NewMemoryHandler creates a MemoryHandler recording records at or above level; nil records everything.
+func NewMemoryHandler(level slog.Leveler) *MemoryHandler {
+	return &MemoryHandler{
+		store: &memoryStore{},
+		level: level,
+	}
+}
+
+// Enabled reports whether the given level is recorded.
+func (h *MemoryHandler) Enabled(_ context.Context, level slog.Level) bool {
+	if h.level == nil {
+		return true
+	}
+	return level >= h.level.Level()
+}
+
+// Handle records r.
+func (h *MemoryHandler) Handle(_ context.Context, r slog.Record) error {
+	rec := MemoryRecord{
+		Time:    r.Time,
+		Level:   r.Level,
+		Message: r.Message,
+		Attrs:   make(map[string]slog.Value, len(h.attrs)+r.NumAttrs()),
+	}
+	for _, ga := range h.attrs {
+		flattenAttr(rec.Attrs, ga.prefix, ga.attr)
+	}
+	prefix := strings.Join(h.groups, ".")
+	r.Attrs(func(a slog.Attr) bool {
+		flattenAttr(rec.Attrs, prefix, a)
+		return true
+	})
+
+	h.store.mu.Lock()
+	defer h.store.mu.Unlock()
+	h.store.records = append(h.store.records, rec)
+	return nil
+}
+
+// flattenAttr resolves a and stores it in m under its group-qualified key, recursing into groups.
+func flattenAttr(m map[string]slog.Value, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	key := a.Key
+	if prefix != "" && key != "" {
+		key = prefix + "." + key
+	} else if key == "" {
+		key = prefix
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		for _, ga := range a.Value.Group() {
+			flattenAttr(m, key, ga)
+		}
+		return
+	}
+	if a.Key == "" {
+		return
+	}
+	m[key] = a.Value
+}
+
+// WithAttrs returns a new MemoryHandler sharing this handler's records.
+func (h *MemoryHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	groups := append([]string{}, h.groups...)
+	prefix := strings.Join(groups, ".")
+	newAttrs := append([]groupAttr{}, h.attrs...)
+	for _, a := range attrs {
+		newAttrs = append(newAttrs, groupAttr{groups: groups, prefix: prefix, attr: a})
+	}
+	return &MemoryHandler{
+		store:  h.store,
+		level:  h.level,
+		attrs:  newAttrs,
+		groups: groups,
+	}
+}
+
+// WithGroup returns a new MemoryHandler sharing this handler's records.
+func (h *MemoryHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &MemoryHandler{
+		store:  h.store,
+		level:  h.level,
+		attrs:  append([]groupAttr{}, h.attrs...),
+		groups: append(append([]string{}, h.groups...), name),
+	}
+}
+
+// Records returns a copy of the recorded records in the order they were logged.
+func (h *MemoryHandler) Records() []MemoryRecord {
+	h.store.mu.Lock()
+	defer h.store.mu.Unlock()
+	return append([]MemoryRecord{}, h.store.records...)
+}
+
+// Reset discards all recorded records.
+func (h *MemoryHandler) Reset() {
+	h.store.mu.Lock()
+	defer h.store.mu.Unlock()
+	h.store.records = nil
+}
@@ -0,0 +1,59 @@
+package glog
+
+import (
+	"log/slog"
+	"sync"
+	"testing"
+)
+
+func TestMemoryHandler_Records(t *testing.T) {
+	mem := NewMemoryHandler(slog.LevelInfo)
+	logger := slog.New(mem).With(slog.String("app", "demo")).WithGroup("http")
+
+	logger.Debug("dropped")
+	logger.Info("request", slog.Int("status", 200), slog.Group("req", slog.String("method", "GET")))
+
+	records := mem.Records()
+	if len(records) != 1 {
+		t.Fatalf("expected 1 record, got %d", len(records))
+	}
+	rec := records[0]
+	if rec.Level != slog.LevelInfo || rec.Message != "request" {
+		t.Errorf("unexpected record: %+v", rec)
+	}
+	if rec.Attrs["app"].String() != "demo" {
+		t.Errorf("expected root app attr, got %v", rec.Attrs)
+	}
+	if rec.Attrs["http.status"].Int64() != 200 {
+		t.Errorf("expected http.status=200, got %v", rec.Attrs)
+	}
+	if rec.Attrs["http.req.method"].String() != "GET" {
+		t.Errorf("expected http.req.method=GET, got %v", rec.Attrs)
+	}
+
+	mem.Reset()
+	if len(mem.Records()) != 0 {
+		t.Error("expected no records after Reset")
+	}
+}
+
+func TestMemoryHandler_Concurrent(t *testing.T) {
+	mem := NewMemoryHandler(nil)
+	logger := slog.New(mem)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				logger.Info("concurrent")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := len(mem.Records()); got != 1000 {
+		t.Fatalf("expected 1000 records, got %d", got)
+	}
+}
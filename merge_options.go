@@ -0,0 +1,40 @@
+package glog
+
+import "reflect"
+
+// MergeOptions returns new Options layering override on top of base, for configuration built in
+// layers (e.g. organization-wide defaults and per-service settings). Neither argument is modified, and
+// either may be nil.
+//
+// A zero field of override means "inherit": the field keeps base's value. Every other field of
+// override wins, whatever its kind:
+//   - strings, numbers and durations win when non-zero (e.g. LogPath, MaxFiles, DedupWindow);
+//   - bools win when true, so an override cannot turn off a bool base sets;
+//   - pointer, interface and func fields win when non-nil (e.g. Writer, ConsoleLevel, ReplaceAttr, Emit);
+//   - slices and maps win when non-nil and replace base's value whole rather than being merged
+//     (e.g. AllowKeys, StaticFields, Writers); a non-nil empty one clears base's value.
+//
+// Fields whose zero value is a meaningful choice (Format's FormatLine, TracePlacement's
+// TraceAfterAttrs, OnDiskFull's DiskFullDropNew, Level's slog.LevelInfo) cannot be selected by an
+// override over a base that sets another value; leave them unset in base to keep them open. Level is
+// the exception: set LevelSet in the override to make its Level win, Info included.
+func MergeOptions(base, override *Options) *Options {
+	merged := &Options{}
+	if base != nil {
+		*merged = *base
+	}
+	if override == nil {
+		return merged
+	}
+	dst := reflect.ValueOf(merged).Elem()
+	src := reflect.ValueOf(override).Elem()
+	for i := range src.NumField() {
+		if field := src.Field(i); !field.IsZero() {
+			dst.Field(i).Set(field)
+		}
+	}
+	if override.LevelSet {
+		merged.Level = override.Level
+	}
+	return merged
+}
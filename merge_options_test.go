@@ -0,0 +1,94 @@
+package glog
+
+import (
+	"bytes"
+	"log/slog"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestMergeOptions(t *testing.T) {
+	var baseOut, overrideOut bytes.Buffer
+	base := &Options{
+		Writer:            &baseOut,
+		Format:            FormatJSON,
+		Level:             slog.LevelInfo,
+		MaxFiles:          7,
+		IncludeRawLevel:   true,
+		AllowKeys:         []string{"service", "user"},
+		StaticFields:      map[string]any{"service": "api", "region": "eu"},
+		MinRotateInterval: time.Second,
+	}
+	override := &Options{
+		Writer:       &overrideOut,
+		Level:        slog.LevelDebug,
+		MaxFiles:     0, // inherit
+		AllowKeys:    []string{},
+		StaticFields: map[string]any{"service": "billing"},
+		AddSource:    true,
+	}
+	merged := MergeOptions(base, override)
+
+	if merged.Writer != &overrideOut {
+		t.Error("expected the override's Writer")
+	}
+	if merged.Format != FormatJSON || merged.MaxFiles != 7 || merged.MinRotateInterval != time.Second || !merged.IncludeRawLevel {
+		t.Errorf("expected zero override fields to inherit base, got %+v", merged)
+	}
+	if merged.Level != slog.LevelDebug || !merged.AddSource {
+		t.Errorf("expected non-zero override fields to win, got level %v, AddSource %v", merged.Level, merged.AddSource)
+	}
+	if merged.AllowKeys == nil || len(merged.AllowKeys) != 0 {
+		t.Errorf("expected a non-nil empty override slice to clear base's, got %q", merged.AllowKeys)
+	}
+	if want := map[string]any{"service": "billing"}; !maps.Equal(merged.StaticFields, want) {
+		t.Errorf("expected the override's map to replace base's whole, got %v", merged.StaticFields)
+	}
+
+	// the inputs are left untouched
+	if base.Writer != &baseOut || base.Level != slog.LevelInfo || !slices.Equal(base.AllowKeys, []string{"service", "user"}) {
+		t.Errorf("expected base unchanged, got %+v", base)
+	}
+	if merged == base || merged == override {
+		t.Error("expected new Options")
+	}
+
+	// the merged options build a working handler
+	slog.New(NewHandler(merged)).Debug("merged", "service", "x")
+	if overrideOut.Len() == 0 || baseOut.Len() != 0 {
+		t.Errorf("expected the record in the override's writer at debug level, got %q and %q", overrideOut.String(), baseOut.String())
+	}
+}
+
+func TestMergeOptions_LevelInfo(t *testing.T) {
+	base := &Options{Level: slog.LevelDebug}
+
+	// Info is Level's zero value, so without LevelSet it reads as "inherit"
+	if got := MergeOptions(base, &Options{Level: slog.LevelInfo}); got.Level != slog.LevelDebug {
+		t.Errorf("expected base's level without LevelSet, got %v", got.Level)
+	}
+	if got := MergeOptions(base, &Options{Level: slog.LevelInfo, LevelSet: true}); got.Level != slog.LevelInfo {
+		t.Errorf("expected the override's Info level with LevelSet, got %v", got.Level)
+	}
+	if got := MergeOptions(&Options{Level: slog.LevelWarn}, &Options{LevelSet: true}); got.Level != slog.LevelInfo {
+		t.Errorf("expected the override's Info level over a Warn base, got %v", got.Level)
+	}
+	if got := MergeOptions(&Options{Level: slog.LevelWarn, LevelSet: true}, &Options{}); got.Level != slog.LevelWarn {
+		t.Errorf("expected base's level kept when the override sets none, got %v", got.Level)
+	}
+}
+
+func TestMergeOptions_Nil(t *testing.T) {
+	base := &Options{LogPath: "app.log", MaxFiles: 3}
+	if got := MergeOptions(base, nil); got == base || got.LogPath != "app.log" || got.MaxFiles != 3 {
+		t.Errorf("expected a copy of base, got %+v", got)
+	}
+	if got := MergeOptions(nil, base); got == base || got.LogPath != "app.log" || got.MaxFiles != 3 {
+		t.Errorf("expected a copy of override, got %+v", got)
+	}
+	if got := MergeOptions(nil, nil); got == nil || got.LogPath != "" {
+		t.Errorf("expected zero Options, got %+v", got)
+	}
+}
@@ -0,0 +1,57 @@
+// Package otelbaggage attaches OpenTelemetry baggage entries to glog records.
+package otelbaggage
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+
+	"github.com/lyuangg/glog"
+	"go.opentelemetry.io/otel/baggage"
+)
+
+// Options configures BaggageExtractor.
+type Options struct {
+	// Group, when set, nests the entries under a group with this name (e.g. "baggage").
+	Group string
+	// Keys lists the baggage keys to log; empty logs every entry.
+	Keys []string
+}
+
+// BaggageExtractor returns a glog.AttrExtractor that adds the baggage carried by the context,
+// one string attribute per entry, sorted by key. Contexts without baggage add nothing. opts may be nil.
+//
+//	handler := glog.NewHandler(&glog.Options{AttrExtractor: otelbaggage.BaggageExtractor(nil)})
+func BaggageExtractor(opts *Options) glog.AttrExtractor {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	var allowed map[string]bool
+	if len(o.Keys) > 0 {
+		allowed = make(map[string]bool, len(o.Keys))
+		for _, k := range o.Keys {
+			allowed[k] = true
+		}
+	}
+	return func(ctx context.Context) []slog.Attr {
+		members := baggage.FromContext(ctx).Members()
+		if len(members) == 0 {
+			return nil
+		}
+		attrs := make([]slog.Attr, 0, len(members))
+		for _, m := range members {
+			if allowed == nil || allowed[m.Key()] {
+				attrs = append(attrs, slog.String(m.Key(), m.Value()))
+			}
+		}
+		if len(attrs) == 0 {
+			return nil
+		}
+		sort.Slice(attrs, func(i, j int) bool { return attrs[i].Key < attrs[j].Key })
+		if o.Group != "" {
+			return []slog.Attr{{Key: o.Group, Value: slog.GroupValue(attrs...)}}
+		}
+		return attrs
+	}
+}
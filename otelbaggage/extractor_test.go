@@ -0,0 +1,84 @@
+package otelbaggage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/lyuangg/glog"
+	"go.opentelemetry.io/otel/baggage"
+)
+
+func baggageContext(t *testing.T, entries map[string]string) context.Context {
+	t.Helper()
+	var members []baggage.Member
+	for k, v := range entries {
+		m, err := baggage.NewMember(k, v)
+		if err != nil {
+			t.Fatalf("NewMember(%q) failed: %v", k, err)
+		}
+		members = append(members, m)
+	}
+	b, err := baggage.New(members...)
+	if err != nil {
+		t.Fatalf("baggage.New failed: %v", err)
+	}
+	return baggage.ContextWithBaggage(context.Background(), b)
+}
+
+func logRecord(t *testing.T, opts *Options, ctx context.Context) map[string]any {
+	t.Helper()
+	var buf bytes.Buffer
+	handler := glog.NewHandler(&glog.Options{
+		Writer:        &buf,
+		Format:        glog.FormatJSON,
+		Level:         slog.LevelInfo,
+		AttrExtractor: BaggageExtractor(opts),
+	})
+	slog.New(handler).InfoContext(ctx, "handled")
+
+	var m map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	return m
+}
+
+func TestBaggageExtractor(t *testing.T) {
+	ctx := baggageContext(t, map[string]string{"tenant": "acme", "region": "eu"})
+	m := logRecord(t, nil, ctx)
+	if m["tenant"] != "acme" || m["region"] != "eu" {
+		t.Errorf("expected the baggage entries as fields, got: %v", m)
+	}
+}
+
+func TestBaggageExtractor_Group(t *testing.T) {
+	ctx := baggageContext(t, map[string]string{"tenant": "acme"})
+	m := logRecord(t, &Options{Group: "baggage"}, ctx)
+	group, ok := m["baggage"].(map[string]any)
+	if !ok || group["tenant"] != "acme" {
+		t.Errorf("expected the entries under a baggage group, got: %v", m)
+	}
+	if _, ok := m["tenant"]; ok {
+		t.Errorf("expected no top-level entry with Group set, got: %v", m)
+	}
+}
+
+func TestBaggageExtractor_Keys(t *testing.T) {
+	ctx := baggageContext(t, map[string]string{"tenant": "acme", "session": "secret"})
+	m := logRecord(t, &Options{Keys: []string{"tenant", "missing"}}, ctx)
+	if m["tenant"] != "acme" {
+		t.Errorf("expected the allowed entry, got: %v", m)
+	}
+	if _, ok := m["session"]; ok {
+		t.Errorf("expected entries outside Keys to be left out, got: %v", m)
+	}
+}
+
+func TestBaggageExtractor_NoBaggage(t *testing.T) {
+	if attrs := BaggageExtractor(nil)(context.Background()); attrs != nil {
+		t.Errorf("expected no attributes without baggage, got: %v", attrs)
+	}
+}
@@ -0,0 +1,130 @@
+package glog
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+const defaultPendingMaxRecords = 1000
+
+// PendingOptions configures a PendingHandler.
+type PendingOptions struct {
+	// MaxRecords bounds how many records are held before MarkReady; later ones are dropped and
+	// counted (see Dropped). 0 means 1000.
+	MaxRecords int
+}
+
+// pendingState is the state shared by a PendingHandler and the handlers derived from it.
+type pendingState struct {
+	mu         sync.Mutex
+	ready      atomic.Bool
+	records    []bufferedRecord
+	maxRecords int
+	dropped    atomic.Int64
+	closer     io.Closer
+}
+
+// PendingHandler wraps a slog.Handler whose destination is not available yet, e.g. during bootstrap
+// before a remote sink is connected. Records are held in memory until MarkReady, which writes them
+// in order; after that, records go straight to the wrapped handler. Handlers derived via
+// WithAttrs/WithGroup share the same pending buffer.
+type PendingHandler struct {
+	handler slog.Handler
+	state   *pendingState
+}
+
+// NewPendingHandler creates a PendingHandler around h. opts may be nil.
+func NewPendingHandler(h slog.Handler, opts *PendingOptions) *PendingHandler {
+	maxRecords := defaultPendingMaxRecords
+	if opts != nil && opts.MaxRecords > 0 {
+		maxRecords = opts.MaxRecords
+	}
+	state := &pendingState{maxRecords: maxRecords}
+	if closer, ok := h.(io.Closer); ok {
+		state.closer = closer
+	}
+	return &PendingHandler{handler: h, state: state}
+}
+
+// Enabled reports whether the wrapped handler is enabled for the given level.
+func (h *PendingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+// Handle holds the record until MarkReady, or writes it directly once ready.
+func (h *PendingHandler) Handle(ctx context.Context, r slog.Record) error {
+	s := h.state
+	if s.ready.Load() {
+		return h.handler.Handle(ctx, r)
+	}
+
+	s.mu.Lock()
+	if s.ready.Load() {
+		s.mu.Unlock()
+		return h.handler.Handle(ctx, r)
+	}
+	if len(s.records) >= s.maxRecords {
+		s.mu.Unlock()
+		s.dropped.Add(1)
+		return nil
+	}
+	s.records = append(s.records, bufferedRecord{handler: h.handler, ctx: ctx, record: r.Clone()})
+	s.mu.Unlock()
+	return nil
+}
+
+// WithAttrs returns a new PendingHandler sharing this handler's pending buffer.
+func (h *PendingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &PendingHandler{handler: h.handler.WithAttrs(attrs), state: h.state}
+}
+
+// WithGroup returns a new PendingHandler sharing this handler's pending buffer.
+func (h *PendingHandler) WithGroup(name string) slog.Handler {
+	return &PendingHandler{handler: h.handler.WithGroup(name), state: h.state}
+}
+
+// MarkReady writes the held records to the wrapped handler in the order they were logged and
+// switches to direct logging. Records logged concurrently wait until the held ones are written.
+// Calling it again is a no-op.
+func (h *PendingHandler) MarkReady() error {
+	s := h.state
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ready.Load() {
+		return nil
+	}
+	var errs []error
+	for _, br := range s.records {
+		if err := br.handler.Handle(br.ctx, br.record); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	s.records = nil
+	s.ready.Store(true)
+	return errors.Join(errs...)
+}
+
+// Ready reports whether MarkReady has been called.
+func (h *PendingHandler) Ready() bool {
+	return h.state.ready.Load()
+}
+
+// Dropped returns how many records were dropped because the pending buffer was full.
+func (h *PendingHandler) Dropped() int64 {
+	return h.state.dropped.Load()
+}
+
+// Close marks the handler ready, writing any held records, and closes the wrapped handler if it
+// implements io.Closer.
+func (h *PendingHandler) Close() error {
+	err := h.MarkReady()
+	if h.state.closer != nil {
+		err = errors.Join(err, h.state.closer.Close())
+	}
+	return err
+}
@@ -0,0 +1,61 @@
+package glog
+
+import (
+	"log/slog"
+	"testing"
+)
+
+func TestPendingHandler_MarkReady(t *testing.T) {
+	mem := NewMemoryHandler(nil)
+	ph := NewPendingHandler(mem, nil)
+	logger := slog.New(ph)
+
+	logger.Info("boot 1")
+	logger.With("component", "db").Info("boot 2")
+	logger.Debug("boot 3")
+	if n := len(mem.Records()); n != 0 {
+		t.Fatalf("expected records held before MarkReady, got %d", n)
+	}
+	if ph.Ready() {
+		t.Fatal("expected not ready before MarkReady")
+	}
+
+	if err := ph.MarkReady(); err != nil {
+		t.Fatalf("MarkReady failed: %v", err)
+	}
+	logger.Info("running")
+
+	got := messages(mem)
+	want := []string{"boot 1", "boot 2", "boot 3", "running"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("record %d: expected %q, got %q", i, want[i], got[i])
+		}
+	}
+	if rec := mem.Records()[1]; rec.Attrs["component"].String() != "db" {
+		t.Errorf("expected the bound attrs kept on held records, got %v", rec.Attrs)
+	}
+}
+
+func TestPendingHandler_Bounded(t *testing.T) {
+	mem := NewMemoryHandler(nil)
+	ph := NewPendingHandler(mem, &PendingOptions{MaxRecords: 2})
+	logger := slog.New(ph)
+
+	for _, msg := range []string{"a", "b", "c", "d"} {
+		logger.Info(msg)
+	}
+	if err := ph.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	if got := messages(mem); len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Errorf("expected the first two records kept, got %q", got)
+	}
+	if ph.Dropped() != 2 {
+		t.Errorf("expected 2 dropped records, got %d", ph.Dropped())
+	}
+}
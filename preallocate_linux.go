@@ -0,0 +1,15 @@
+//go:build linux
+
+package glog
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// preallocate reserves length bytes starting at offset without changing the file size,
+// so appends still land at the logical end of the file.
+func preallocate(f *os.File, offset, length int64) error {
+	return unix.Fallocate(int(f.Fd()), unix.FALLOC_FL_KEEP_SIZE, offset, length)
+}
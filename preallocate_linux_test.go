@@ -0,0 +1,58 @@
+//go:build linux
+
+package glog
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestFileWriter_Preallocate(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "glog_test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	const prealloc = 1 << 20
+	filePath := filepath.Join(tmpDir, "prealloc.log")
+	fw := NewFileWriterWithOptions(filePath, FileWriterOptions{PreallocateBytes: prealloc})
+
+	data := []byte("preallocated line\n")
+	var written int64
+	for i := 0; i < 10; i++ {
+		n, err := fw.Write(data)
+		if err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+		written += int64(n)
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		t.Fatalf("stat failed: %v", err)
+	}
+	if info.Size() != written {
+		t.Fatalf("preallocation must not change the logical size: got %d, expected %d", info.Size(), written)
+	}
+	if st, ok := info.Sys().(*syscall.Stat_t); ok && st.Blocks*512 < prealloc {
+		t.Logf("filesystem did not preallocate (blocks=%d); only checking sizes", st.Blocks)
+	}
+
+	if err := fw.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	info, err = os.Stat(filePath)
+	if err != nil {
+		t.Fatalf("stat failed: %v", err)
+	}
+	if info.Size() != written {
+		t.Fatalf("expected final size %d, got %d", written, info.Size())
+	}
+	if st, ok := info.Sys().(*syscall.Stat_t); ok && st.Blocks*512 >= prealloc {
+		t.Errorf("expected preallocated tail to be released on Close, still %d bytes allocated", st.Blocks*512)
+	}
+}
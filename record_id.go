@@ -0,0 +1,77 @@
+package glog
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"sync"
+	"time"
+)
+
+// recordIDFieldName is the field IncludeRecordID adds.
+const recordIDFieldName = "record_id"
+
+// crockford is the Crockford base32 alphabet ULIDs are written in.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ulidSource generates ULIDs: a 48-bit millisecond timestamp followed by 80 random bits, written as
+// 26 Crockford base32 characters so they sort by time. IDs generated within the same millisecond
+// increment the random part of the previous one, so they stay unique and ordered.
+type ulidSource struct {
+	mu     sync.Mutex
+	lastMs uint64
+	hi     uint16 // top 16 random bits
+	lo     uint64 // low 64 random bits
+	now    func() time.Time
+}
+
+// defaultULIDs is the generator IncludeRecordID uses without a RecordIDGenerator.
+var defaultULIDs = &ulidSource{now: time.Now}
+
+// newULID returns a new ULID from defaultULIDs.
+func newULID() string {
+	return defaultULIDs.next()
+}
+
+// next returns the next ULID.
+func (s *ulidSource) next() string {
+	ms := uint64(s.now().UnixMilli())
+
+	s.mu.Lock()
+	if ms > s.lastMs {
+		var b [10]byte
+		_, _ = rand.Read(b[:]) // crypto/rand.Read never fails
+		s.lastMs = ms
+		s.hi = binary.BigEndian.Uint16(b[:2])
+		s.lo = binary.BigEndian.Uint64(b[2:])
+	} else {
+		// same millisecond, or the clock went back: keep the last timestamp and count up
+		ms = s.lastMs
+		s.lo++
+		if s.lo == 0 {
+			s.hi++
+		}
+	}
+	hi, lo := s.hi, s.lo
+	s.mu.Unlock()
+
+	// 128 bits: 48 of time, 80 of randomness; the first character carries only 3 bits
+	var id [26]byte
+	id[0] = crockford[(ms>>45)&0x07]
+	for i := 1; i < 10; i++ {
+		id[i] = crockford[(ms>>(45-5*i))&0x1f]
+	}
+	// the 80 random bits form 16 characters of 5 bits each
+	for i := 0; i < 16; i++ {
+		shift := 75 - 5*i // bit offset of this character within the 80 random bits
+		var v uint64
+		if shift >= 64 {
+			v = uint64(hi) >> (shift - 64)
+		} else if shift > 59 {
+			v = lo>>shift | uint64(hi)<<(64-shift)
+		} else {
+			v = lo >> shift
+		}
+		id[10+i] = crockford[v&0x1f]
+	}
+	return string(id[:])
+}
@@ -0,0 +1,91 @@
+package glog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHandler_IncludeRecordID_Unique(t *testing.T) {
+	var mem syncBuffer
+	handler := NewHandler(&Options{Writer: &mem, Format: FormatJSON, Level: slog.LevelInfo, IncludeRecordID: true})
+	logger := slog.New(handler)
+
+	const goroutines, perGoroutine = 8, 500
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perGoroutine; i++ {
+				logger.Info("concurrent", "i", i)
+			}
+		}()
+	}
+	wg.Wait()
+
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSpace(mem.String()), "\n") {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("failed to parse %q: %v", line, err)
+		}
+		id, _ := entry["record_id"].(string)
+		if len(id) != 26 {
+			t.Fatalf("expected a 26-character ULID, got %q", id)
+		}
+		if seen[id] {
+			t.Fatalf("duplicate record ID %s", id)
+		}
+		seen[id] = true
+	}
+	if len(seen) != goroutines*perGoroutine {
+		t.Errorf("expected %d record IDs, got %d", goroutines*perGoroutine, len(seen))
+	}
+}
+
+func TestULID_TimeOrdered(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := &fakeClock{t: start}
+	src := &ulidSource{now: clock.Now}
+
+	var ids []string
+	for i := 0; i < 5; i++ {
+		ids = append(ids, src.next(), src.next()) // two in the same millisecond
+		clock.Set(clock.Now().Add(time.Millisecond))
+	}
+	clock.Set(start) // a clock stepping back does not break the order
+	ids = append(ids, src.next())
+
+	if !sort.StringsAreSorted(ids) {
+		t.Errorf("expected IDs in generation order, got %v", ids)
+	}
+	// 2024-01-01T12:00:00Z is 1704110400000 ms, "01HK2EA8G0" in Crockford base32
+	if !strings.HasPrefix(ids[0], "01HK2EA8G0") {
+		t.Errorf("expected the timestamp prefix 01HK2EA8G0, got %s", ids[0])
+	}
+}
+
+func TestHandler_RecordIDGenerator(t *testing.T) {
+	var buf bytes.Buffer
+	n := 0
+	handler := NewHandler(&Options{
+		Writer:            &buf,
+		Format:            FormatJSON,
+		Level:             slog.LevelInfo,
+		IncludeRecordID:   true,
+		RecordIDGenerator: func() string { n++; return "id-" + string(rune('0'+n)) },
+	})
+	if err := handler.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "custom", 0)); err != nil {
+		t.Fatalf("Handle failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"record_id":"id-1"`) {
+		t.Errorf("expected the custom generator's ID, got: %s", buf.String())
+	}
+}
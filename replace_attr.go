@@ -0,0 +1,135 @@
+package glog
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+)
+
+// allowKeysReplaceAttr returns a ReplaceAttr that drops every attribute not in keys.
+// Top-level built-in keys are always kept.
+func allowKeysReplaceAttr(keys []string) func(groups []string, a slog.Attr) slog.Attr {
+	allowed := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		allowed[k] = true
+	}
+
+	return func(groups []string, a slog.Attr) slog.Attr {
+		if len(groups) == 0 {
+			switch a.Key {
+			case slog.TimeKey, slog.LevelKey, slog.MessageKey:
+				return a
+			case slog.SourceKey:
+				// the JSON handler expands *slog.Source into function/file/line and passes those
+				// through ReplaceAttr as top-level keys, where the allowlist would drop them
+				if src, ok := a.Value.Any().(*slog.Source); ok && src != nil && *src != (slog.Source{}) {
+					return slog.Any(a.Key, sourceValue(*src))
+				}
+				return a
+			}
+			if allowed[a.Key] {
+				return a
+			}
+			return slog.Attr{}
+		}
+
+		// inside groups: keep when any enclosing group path or the full dotted key is listed
+		path := ""
+		for _, g := range groups {
+			if path == "" {
+				path = g
+			} else {
+				path += "." + g
+			}
+			if allowed[path] {
+				return a
+			}
+		}
+		if allowed[path+"."+a.Key] {
+			return a
+		}
+		return slog.Attr{}
+	}
+}
+
+// redactValuesReplaceAttr returns a ReplaceAttr that replaces every match of patterns in string
+// attribute values, at any group depth, with mask. Top-level built-in keys are left alone.
+func redactValuesReplaceAttr(patterns []*regexp.Regexp, mask string) func(groups []string, a slog.Attr) slog.Attr {
+	return func(groups []string, a slog.Attr) slog.Attr {
+		if a.Value.Kind() != slog.KindString {
+			return a
+		}
+		if len(groups) == 0 {
+			switch a.Key {
+			case slog.TimeKey, slog.LevelKey, slog.MessageKey, slog.SourceKey:
+				return a
+			}
+		}
+		v := a.Value.String()
+		redacted := v
+		for _, p := range patterns {
+			redacted = p.ReplaceAllLiteralString(redacted, mask)
+		}
+		if redacted == v {
+			return a
+		}
+		return slog.String(a.Key, redacted)
+	}
+}
+
+// levelFormatterReplaceAttr returns a ReplaceAttr that renders the top-level level with format.
+func levelFormatterReplaceAttr(format func(slog.Level) string) func(groups []string, a slog.Attr) slog.Attr {
+	return func(groups []string, a slog.Attr) slog.Attr {
+		if len(groups) == 0 && a.Key == slog.LevelKey {
+			if level, ok := a.Value.Any().(slog.Level); ok {
+				return slog.String(a.Key, format(level))
+			}
+		}
+		return a
+	}
+}
+
+// stringifyReplaceAttr renders bool and numeric attribute values as strings, at any group depth.
+func stringifyReplaceAttr(groups []string, a slog.Attr) slog.Attr {
+	switch a.Value.Kind() {
+	case slog.KindBool, slog.KindInt64, slog.KindUint64, slog.KindFloat64:
+		return slog.String(a.Key, a.Value.String())
+	}
+	return a
+}
+
+// emptySourceReplaceAttr returns a ReplaceAttr for the source of records without a PC, which slog's
+// built-in handlers would otherwise emit as an empty source: it is replaced by placeholder, or
+// dropped when placeholder is empty.
+func emptySourceReplaceAttr(placeholder string) func(groups []string, a slog.Attr) slog.Attr {
+	return func(groups []string, a slog.Attr) slog.Attr {
+		// match on the value rather than the key, which the user's ReplaceAttr may have renamed
+		if len(groups) == 0 {
+			if src, ok := a.Value.Any().(*slog.Source); ok && (src == nil || *src == (slog.Source{})) {
+				if placeholder == "" {
+					return slog.Attr{}
+				}
+				return slog.String(a.Key, placeholder)
+			}
+		}
+		return a
+	}
+}
+
+// sourceValue renders a source location the way slog's built-in handlers do (an object in JSON,
+// "file:line" in text) without exposing its fields to further ReplaceAttr calls.
+type sourceValue slog.Source
+
+func (s sourceValue) MarshalJSON() ([]byte, error) {
+	m := struct {
+		Function string `json:"function,omitempty"`
+		File     string `json:"file,omitempty"`
+		Line     int    `json:"line,omitempty"`
+	}{s.Function, s.File, s.Line}
+	return json.Marshal(m)
+}
+
+func (s sourceValue) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%s:%d", s.File, s.Line)), nil
+}
@@ -0,0 +1,125 @@
+package glog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestHandler_AllowKeys(t *testing.T) {
+	var buf bytes.Buffer
+
+	handler := NewHandler(&Options{
+		Writer:         &buf,
+		Format:         FormatJSON,
+		AddSource:      true,
+		AllowKeys:      []string{"user_id", "http.method", "db"},
+		TraceExtractor: DefaultTraceExtractor,
+	})
+	defer handler.Close()
+
+	ctx := context.WithValue(context.Background(), "trace_id", "t-1")
+	slog.New(handler).InfoContext(ctx, "allowlisted",
+		slog.String("user_id", "42"),
+		slog.String("password", "secret"),
+		slog.Group("http", slog.String("method", "GET"), slog.String("cookie", "c")),
+		slog.Group("db", slog.String("table", "users")),
+	)
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("failed to parse JSON: %v, output: %s", err, buf.String())
+	}
+
+	for _, key := range []string{"time", "level", "msg", "source", "user_id", "trace_id"} {
+		if _, ok := entry[key]; !ok {
+			t.Errorf("expected %s to be kept, got: %s", key, buf.String())
+		}
+	}
+	if _, ok := entry["password"]; ok {
+		t.Errorf("expected password to be dropped, got: %s", buf.String())
+	}
+
+	httpGroup, _ := entry["http"].(map[string]any)
+	if httpGroup["method"] != "GET" || httpGroup["cookie"] != nil {
+		t.Errorf("expected only http.method in http group, got %v", entry["http"])
+	}
+	dbGroup, _ := entry["db"].(map[string]any)
+	if dbGroup["table"] != "users" {
+		t.Errorf("expected the whole db group to be kept, got %v", entry["db"])
+	}
+}
+
+func TestHandler_AllowKeys_LineFormat(t *testing.T) {
+	var buf bytes.Buffer
+
+	handler := NewHandler(&Options{
+		Writer:    &buf,
+		Format:    FormatLine,
+		AllowKeys: []string{"kept"},
+	})
+	defer handler.Close()
+
+	slog.New(handler).With(slog.String("dropped_static", "x")).Info("line", slog.Int("kept", 1), slog.Int("dropped", 2))
+
+	out := strings.TrimSpace(buf.String())
+	if !strings.HasSuffix(out, `INFO: line {"kept":1}`) {
+		t.Fatalf("expected only kept field, got: %s", out)
+	}
+}
+
+func TestHandler_AllowKeys_TextSource(t *testing.T) {
+	var buf bytes.Buffer
+
+	handler := NewHandler(&Options{
+		Writer:    &buf,
+		Format:    FormatText,
+		AddSource: true,
+		AllowKeys: []string{"kept"},
+	})
+	defer handler.Close()
+
+	slog.New(handler).Info("text", slog.Int("kept", 1), slog.Int("dropped", 2))
+
+	out := buf.String()
+	if !strings.Contains(out, "source=") || !strings.Contains(out, "replace_attr_test.go:") {
+		t.Errorf("expected source as file:line, got: %s", out)
+	}
+	if !strings.Contains(out, "kept=1") || strings.Contains(out, "dropped") {
+		t.Errorf("expected only kept attr, got: %s", out)
+	}
+}
+
+func TestHandler_RedactValuePatterns(t *testing.T) {
+	card := regexp.MustCompile(`\b\d{4}(?:[ -]?\d{4}){3}\b`)
+	email := regexp.MustCompile(`[\w.+-]+@[\w-]+\.[\w.]+`)
+
+	for _, format := range []FormatType{FormatJSON, FormatLine} {
+		var buf bytes.Buffer
+		handler := NewHandler(&Options{
+			Writer:              &buf,
+			Format:              format,
+			Level:               slog.LevelInfo,
+			RedactValuePatterns: []*regexp.Regexp{card, email},
+		})
+		slog.New(handler).Info("payment 4111 1111 1111 1111 failed",
+			slog.String("note", "charged 4111 1111 1111 1111 for order 42"),
+			slog.Group("user", slog.String("contact", "mail jane.doe@example.com today")),
+			slog.Int("amount", 1234567890123456),
+		)
+
+		out := buf.String()
+		for _, want := range []string{"charged [REDACTED] for order 42", "mail [REDACTED] today", "1234567890123456", "payment 4111 1111 1111 1111 failed"} {
+			if !strings.Contains(out, want) {
+				t.Errorf("format %d: expected %q, got: %s", format, want, out)
+			}
+		}
+		if strings.Contains(out, "jane.doe") {
+			t.Errorf("format %d: expected the email masked, got: %s", format, out)
+		}
+	}
+}
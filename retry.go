@@ -0,0 +1,190 @@
+package glog
+
+import (
+	"errors"
+	"io"
+	"math/rand/v2"
+	"time"
+)
+
+// Defaults for the zero fields of RetryPolicy.
+const (
+	defaultInitialBackoff  = 50 * time.Millisecond
+	defaultMaxBackoff      = 2 * time.Second
+	defaultBreakerCooldown = 30 * time.Second
+)
+
+// ErrCircuitOpen is returned by a network sink's Write when its circuit breaker is open and there is
+// no fallback writer to take the record.
+var ErrCircuitOpen = errors.New("glog: sink circuit breaker is open")
+
+// RetryPolicy configures how a network sink (see UnixgramOptions) handles failed sends. The zero value
+// retries nothing, holds nothing and has no breaker, so a failed send fails the write.
+//
+// Retries run inside Write, which blocks the logging goroutine for the backoffs; keep MaxRetries and
+// the backoffs small and let MaxBuffered and the breaker carry longer outages.
+type RetryPolicy struct {
+	// MaxRetries is how many more times a failed send is tried, with exponential backoff in between.
+	MaxRetries int
+	// InitialBackoff is the wait before the first retry, doubled for each further one; default 50ms.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the wait between two retries; default 2s.
+	MaxBackoff time.Duration
+	// Jitter randomly shortens each wait by up to this fraction (0 to 1), so writers that failed
+	// together do not retry in lockstep.
+	Jitter float64
+	// MaxBuffered is how many bytes of records that could not be sent are held and sent, in order,
+	// before the next record once the endpoint is back. Records that do not fit go to Fallback.
+	// 0 holds nothing.
+	MaxBuffered int
+	// BreakerThreshold opens the circuit breaker after this many records in a row failed: held records
+	// and every record written while it is open go straight to Fallback, without trying the endpoint.
+	// 0 disables the breaker.
+	BreakerThreshold int
+	// BreakerCooldown is how long the breaker stays open; the first record after it tries the endpoint
+	// again, closing the breaker on success and reopening it on failure. Default 30s.
+	BreakerCooldown time.Duration
+	// Fallback receives the records the endpoint did not take and that are not held, and held records
+	// it later rejects for good (e.g. too large), e.g. os.Stderr or a FileWriter. With nil, such writes
+	// fail with the send error (ErrCircuitOpen while the breaker is open), and rejected held records
+	// are dropped.
+	Fallback io.Writer
+
+	now   func() time.Time    // clock; nil means time.Now (tests inject a fake one)
+	sleep func(time.Duration) // waits between retries; nil means time.Sleep
+}
+
+// retrier applies a RetryPolicy to the sends of one sink. It is not safe for concurrent use; the sink
+// calls it with its own lock held.
+type retrier struct {
+	policy RetryPolicy
+	// retryable reports whether a send error may be transient; others fail the write at once.
+	retryable func(error) bool
+
+	failures  int       // records in a row that could not be sent
+	openUntil time.Time // when the open breaker lets a record try again; zero when closed
+	held      [][]byte  // records held for later delivery, oldest first
+	heldBytes int
+}
+
+// newRetrier creates a retrier for policy, filling in defaults.
+func newRetrier(policy RetryPolicy, retryable func(error) bool) *retrier {
+	if policy.InitialBackoff <= 0 {
+		policy.InitialBackoff = defaultInitialBackoff
+	}
+	if policy.MaxBackoff <= 0 {
+		policy.MaxBackoff = defaultMaxBackoff
+	}
+	if policy.BreakerCooldown <= 0 {
+		policy.BreakerCooldown = defaultBreakerCooldown
+	}
+	if policy.now == nil {
+		policy.now = time.Now
+	}
+	if policy.sleep == nil {
+		policy.sleep = time.Sleep
+	}
+	return &retrier{policy: policy, retryable: retryable}
+}
+
+// deliver sends p with send, after any held records, applying the policy when sends fail. A held
+// record the endpoint rejects for good (a non-retryable error, e.g. too large for the new socket)
+// goes to Fallback, or is dropped without one, so it does not block the records behind it.
+func (r *retrier) deliver(p []byte, send func([]byte) error) error {
+	if !r.openUntil.IsZero() && r.policy.now().Before(r.openUntil) {
+		return r.fallback(p, ErrCircuitOpen)
+	}
+
+	var errs []error // Fallback write errors of rejected held records
+	var err error
+	for len(r.held) > 0 {
+		b := r.held[0]
+		if err = r.send(b, send); err != nil && r.retryable(err) {
+			break
+		}
+		r.heldBytes -= len(b)
+		r.held = r.held[1:]
+		if err != nil {
+			if r.policy.Fallback != nil {
+				_, ferr := r.policy.Fallback.Write(b)
+				errs = append(errs, ferr)
+			}
+			err = nil
+		}
+	}
+	if err == nil {
+		err = r.send(p, send)
+	}
+	if err == nil {
+		r.failures = 0
+		r.openUntil = time.Time{}
+		return errors.Join(errs...)
+	}
+	if !r.retryable(err) {
+		return errors.Join(append(errs, err)...)
+	}
+
+	r.failures++
+	if r.policy.BreakerThreshold > 0 && r.failures >= r.policy.BreakerThreshold {
+		// the endpoint has been down for a while: stop holding records and fail fast
+		r.openUntil = r.policy.now().Add(r.policy.BreakerCooldown)
+		for _, b := range r.held {
+			errs = append(errs, r.fallback(b, err))
+		}
+		r.held, r.heldBytes = nil, 0
+		return errors.Join(append(errs, r.fallback(p, err))...)
+	}
+	if r.heldBytes+len(p) <= r.policy.MaxBuffered {
+		r.held = append(r.held, append([]byte(nil), p...))
+		r.heldBytes += len(p)
+		return errors.Join(errs...)
+	}
+	return errors.Join(append(errs, r.fallback(p, err))...)
+}
+
+// send calls send for p, retrying transient failures with backoff up to MaxRetries times.
+func (r *retrier) send(p []byte, send func([]byte) error) error {
+	err := send(p)
+	for attempt := 0; err != nil && attempt < r.policy.MaxRetries && r.retryable(err); attempt++ {
+		r.policy.sleep(r.backoff(attempt))
+		err = send(p)
+	}
+	return err
+}
+
+// backoff returns the wait before retry number attempt (from 0): InitialBackoff doubled per attempt,
+// capped at MaxBackoff, shortened by a random part of Jitter.
+func (r *retrier) backoff(attempt int) time.Duration {
+	d := r.policy.InitialBackoff
+	for i := 0; i < attempt && d < r.policy.MaxBackoff; i++ {
+		d *= 2
+	}
+	d = min(d, r.policy.MaxBackoff)
+	if r.policy.Jitter > 0 {
+		d -= time.Duration(float64(d) * min(r.policy.Jitter, 1) * rand.Float64())
+	}
+	return d
+}
+
+// release hands the held records to the Fallback writer, for a sink that is closing, and returns its
+// write errors. Without a Fallback the held records are dropped.
+func (r *retrier) release() error {
+	var errs []error
+	if r.policy.Fallback != nil {
+		for _, b := range r.held {
+			_, err := r.policy.Fallback.Write(b)
+			errs = append(errs, err)
+		}
+	}
+	r.held, r.heldBytes = nil, 0
+	return errors.Join(errs...)
+}
+
+// fallback writes p to the Fallback writer, or returns err when there is none.
+func (r *retrier) fallback(p []byte, err error) error {
+	if r.policy.Fallback == nil {
+		return err
+	}
+	_, ferr := r.policy.Fallback.Write(p)
+	return ferr
+}
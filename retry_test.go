@@ -0,0 +1,196 @@
+package glog
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// fakeEndpoint is a send func whose failures the test controls.
+type fakeEndpoint struct {
+	down      bool
+	failNext  int    // fail this many sends, then recover
+	reject    string // fail sends of this record with a non-retryable error
+	attempts  int
+	delivered []string
+}
+
+var (
+	errEndpointDown = errors.New("endpoint down")
+	errRejected     = errors.New("record rejected")
+)
+
+func (e *fakeEndpoint) send(p []byte) error {
+	e.attempts++
+	if string(p) == e.reject && !e.down {
+		return errRejected
+	}
+	if e.down || e.failNext > 0 {
+		e.failNext--
+		return errEndpointDown
+	}
+	e.delivered = append(e.delivered, string(p))
+	return nil
+}
+
+func newTestRetrier(policy RetryPolicy, clock *fakeClock, sleeps *[]time.Duration) *retrier {
+	policy.now = clock.Now
+	policy.sleep = func(d time.Duration) { *sleeps = append(*sleeps, d) }
+	return newRetrier(policy, func(err error) bool { return errors.Is(err, errEndpointDown) })
+}
+
+func TestRetrier_TransientFailure(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	var sleeps []time.Duration
+	r := newTestRetrier(RetryPolicy{MaxRetries: 4, InitialBackoff: 10 * time.Millisecond, MaxBackoff: 25 * time.Millisecond}, clock, &sleeps)
+	endpoint := &fakeEndpoint{failNext: 3}
+
+	if err := r.deliver([]byte("a"), endpoint.send); err != nil {
+		t.Fatalf("expected the retries to deliver the record, got %v", err)
+	}
+	if !slices.Equal(endpoint.delivered, []string{"a"}) || endpoint.attempts != 4 {
+		t.Errorf("expected delivery on the 4th attempt, got %q after %d attempts", endpoint.delivered, endpoint.attempts)
+	}
+	// exponential, capped at MaxBackoff
+	if want := []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 25 * time.Millisecond}; !slices.Equal(sleeps, want) {
+		t.Errorf("expected backoffs %v, got %v", want, sleeps)
+	}
+}
+
+func TestRetrier_Jitter(t *testing.T) {
+	r := newRetrier(RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Jitter: 0.5}, nil)
+	for attempt, base := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond} {
+		for range 20 {
+			if d := r.backoff(attempt); d > base || d < base/2 {
+				t.Fatalf("attempt %d: expected a backoff between %v and %v, got %v", attempt, base/2, base, d)
+			}
+		}
+	}
+}
+
+func TestRetrier_OutageAndRecovery(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := &fakeClock{t: start}
+	var sleeps []time.Duration
+	var fallback bytes.Buffer
+	r := newTestRetrier(RetryPolicy{
+		MaxRetries:       1,
+		MaxBuffered:      4,
+		BreakerThreshold: 4,
+		BreakerCooldown:  time.Minute,
+		Fallback:         &fallback,
+	}, clock, &sleeps)
+	endpoint := &fakeEndpoint{down: true}
+	deliver := func(s string) {
+		t.Helper()
+		if err := r.deliver([]byte(s), endpoint.send); err != nil {
+			t.Fatalf("deliver %q failed: %v", s, err)
+		}
+	}
+
+	// held while they fit the buffer, then to the fallback
+	deliver("a1")
+	deliver("a2")
+	deliver("a3")
+	if fallback.String() != "a3" || len(r.held) != 2 {
+		t.Fatalf("expected a1 and a2 held and a3 in the fallback, got %q held %d", fallback.String(), len(r.held))
+	}
+
+	// the 4th failure in a row opens the breaker: held records go to the fallback too
+	deliver("a4")
+	if fallback.String() != "a3a1a2a4" || len(r.held) != 0 {
+		t.Fatalf("expected the breaker to flush held records to the fallback, got %q", fallback.String())
+	}
+	attempts := endpoint.attempts
+	deliver("a5")
+	if endpoint.attempts != attempts || fallback.String() != "a3a1a2a4a5" {
+		t.Errorf("expected the open breaker to fail fast, got %d new attempts", endpoint.attempts-attempts)
+	}
+
+	// after the cooldown a record tries again; a failure reopens the breaker at once
+	clock.Set(start.Add(time.Minute))
+	deliver("a6")
+	if endpoint.attempts == attempts || fallback.String() != "a3a1a2a4a5a6" {
+		t.Errorf("expected a trial send and the record in the fallback, got %q", fallback.String())
+	}
+	attempts = endpoint.attempts
+	deliver("a7")
+	if endpoint.attempts != attempts {
+		t.Error("expected the breaker reopened after the failed trial")
+	}
+
+	// recovery closes the breaker
+	endpoint.down = false
+	clock.Set(start.Add(3 * time.Minute))
+	deliver("b1")
+	deliver("b2")
+	if !slices.Equal(endpoint.delivered, []string{"b1", "b2"}) || r.failures != 0 {
+		t.Errorf("expected delivery after recovery, got %q with %d failures", endpoint.delivered, r.failures)
+	}
+}
+
+func TestRetrier_HeldRecordsDeliveredInOrder(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	var sleeps []time.Duration
+	r := newTestRetrier(RetryPolicy{MaxBuffered: 1 << 10}, clock, &sleeps)
+	endpoint := &fakeEndpoint{down: true}
+
+	for _, s := range []string{"a", "b", "c"} {
+		if err := r.deliver([]byte(s), endpoint.send); err != nil {
+			t.Fatalf("expected %q held, got %v", s, err)
+		}
+	}
+	endpoint.down = false
+	if err := r.deliver([]byte("d"), endpoint.send); err != nil {
+		t.Fatalf("deliver failed: %v", err)
+	}
+	if want := []string{"a", "b", "c", "d"}; !slices.Equal(endpoint.delivered, want) {
+		t.Errorf("expected %q, got %q", want, endpoint.delivered)
+	}
+}
+
+func TestRetrier_HeldRecordRejected(t *testing.T) {
+	for _, withFallback := range []bool{false, true} {
+		clock := &fakeClock{t: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+		var sleeps []time.Duration
+		var fallback bytes.Buffer
+		policy := RetryPolicy{MaxBuffered: 1 << 10}
+		if withFallback {
+			policy.Fallback = &fallback
+		}
+		r := newTestRetrier(policy, clock, &sleeps)
+		endpoint := &fakeEndpoint{down: true, reject: "big"}
+
+		for _, s := range []string{"a", "big", "b"} {
+			if err := r.deliver([]byte(s), endpoint.send); err != nil {
+				t.Fatalf("expected %q held, got %v", s, err)
+			}
+		}
+		// back up, but the endpoint now refuses "big" for good: it must not block the others
+		endpoint.down = false
+		for _, s := range []string{"c", "d"} {
+			if err := r.deliver([]byte(s), endpoint.send); err != nil {
+				t.Fatalf("fallback=%v: deliver %q failed: %v", withFallback, s, err)
+			}
+		}
+		if want := []string{"a", "b", "c", "d"}; !slices.Equal(endpoint.delivered, want) || len(r.held) != 0 || r.heldBytes != 0 {
+			t.Errorf("fallback=%v: expected %q delivered and nothing held, got %q with %d held", withFallback, want, endpoint.delivered, len(r.held))
+		}
+		if want := map[bool]string{false: "", true: "big"}[withFallback]; fallback.String() != want {
+			t.Errorf("fallback=%v: expected %q in the fallback, got %q", withFallback, want, fallback.String())
+		}
+	}
+}
+
+func TestRetrier_NoPolicy(t *testing.T) {
+	r := newRetrier(RetryPolicy{}, func(error) bool { return true })
+	endpoint := &fakeEndpoint{down: true}
+	if err := r.deliver([]byte("a"), endpoint.send); !errors.Is(err, errEndpointDown) {
+		t.Errorf("expected the send error, got %v", err)
+	}
+	if endpoint.attempts != 1 || len(r.held) != 0 {
+		t.Errorf("expected one attempt and nothing held, got %d attempts and %d held", endpoint.attempts, len(r.held))
+	}
+}
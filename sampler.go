@@ -0,0 +1,71 @@
+package glog
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+)
+
+// Sampler decides whether a record is written; returning false drops it. It must be safe for
+// concurrent use.
+//
+// By default it runs first in Handle, before TraceExtractor, AttrExtractor and RecordHandler, so a
+// dropped record costs no enrichment; it then sees only the attributes the record was logged with.
+// Set Options.SampleAfterEnrichment for a sampler that reads the fields added by enrichment.
+type Sampler func(ctx context.Context, r slog.Record) bool
+
+// sample reports whether h's Sampler keeps r, counting the records it drops.
+func (h *Handler) sample(ctx context.Context, r slog.Record) bool {
+	if h.opts.Sampler(ctx, r) {
+		return true
+	}
+	h.drops.sample.Add(1)
+	return false
+}
+
+// RandomSampler returns a Sampler that keeps each record with probability rate (0 keeps none, 1 keeps
+// all). Decisions are drawn from src, so a seeded source (e.g. rand.NewPCG(1, 2)) makes the set of
+// kept records repeatable in tests; nil uses the randomly seeded global source.
+func RandomSampler(rate float64, src rand.Source) Sampler {
+	if src == nil {
+		return func(context.Context, slog.Record) bool {
+			return rand.Float64() < rate
+		}
+	}
+	r := rand.New(src)
+	var mu sync.Mutex // sources are not safe for concurrent use
+	return func(context.Context, slog.Record) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return r.Float64() < rate
+	}
+}
+
+// sampledContextKey is the context key used by WithSampled.
+type sampledContextKey struct{}
+
+// WithSampled returns a context marking the work it carries as sampled for logging, or not, for
+// ContextSampler. A worker pool can keep the debug output of 1 in N workers by marking each worker's
+// context with WithSampled(ctx, id%N == 0).
+func WithSampled(ctx context.Context, sampled bool) context.Context {
+	return context.WithValue(ctx, sampledContextKey{}, sampled)
+}
+
+// IsSampled reports whether ctx was marked as sampled with WithSampled.
+func IsSampled(ctx context.Context) bool {
+	sampled, _ := ctx.Value(sampledContextKey{}).(bool)
+	return sampled
+}
+
+// ContextSampler returns a Sampler that drops records below minLevel unless they were logged with a
+// context marked by WithSampled(ctx, true); records at or above minLevel always pass. nil means
+// slog.LevelInfo, so only debug records are sampled and errors from every worker get through.
+func ContextSampler(minLevel slog.Leveler) Sampler {
+	if minLevel == nil {
+		minLevel = slog.LevelInfo
+	}
+	return func(ctx context.Context, r slog.Record) bool {
+		return r.Level >= minLevel.Level() || IsSampled(ctx)
+	}
+}
@@ -0,0 +1,156 @@
+package glog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestHandler_Sampler_SkipsEnrichment(t *testing.T) {
+	var buf bytes.Buffer
+	var enriched atomic.Int64
+	handler := NewHandler(&Options{
+		Writer: &buf,
+		Format: FormatJSON,
+		Level:  slog.LevelInfo,
+		AttrExtractor: func(context.Context) []slog.Attr {
+			enriched.Add(1)
+			return []slog.Attr{slog.String("tenant", "acme")}
+		},
+		Sampler: func(_ context.Context, r slog.Record) bool {
+			return r.Level >= slog.LevelWarn
+		},
+		LogDropSummary: true,
+	})
+
+	logger := slog.New(handler)
+	logger.Info("sampled out")
+	logger.Info("sampled out")
+	logger.Warn("kept")
+	handler.Close()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"msg":"kept","tenant":"acme"`) {
+		t.Fatalf("expected the kept record with its enrichment and the drop summary, got:\n%s", buf.String())
+	}
+	if !strings.Contains(lines[1], `"sample":2`) {
+		t.Errorf("expected 2 sampled-out records in the drop summary, got: %s", lines[1])
+	}
+	if n := enriched.Load(); n != 1 {
+		t.Errorf("expected only the kept record to be enriched, got %d extractor calls", n)
+	}
+}
+
+func TestHandler_Sampler_AfterEnrichment(t *testing.T) {
+	var buf bytes.Buffer
+	handler := NewHandler(&Options{
+		Writer:         &buf,
+		Format:         FormatJSON,
+		Level:          slog.LevelInfo,
+		TraceExtractor: DefaultTraceExtractor,
+		Sampler: func(_ context.Context, r slog.Record) bool {
+			traced := false
+			r.Attrs(func(a slog.Attr) bool {
+				traced = a.Key == "trace_id"
+				return !traced
+			})
+			return traced
+		},
+		SampleAfterEnrichment: true,
+	})
+
+	logger := slog.New(handler)
+	logger.Info("untraced")
+	logger.InfoContext(SetTraceID(context.Background(), "t-1"), "traced")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], `"msg":"traced"`) {
+		t.Errorf("expected the sampler to see the trace fields and keep only the traced record, got:\n%s", buf.String())
+	}
+}
+
+func TestRandomSampler_Seeded(t *testing.T) {
+	kept := func() []int {
+		var buf bytes.Buffer
+		handler := NewHandler(&Options{
+			Writer:  &buf,
+			Format:  FormatJSON,
+			Level:   slog.LevelInfo,
+			Sampler: RandomSampler(0.5, rand.NewPCG(1, 2)),
+		})
+		logger := slog.New(handler)
+		for i := 0; i < 16; i++ {
+			logger.Info("sampled", "i", i)
+		}
+
+		var ids []int
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			var entry struct{ I int }
+			if err := json.Unmarshal([]byte(line), &entry); err != nil {
+				t.Fatalf("failed to parse %q: %v", line, err)
+			}
+			ids = append(ids, entry.I)
+		}
+		return ids
+	}
+
+	first := kept()
+	if want := []int{1, 3, 5, 7, 9, 12}; !slices.Equal(first, want) {
+		t.Errorf("expected records %v kept with seed (1, 2), got %v", want, first)
+	}
+	if second := kept(); !slices.Equal(first, second) {
+		t.Errorf("expected the same records kept with the same seed, got %v and %v", first, second)
+	}
+}
+
+func TestContextSampler(t *testing.T) {
+	buf := &syncBuffer{}
+	logger := slog.New(NewHandler(&Options{
+		Writer:  buf,
+		Format:  FormatJSON,
+		Level:   slog.LevelDebug,
+		Sampler: ContextSampler(nil),
+	}))
+
+	var wg sync.WaitGroup
+	for id := range 6 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx := context.Background()
+			if id != 5 { // one worker is left unmarked
+				ctx = WithSampled(ctx, id%3 == 0)
+			}
+			logger.DebugContext(ctx, "step", "worker", id)
+			logger.ErrorContext(ctx, "failed", "worker", id)
+		}()
+	}
+	wg.Wait()
+
+	debug, errs := map[float64]bool{}, map[float64]bool{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("failed to parse JSON: %v", err)
+		}
+		worker, _ := entry["worker"].(float64)
+		if entry["level"] == "DEBUG" {
+			debug[worker] = true
+		} else {
+			errs[worker] = true
+		}
+	}
+	if len(debug) != 2 || !debug[0] || !debug[3] {
+		t.Errorf("expected debug records from the sampled workers 0 and 3 only, got %v", debug)
+	}
+	if len(errs) != 6 {
+		t.Errorf("expected errors from all 6 workers, got %v", errs)
+	}
+}
@@ -0,0 +1,136 @@
+package glog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"time"
+)
+
+// SchemaField is one positional field of a Schema.
+type SchemaField struct {
+	Key  string
+	Kind slog.Kind // KindAny accepts any value; KindGroup and KindLogValuer are not supported
+}
+
+// Schema is a fixed, ordered list of attribute keys and kinds for hot paths that always
+// log the same attributes. Use Handler.LogSchema to log values positionally against it.
+type Schema struct {
+	fields []SchemaField
+	attrs  []schemaAttrFunc // one per field, chosen by NewSchema for the field's kind
+}
+
+// schemaAttrFunc builds a field's attribute from v; ok is false when v does not have the field's kind.
+type schemaAttrFunc func(key string, v any) (a slog.Attr, ok bool)
+
+// NewSchema validates and registers a schema. Keys must be non-empty and unique, and kinds
+// supported; values are then only checked against the kinds when logged.
+func NewSchema(fields ...SchemaField) (*Schema, error) {
+	if len(fields) == 0 {
+		return nil, errors.New("glog: schema has no fields")
+	}
+	seen := make(map[string]bool, len(fields))
+	attrs := make([]schemaAttrFunc, len(fields))
+	for i, f := range fields {
+		if f.Key == "" {
+			return nil, fmt.Errorf("glog: schema field %d has an empty key", i)
+		}
+		if seen[f.Key] {
+			return nil, fmt.Errorf("glog: schema field %q is duplicated", f.Key)
+		}
+		seen[f.Key] = true
+		attrs[i] = schemaAttrFuncs[f.Kind]
+		if attrs[i] == nil {
+			return nil, fmt.Errorf("glog: schema field %q has unsupported kind %s", f.Key, f.Kind)
+		}
+	}
+	return &Schema{fields: append([]SchemaField{}, fields...), attrs: attrs}, nil
+}
+
+// LogSchema logs msg with one attribute per schema field, taking values positionally.
+// Each value must match its field's kind; attributes are built directly from the typed
+// values, skipping slog's key/value argument parsing. Nothing is done if level is disabled.
+func (h *Handler) LogSchema(ctx context.Context, s *Schema, level slog.Level, msg string, values ...any) error {
+	if len(values) != len(s.fields) {
+		return fmt.Errorf("glog: schema expects %d values, got %d", len(s.fields), len(values))
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if !h.Enabled(ctx, level) {
+		return nil
+	}
+
+	var pc uintptr
+	if h.opts.AddSource || h.opts.SourceLevel != nil {
+		var pcs [1]uintptr
+		runtime.Callers(2, pcs[:]) // skip runtime.Callers and LogSchema
+		pc = pcs[0]
+	}
+
+	r := slog.NewRecord(time.Now(), level, msg, pc)
+	for i, f := range s.fields {
+		a, ok := s.attrs[i](f.Key, values[i])
+		if !ok {
+			return fmt.Errorf("glog: schema field %q expects %s, got %T", f.Key, f.Kind, values[i])
+		}
+		r.AddAttrs(a)
+	}
+	return h.Handle(ctx, r)
+}
+
+// schemaAttrFuncs holds the attribute builder for each supported kind.
+var schemaAttrFuncs = map[slog.Kind]schemaAttrFunc{
+	slog.KindString: func(key string, v any) (slog.Attr, bool) {
+		s, ok := v.(string)
+		return slog.String(key, s), ok
+	},
+	slog.KindInt64: func(key string, v any) (slog.Attr, bool) {
+		switch n := v.(type) {
+		case int:
+			return slog.Int(key, n), true
+		case int64:
+			return slog.Int64(key, n), true
+		case int32:
+			return slog.Int64(key, int64(n)), true
+		}
+		return slog.Attr{}, false
+	},
+	slog.KindUint64: func(key string, v any) (slog.Attr, bool) {
+		switch n := v.(type) {
+		case uint:
+			return slog.Uint64(key, uint64(n)), true
+		case uint64:
+			return slog.Uint64(key, n), true
+		case uint32:
+			return slog.Uint64(key, uint64(n)), true
+		}
+		return slog.Attr{}, false
+	},
+	slog.KindFloat64: func(key string, v any) (slog.Attr, bool) {
+		switch n := v.(type) {
+		case float64:
+			return slog.Float64(key, n), true
+		case float32:
+			return slog.Float64(key, float64(n)), true
+		}
+		return slog.Attr{}, false
+	},
+	slog.KindBool: func(key string, v any) (slog.Attr, bool) {
+		b, ok := v.(bool)
+		return slog.Bool(key, b), ok
+	},
+	slog.KindDuration: func(key string, v any) (slog.Attr, bool) {
+		d, ok := v.(time.Duration)
+		return slog.Duration(key, d), ok
+	},
+	slog.KindTime: func(key string, v any) (slog.Attr, bool) {
+		t, ok := v.(time.Time)
+		return slog.Time(key, t), ok
+	},
+	slog.KindAny: func(key string, v any) (slog.Attr, bool) {
+		return slog.Any(key, v), true
+	},
+}
@@ -0,0 +1,131 @@
+package glog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewSchema_Validation(t *testing.T) {
+	if _, err := NewSchema(); err == nil {
+		t.Error("expected error for empty schema")
+	}
+	if _, err := NewSchema(SchemaField{Key: "", Kind: slog.KindString}); err == nil {
+		t.Error("expected error for empty key")
+	}
+	if _, err := NewSchema(
+		SchemaField{Key: "a", Kind: slog.KindString},
+		SchemaField{Key: "a", Kind: slog.KindInt64},
+	); err == nil {
+		t.Error("expected error for duplicate key")
+	}
+	if _, err := NewSchema(SchemaField{Key: "g", Kind: slog.KindGroup}); err == nil {
+		t.Error("expected error for group kind")
+	}
+	if _, err := NewSchema(SchemaField{Key: "x", Kind: slog.Kind(99)}); err == nil {
+		t.Error("expected error for unknown kind")
+	}
+}
+
+func TestHandler_LogSchema(t *testing.T) {
+	var buf bytes.Buffer
+
+	handler := NewHandler(&Options{Writer: &buf, Format: FormatJSON})
+	defer handler.Close()
+
+	schema, err := NewSchema(
+		SchemaField{Key: "user", Kind: slog.KindString},
+		SchemaField{Key: "status", Kind: slog.KindInt64},
+		SchemaField{Key: "ok", Kind: slog.KindBool},
+		SchemaField{Key: "latency", Kind: slog.KindDuration},
+		SchemaField{Key: "extra", Kind: slog.KindAny},
+	)
+	if err != nil {
+		t.Fatalf("NewSchema failed: %v", err)
+	}
+
+	err = handler.LogSchema(context.Background(), schema, slog.LevelInfo, "request",
+		"alice", 200, true, 15*time.Millisecond, []int{1, 2})
+	if err != nil {
+		t.Fatalf("LogSchema failed: %v", err)
+	}
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("failed to parse JSON: %v, output: %s", err, buf.String())
+	}
+	if entry["msg"] != "request" || entry["user"] != "alice" || entry["status"] != float64(200) || entry["ok"] != true {
+		t.Errorf("unexpected entry: %v", entry)
+	}
+	if entry["latency"] != float64(15*time.Millisecond) {
+		t.Errorf("unexpected latency: %v", entry["latency"])
+	}
+}
+
+func TestHandler_LogSchema_TypeMismatch(t *testing.T) {
+	var buf bytes.Buffer
+
+	handler := NewHandler(&Options{Writer: &buf})
+	defer handler.Close()
+
+	schema, _ := NewSchema(
+		SchemaField{Key: "user", Kind: slog.KindString},
+		SchemaField{Key: "status", Kind: slog.KindInt64},
+	)
+
+	err := handler.LogSchema(context.Background(), schema, slog.LevelInfo, "request", "alice", "200")
+	if err == nil || !strings.Contains(err.Error(), `"status"`) {
+		t.Fatalf("expected type mismatch error for status, got %v", err)
+	}
+	if err := handler.LogSchema(context.Background(), schema, slog.LevelInfo, "request", "alice"); err == nil {
+		t.Fatal("expected error for wrong number of values")
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("expected nothing written on error, got: %s", buf.String())
+	}
+}
+
+func TestHandler_LogSchema_Disabled(t *testing.T) {
+	var buf bytes.Buffer
+
+	handler := NewHandler(&Options{Writer: &buf, Level: slog.LevelWarn})
+	defer handler.Close()
+
+	schema, _ := NewSchema(SchemaField{Key: "n", Kind: slog.KindInt64})
+	if err := handler.LogSchema(context.Background(), schema, slog.LevelInfo, "skipped", 1); err != nil {
+		t.Fatalf("LogSchema failed: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("expected no output below level, got: %s", buf.String())
+	}
+}
+
+func TestHandler_LogSchema_SourceLevel(t *testing.T) {
+	var buf bytes.Buffer
+
+	handler := NewHandler(&Options{Writer: &buf, Format: FormatJSON, SourceLevel: slog.LevelWarn})
+	defer handler.Close()
+
+	schema, _ := NewSchema(SchemaField{Key: "n", Kind: slog.KindInt64})
+	if err := handler.LogSchema(context.Background(), schema, slog.LevelInfo, "below", 1); err != nil {
+		t.Fatalf("LogSchema failed: %v", err)
+	}
+	if err := handler.LogSchema(context.Background(), schema, slog.LevelError, "above", 2); err != nil {
+		t.Fatalf("LogSchema failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got: %s", buf.String())
+	}
+	if strings.Contains(lines[0], "source") {
+		t.Errorf("expected no source below SourceLevel, got: %s", lines[0])
+	}
+	if !strings.Contains(lines[1], "schema_test.go") {
+		t.Errorf("expected the caller's source at SourceLevel, got: %s", lines[1])
+	}
+}
@@ -0,0 +1,27 @@
+package glog
+
+import "io"
+
+// SecondaryOutput is a second output every record is written to as well, in its own format and
+// destination, e.g. while a new ingestion pipeline is verified against the current one before
+// switching to it. Records are enriched once (trace fields, extractors, RecordHandler, StaticFields)
+// and then encoded separately for each output.
+type SecondaryOutput struct {
+	// Format is the encoding of the second output, e.g. FormatJSON. FormatByLevel does not apply to it.
+	Format FormatType
+	// Writer receives the second output. When nil, it goes to a FileWriter at LogPath.
+	Writer io.Writer
+	// LogPath is the second output's file path when Writer is nil; it supports Go time layouts like
+	// Options.LogPath.
+	LogPath string
+	// File configures the FileWriter for LogPath, e.g. Compress to gzip each finished file.
+	File FileWriterOptions
+}
+
+// writer returns the writer the second output goes to.
+func (s *SecondaryOutput) writer() io.Writer {
+	if s.Writer != nil {
+		return s.Writer
+	}
+	return NewFileWriterWithOptions(s.LogPath, s.File)
+}
@@ -0,0 +1,98 @@
+package glog
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHandler_Secondary(t *testing.T) {
+	tmpDir := t.TempDir()
+	handler := NewHandler(&Options{
+		LogPath:        filepath.Join(tmpDir, "plain.log"),
+		Format:         FormatLine,
+		Level:          slog.LevelInfo,
+		TraceExtractor: DefaultTraceExtractor,
+		StaticFields:   map[string]any{"service": "api"},
+		Secondary: &SecondaryOutput{
+			Format:  FormatJSON,
+			LogPath: filepath.Join(tmpDir, "structured.log"),
+			File:    FileWriterOptions{Compress: true},
+		},
+	})
+	logger := slog.New(handler)
+	ctx := SetTraceID(context.Background(), "t-1")
+	logger.InfoContext(ctx, "first", "n", 1)
+	logger.WarnContext(ctx, "second", "n", 2)
+	logger.Debug("filtered")
+	if err := handler.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	plain, err := os.ReadFile(filepath.Join(tmpDir, "plain.log"))
+	if err != nil {
+		t.Fatalf("failed to read the plain file: %v", err)
+	}
+	plainLines := strings.Split(strings.TrimSpace(string(plain)), "\n")
+
+	archive, err := os.Open(filepath.Join(tmpDir, "structured.log.gz"))
+	if err != nil {
+		t.Fatalf("expected the structured file compressed: %v", err)
+	}
+	defer archive.Close()
+	zr, err := gzip.NewReader(archive)
+	if err != nil {
+		t.Fatalf("failed to open gzip stream: %v", err)
+	}
+	var structured []map[string]any
+	scanner := bufio.NewScanner(zr)
+	for scanner.Scan() {
+		var entry map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("failed to parse JSON %q: %v", scanner.Text(), err)
+		}
+		structured = append(structured, entry)
+	}
+
+	if len(plainLines) != 2 || len(structured) != 2 {
+		t.Fatalf("expected 2 records in each output, got %d plain and %d structured", len(plainLines), len(structured))
+	}
+	for i, want := range []struct {
+		level, msg string
+		n          float64
+	}{{"INFO", "first", 1}, {"WARN", "second", 2}} {
+		line := plainLines[i]
+		if !strings.Contains(line, want.level+": "+want.msg+" {") || !strings.Contains(line, `"trace_id":"t-1"`) || !strings.Contains(line, `"service":"api"`) {
+			t.Errorf("unexpected plain line %d: %s", i, line)
+		}
+		entry := structured[i]
+		if entry["level"] != want.level || entry["msg"] != want.msg || entry["n"] != want.n || entry["trace_id"] != "t-1" || entry["service"] != "api" {
+			t.Errorf("unexpected structured record %d: %v", i, entry)
+		}
+	}
+}
+
+func TestHandler_SecondaryWriter(t *testing.T) {
+	var primary, secondary bytes.Buffer
+	handler := NewHandler(&Options{
+		Writer:    &primary,
+		Format:    FormatJSON,
+		Level:     slog.LevelInfo,
+		Secondary: &SecondaryOutput{Format: FormatText, Writer: &secondary},
+	})
+	slog.New(handler).With("user", "u-1").WithGroup("req").Info("served", "status", 200)
+
+	if !strings.Contains(primary.String(), `"user":"u-1","req":{"status":200}`) {
+		t.Errorf("unexpected primary output: %s", primary.String())
+	}
+	if !strings.Contains(secondary.String(), `msg=served user=u-1 req.status=200`) {
+		t.Errorf("unexpected secondary output: %s", secondary.String())
+	}
+}
@@ -0,0 +1,42 @@
+package glog
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+)
+
+// InstallFlushOnSignal flushes h (see Handler.Flush) when the process receives one of signals,
+// e.g. syscall.SIGINT and syscall.SIGTERM, so buffered records are not lost on shutdown.
+//
+// The signal is not swallowed: after flushing, glog stops listening and re-sends the signal to the
+// process, so the default behavior (usually exiting) or other signal.Notify listeners still apply.
+// Other listeners therefore see the signal twice. Only the first signal is handled.
+//
+// The returned func stops listening; it is safe to call more than once.
+func InstallFlushOnSignal(h *Handler, signals ...os.Signal) (stop func()) {
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, signals...)
+
+	go func() {
+		select {
+		case <-done:
+			return
+		case sig := <-ch:
+			_ = h.Flush() // nothing to report to; the process is most likely about to exit
+			signal.Stop(ch)
+			if p, err := os.FindProcess(os.Getpid()); err == nil {
+				_ = p.Signal(sig)
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
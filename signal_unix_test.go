@@ -0,0 +1,51 @@
+//go:build unix
+
+package glog
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestInstallFlushOnSignal(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "glog_test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	logPath := filepath.Join(tmpDir, "signal.log")
+	handler := NewHandler(&Options{
+		LogPath:       logPath,
+		FlushInterval: 3600, // only an explicit flush writes the buffer during the test
+		Level:         slog.LevelInfo,
+	})
+	defer handler.Close()
+
+	stop := InstallFlushOnSignal(handler, syscall.SIGWINCH) // ignored by default, so the test survives re-raising
+	defer stop()
+
+	slog.New(handler).Info("buffered before signal")
+	if content, _ := os.ReadFile(logPath); len(content) != 0 {
+		t.Fatalf("expected record to be buffered, got: %s", content)
+	}
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGWINCH); err != nil {
+		t.Fatalf("failed to send signal: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		content, _ := os.ReadFile(logPath)
+		if strings.Contains(string(content), "buffered before signal") {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("expected buffer flushed after signal")
+}
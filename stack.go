@@ -0,0 +1,52 @@
+package glog
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// defaultStackTraceKey is the StackTraceKey used when it is empty, outside ECS mode.
+const defaultStackTraceKey = "stacktrace"
+
+// maxStackDepth caps the number of frames stackTrace collects.
+const maxStackDepth = 64
+
+// stackTrace returns the stack of the calling goroutine formatted like runtime/debug.Stack, one
+// "function\n\tfile:line" entry per frame. When pc, the record's source location, is on the stack, it
+// starts at that frame, leaving out slog and the handlers it went through.
+func stackTrace(pc uintptr) string {
+	var pcs [maxStackDepth]uintptr
+	n := runtime.Callers(2, pcs[:]) // skip runtime.Callers and stackTrace
+	var frames []runtime.Frame
+	it := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := it.Next()
+		frames = append(frames, frame)
+		if !more {
+			break
+		}
+	}
+	if pc != 0 {
+		caller, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+		for i, frame := range frames {
+			if frame.Function == caller.Function {
+				frames = frames[i:]
+				break
+			}
+		}
+	}
+
+	var b strings.Builder
+	for i, frame := range frames {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(frame.Function)
+		b.WriteString("\n\t")
+		b.WriteString(frame.File)
+		b.WriteByte(':')
+		b.WriteString(strconv.Itoa(frame.Line))
+	}
+	return b.String()
+}
@@ -0,0 +1,75 @@
+package glog
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestHandler_StackTraceKey(t *testing.T) {
+	cases := []struct {
+		name string
+		opts Options
+		key  string
+	}{
+		{name: "default", key: "stacktrace"},
+		{name: "configured", opts: Options{StackTraceKey: "stack"}, key: "stack"},
+		{name: "ecs", opts: Options{ECS: true}, key: "error.stack_trace"},
+		{name: "ecs configured", opts: Options{ECS: true, StackTraceKey: "stack_trace"}, key: "stack_trace"},
+		{name: "allowlist", opts: Options{StackTraceKey: "stack", AllowKeys: []string{"user"}}, key: "stack"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			opts := tc.opts
+			opts.Writer = &buf
+			opts.Format = FormatJSON
+			opts.Level = slog.LevelInfo
+			opts.StackTraceLevel = slog.LevelError
+			logger := slog.New(NewHandler(&opts))
+			logger.Info("fine")
+			logger.Error("failed", "user", "u-1")
+
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			if len(lines) != 2 {
+				t.Fatalf("expected 2 lines, got: %s", buf.String())
+			}
+			if strings.Contains(lines[0], `"`+tc.key+`"`) {
+				t.Errorf("expected no stack below StackTraceLevel, got: %s", lines[0])
+			}
+			var entry map[string]any
+			if err := json.Unmarshal([]byte(lines[1]), &entry); err != nil {
+				t.Fatalf("failed to parse JSON: %v", err)
+			}
+			stack, _ := entry[tc.key].(string)
+			// the stack starts at the logging function, not inside slog or the handler
+			if !strings.HasPrefix(stack, "github.com/lyuangg/glog.TestHandler_StackTraceKey.func1\n\t") {
+				t.Errorf("expected a stack under %q starting at the caller, got: %s", tc.key, lines[1])
+			}
+		})
+	}
+}
+
+func TestHandler_StackTraceKeyReplaceAttr(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&Options{
+		Writer:          &buf,
+		Format:          FormatJSON,
+		Level:           slog.LevelInfo,
+		StackTraceLevel: slog.LevelError,
+		StackTraceKey:   "stack",
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == "stack" {
+				a.Key = "exception.stacktrace"
+			}
+			return a
+		},
+	}))
+	logger.Error("failed")
+
+	if !strings.Contains(buf.String(), `"exception.stacktrace":"github.com/lyuangg/glog.TestHandler_StackTraceKeyReplaceAttr`) {
+		t.Errorf("expected ReplaceAttr to remap the stack key, got: %s", buf.String())
+	}
+}
@@ -0,0 +1,18 @@
+package glog
+
+import "io"
+
+// transformWriter passes each record written to it through fn before writing it to w. Like
+// envelopeWriter, it relies on the encoders writing one complete record per Write.
+type transformWriter struct {
+	w  io.Writer
+	fn func([]byte) []byte
+}
+
+// Write writes fn(p) to w in a single call. It reports len(p) when the transformed record was written.
+func (t *transformWriter) Write(p []byte) (int, error) {
+	if _, err := t.w.Write(t.fn(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
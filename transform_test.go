@@ -0,0 +1,57 @@
+package glog
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestHandler_BytesTransformer(t *testing.T) {
+	for _, format := range []FormatType{FormatLine, FormatJSON, FormatText} {
+		var buf bytes.Buffer
+		logger := slog.New(NewHandler(&Options{
+			Writer:           &buf,
+			Format:           format,
+			Level:            slog.LevelInfo,
+			BytesTransformer: bytes.ToUpper,
+		}))
+		logger.Info("served", "path", "/api")
+		logger.Info("second")
+
+		out := buf.String()
+		if out != strings.ToUpper(out) || !strings.Contains(out, "SERVED") || !strings.Contains(out, "/API") {
+			t.Errorf("format %d: expected uppercased output, got: %s", format, out)
+		}
+		if strings.Count(out, "\n") != 2 {
+			t.Errorf("format %d: expected the transform to run per record, got: %q", format, out)
+		}
+	}
+}
+
+func TestHandler_BytesTransformerPrefix(t *testing.T) {
+	var buf bytes.Buffer
+	var emitted []string
+	logger := slog.New(NewHandler(&Options{
+		Writer: &buf,
+		Format: FormatJSON,
+		Level:  slog.LevelInfo,
+		BytesTransformer: func(p []byte) []byte {
+			return append([]byte("host-1 "), p...)
+		},
+		Emit: func(_ context.Context, formatted []byte, _ slog.Record) {
+			emitted = append(emitted, string(formatted))
+		},
+	}))
+	logger.Info("a")
+	logger.Info("b")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], `host-1 {"time"`) || !strings.HasPrefix(lines[1], `host-1 {"time"`) {
+		t.Errorf("expected every record prefixed, got: %s", buf.String())
+	}
+	if len(emitted) != 2 || !strings.HasPrefix(emitted[0], `{"time"`) {
+		t.Errorf("expected Emit to see the record before the transform, got %q", emitted)
+	}
+}
@@ -0,0 +1,148 @@
+package glog
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"syscall"
+)
+
+// UnixgramOptions configures a UnixgramWriter.
+type UnixgramOptions struct {
+	// TruncateOversized cuts a record too large for one datagram (EMSGSIZE) down to a size the socket
+	// accepts, instead of failing the write with the error. The size is found by halving the record, so
+	// it may be well below the socket's limit; later records are cut to it directly. A cut record keeps
+	// its trailing newline.
+	TruncateOversized bool
+	// Retry configures retries with backoff, holding records during an outage, a circuit breaker and a
+	// fallback writer. The zero value keeps the single reconnect-and-retry described on UnixgramWriter.
+	// Oversized records (EMSGSIZE) are never retried.
+	Retry RetryPolicy
+}
+
+// UnixgramWriter sends each Write as one datagram to a Unix datagram socket, such as the one a local
+// log forwarder (Vector, Fluent Bit, ...) listens on. It is safe for concurrent use and can be used as
+// Options.Writer; every record the handlers write then becomes one datagram.
+//
+// The socket is connected on the first write. When a send fails, e.g. because the listener restarted
+// and recreated the socket, the writer reconnects and retries once; UnixgramOptions.Retry adds more.
+type UnixgramWriter struct {
+	path  string
+	opts  UnixgramOptions
+	retry *retrier
+
+	mu     sync.Mutex
+	conn   *net.UnixConn
+	limit  int // datagram size the socket accepted after an EMSGSIZE; 0 until one happens
+	closed bool
+}
+
+// NewUnixgramWriter creates a UnixgramWriter sending to the socket at path.
+func NewUnixgramWriter(path string) *UnixgramWriter {
+	return NewUnixgramWriterWithOptions(path, UnixgramOptions{})
+}
+
+// NewUnixgramWriterWithOptions creates a UnixgramWriter with options.
+func NewUnixgramWriterWithOptions(path string, opts UnixgramOptions) *UnixgramWriter {
+	return &UnixgramWriter{path: path, opts: opts, retry: newRetrier(opts.Retry, func(err error) bool {
+		return !errors.Is(err, syscall.EMSGSIZE)
+	})}
+}
+
+// Write sends p as a single datagram. With TruncateOversized, an oversized p is cut and len(p) is
+// still reported, since the record was delivered. A record held by the Retry policy or taken by its
+// Fallback counts as written too.
+func (w *UnixgramWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, net.ErrClosed
+	}
+
+	n := len(p)
+	if w.opts.TruncateOversized && w.limit > 0 && len(p) > w.limit {
+		p = truncateRecord(p, w.limit)
+	}
+	if err := w.retry.deliver(p, w.sendRecordLocked); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// sendRecordLocked sends one record, reconnecting and retrying once when the send fails and
+// truncating it on EMSGSIZE with TruncateOversized. Caller must hold w.mu.
+func (w *UnixgramWriter) sendRecordLocked(p []byte) error {
+	err := w.sendLocked(p)
+	if err != nil && !errors.Is(err, syscall.EMSGSIZE) {
+		// the listener may have gone away and come back under the same path
+		w.closeConnLocked()
+		err = w.sendLocked(p)
+	}
+	if errors.Is(err, syscall.EMSGSIZE) && w.opts.TruncateOversized {
+		err = w.sendTruncatedLocked(p)
+	}
+	return err
+}
+
+// sendLocked sends p, connecting first if needed. Caller must hold w.mu.
+func (w *UnixgramWriter) sendLocked(p []byte) error {
+	if w.conn == nil {
+		conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: w.path, Net: "unixgram"})
+		if err != nil {
+			return err
+		}
+		w.conn = conn
+	}
+	_, err := w.conn.Write(p)
+	return err
+}
+
+// sendTruncatedLocked halves p until the socket accepts it, and remembers that size for later
+// oversized writes. Caller must hold w.mu.
+func (w *UnixgramWriter) sendTruncatedLocked(p []byte) error {
+	for n := len(p) / 2; n > 0; n /= 2 {
+		err := w.sendLocked(truncateRecord(p, n))
+		if err == nil {
+			w.limit = n
+			return nil
+		}
+		if !errors.Is(err, syscall.EMSGSIZE) {
+			return err
+		}
+	}
+	return syscall.EMSGSIZE
+}
+
+// truncateRecord returns the first n bytes of p, ending in a newline when p does. p is not modified.
+func truncateRecord(p []byte, n int) []byte {
+	if n >= len(p) || p[len(p)-1] != '\n' {
+		return p[:min(n, len(p))]
+	}
+	out := make([]byte, n)
+	copy(out, p[:n-1])
+	out[n-1] = '\n'
+	return out
+}
+
+// closeConnLocked closes the connection, if any. Caller must hold w.mu.
+func (w *UnixgramWriter) closeConnLocked() {
+	if w.conn != nil {
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+}
+
+// Close closes the connection. Records still held by the Retry policy go to its Fallback writer.
+// Writes after Close fail with net.ErrClosed.
+func (w *UnixgramWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	err := w.retry.release()
+	if w.conn == nil {
+		return err
+	}
+	err = errors.Join(err, w.conn.Close())
+	w.conn = nil
+	return err
+}
@@ -0,0 +1,161 @@
+//go:build unix
+
+package glog
+
+import (
+	"bytes"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// listenUnixgram listens on a datagram socket at path.
+func listenUnixgram(t *testing.T, path string) *net.UnixConn {
+	t.Helper()
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	return conn
+}
+
+// readDatagram reads one datagram from conn.
+func readDatagram(t *testing.T, conn *net.UnixConn) string {
+	t.Helper()
+	buf := make([]byte, 1<<20)
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	return string(buf[:n])
+}
+
+func TestUnixgramWriter_OneDatagramPerRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.sock")
+	listener := listenUnixgram(t, path)
+	defer listener.Close()
+
+	w := NewUnixgramWriter(path)
+	defer w.Close()
+	handler := NewHandler(&Options{Writer: w, Format: FormatJSON, Level: slog.LevelInfo})
+	logger := slog.New(handler)
+	logger.Info("first", "n", 1)
+	logger.Info("second", "n", 2)
+
+	for _, want := range []string{`"msg":"first","n":1}`, `"msg":"second","n":2}`} {
+		got := readDatagram(t, listener)
+		if !strings.HasSuffix(got, want+"\n") || strings.Count(got, "\n") != 1 {
+			t.Errorf("expected one record per datagram ending with %s, got %q", want, got)
+		}
+	}
+}
+
+func TestUnixgramWriter_ReconnectsAfterRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.sock")
+	listener := listenUnixgram(t, path)
+
+	w := NewUnixgramWriter(path)
+	defer w.Close()
+	if _, err := w.Write([]byte("before\n")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if got := readDatagram(t, listener); got != "before\n" {
+		t.Errorf("expected the first datagram, got %q", got)
+	}
+
+	// the forwarder restarts and recreates its socket
+	listener.Close()
+	os.Remove(path)
+	listener = listenUnixgram(t, path)
+	defer listener.Close()
+
+	if _, err := w.Write([]byte("after\n")); err != nil {
+		t.Fatalf("Write after restart failed: %v", err)
+	}
+	if got := readDatagram(t, listener); got != "after\n" {
+		t.Errorf("expected the datagram on the new socket, got %q", got)
+	}
+}
+
+func TestUnixgramWriter_Oversized(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.sock")
+	listener := listenUnixgram(t, path)
+	defer listener.Close()
+	huge := append(bytes.Repeat([]byte("x"), 4<<20), '\n')
+
+	strict := NewUnixgramWriter(path)
+	defer strict.Close()
+	if _, err := strict.Write(huge); err == nil {
+		t.Fatal("expected an error for a record larger than a datagram")
+	}
+
+	truncating := NewUnixgramWriterWithOptions(path, UnixgramOptions{TruncateOversized: true})
+	defer truncating.Close()
+	n, err := truncating.Write(huge)
+	if err != nil || n != len(huge) {
+		t.Fatalf("expected the truncated record to count as written, got n=%d err=%v", n, err)
+	}
+	if got := readDatagram(t, listener); len(got) == 0 || len(got) >= len(huge) || !strings.HasSuffix(got, "x\n") {
+		t.Errorf("expected a truncated datagram ending in a newline, got %d bytes", len(got))
+	}
+	// later oversized records are cut to the size found, without searching again
+	if _, err := truncating.Write(huge); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if got := readDatagram(t, listener); !strings.HasSuffix(got, "x\n") || bytes.Count(huge, []byte("\n")) != 1 {
+		t.Errorf("expected the record cut with its newline kept and the input untouched, got %d bytes", len(got))
+	}
+}
+
+func TestUnixgramWriter_WriteAfterClose(t *testing.T) {
+	w := NewUnixgramWriter(filepath.Join(t.TempDir(), "missing.sock"))
+	w.Close()
+	if _, err := w.Write([]byte("late\n")); err != net.ErrClosed {
+		t.Errorf("expected net.ErrClosed, got %v", err)
+	}
+}
+
+func TestUnixgramWriter_RetryHoldsDuringOutage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.sock")
+	var fallback bytes.Buffer
+	w := NewUnixgramWriterWithOptions(path, UnixgramOptions{Retry: RetryPolicy{
+		MaxRetries:     1,
+		InitialBackoff: time.Millisecond,
+		MaxBuffered:    1 << 10,
+		Fallback:       &fallback,
+	}})
+
+	// nothing listens yet: the record is held
+	if _, err := w.Write([]byte("during\n")); err != nil {
+		t.Fatalf("expected the record held, got %v", err)
+	}
+
+	listener := listenUnixgram(t, path)
+	defer listener.Close()
+	if _, err := w.Write([]byte("after\n")); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	for _, want := range []string{"during\n", "after\n"} {
+		if got := readDatagram(t, listener); got != want {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+	}
+
+	// records still held at Close go to the fallback
+	listener.Close()
+	os.Remove(path)
+	if _, err := w.Write([]byte("lost\n")); err != nil {
+		t.Fatalf("expected the record held, got %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if fallback.String() != "lost\n" {
+		t.Errorf("expected the held record in the fallback, got %q", fallback.String())
+	}
+}
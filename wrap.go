@@ -0,0 +1,67 @@
+package glog
+
+import (
+	"context"
+	"io"
+	"log"
+	"log/slog"
+	"reflect"
+	"sync/atomic"
+)
+
+// WrapHandler returns a handler that applies glog's record processing on top of inner: trace and
+// context injection (TraceExtractor, AttrExtractor, WithCorrelationID, IncludeDeadline, IncludeRawLevel,
+// IncludeGroupPath), RecordHandler, RequireMessage, AttrOrder, StaticFields, DedupWindow and AlertCooldown.
+// Encoding, levels and output are left to inner, so the options for those (Format, Level, Writer,
+// ReplaceAttr, ...) are ignored. Use it to enrich records logged through slog.Default:
+//
+//	slog.SetDefault(slog.New(glog.WrapHandler(slog.Default().Handler(), opts)))
+//
+// slog's built-in default handler writes through the log package, which slog.SetDefault points back
+// at the new default; wrapping it as is would make every record call itself. WrapHandler replaces it
+// with a slog.TextHandler writing where the log package wrote at the time of the call (os.Stderr
+// unless log.SetOutput changed it), at the level it had then.
+//
+// The returned handler is a *Handler; its Close flushes held records but closes nothing.
+func WrapHandler(inner slog.Handler, opts *Options) slog.Handler {
+	if opts == nil {
+		opts = defaultOptions()
+	}
+	if isSlogDefaultHandler(inner) {
+		inner = slog.NewTextHandler(log.Writer(), &slog.HandlerOptions{
+			AddSource: log.Flags()&(log.Lshortfile|log.Llongfile) != 0,
+			Level:     enabledLevel(inner),
+		})
+	}
+	h := newHandler(opts)
+	h.writer = io.Discard
+	h.handler = inner
+	if opts.IncludeGroupPath {
+		h.groupPathField = groupPathFieldName(opts)
+	}
+	if opts.TracePlacement == TraceBeforeAttrs {
+		h.root = inner
+		h.traceChain = new(atomic.Pointer[traceChain])
+	}
+	if len(opts.StaticFields) > 0 {
+		return h.WithAttrs(staticAttrs(opts.StaticFields))
+	}
+	return h
+}
+
+// isSlogDefaultHandler reports whether h is the handler of slog's initial default logger, which has
+// an unexported type.
+func isSlogDefaultHandler(h slog.Handler) bool {
+	t := reflect.TypeOf(h)
+	return t != nil && t.String() == "*slog.defaultHandler"
+}
+
+// enabledLevel returns the lowest standard level h is enabled for.
+func enabledLevel(h slog.Handler) slog.Level {
+	for _, level := range []slog.Level{slog.LevelDebug, slog.LevelInfo, slog.LevelWarn} {
+		if h.Enabled(context.Background(), level) {
+			return level
+		}
+	}
+	return slog.LevelError
+}
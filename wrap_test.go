@@ -0,0 +1,87 @@
+package glog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWrapHandler(t *testing.T) {
+	var buf bytes.Buffer
+	inner := slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo})
+	handler := WrapHandler(inner, &Options{
+		TraceExtractor: DefaultTraceExtractor,
+		StaticFields:   map[string]any{"service": "api"},
+		RecordHandler: func(_ context.Context, r *slog.Record) {
+			r.AddAttrs(slog.Bool("enriched", true))
+		},
+	})
+
+	logger := slog.New(handler)
+	ctx := SetSpanID(SetTraceID(context.Background(), "t-1"), "s-1")
+	logger.InfoContext(ctx, "handled", "status", 200)
+	logger.DebugContext(ctx, "dropped by the inner level")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expected 1 line, got %d: %s", len(lines), buf.String())
+	}
+	var m map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &m); err != nil {
+		t.Fatalf("expected the inner JSON handler's output, got %q: %v", lines[0], err)
+	}
+	for key, want := range map[string]any{"trace_id": "t-1", "span_id": "s-1", "service": "api", "enriched": true, "status": float64(200)} {
+		if m[key] != want {
+			t.Errorf("expected %s=%v, got %v in %s", key, want, m[key], lines[0])
+		}
+	}
+}
+
+func TestWrapHandler_WithGroup(t *testing.T) {
+	var buf bytes.Buffer
+	handler := WrapHandler(slog.NewJSONHandler(&buf, nil), &Options{TraceExtractor: DefaultTraceExtractor})
+
+	slog.New(handler).WithGroup("req").InfoContext(SetTraceID(context.Background(), "t-1"), "handled")
+
+	if !strings.Contains(buf.String(), `"req":{"trace_id":"t-1"}`) {
+		t.Errorf("expected trace fields inside the open group, got: %s", buf.String())
+	}
+}
+
+func TestWrapHandler_SlogDefault(t *testing.T) {
+	prev, prevOutput, prevFlags := slog.Default(), log.Writer(), log.Flags()
+	if !isSlogDefaultHandler(prev.Handler()) {
+		t.Fatalf("expected slog's built-in default handler, got %T", prev.Handler())
+	}
+	var buf syncBuffer
+	log.SetOutput(&buf)
+	defer func() {
+		slog.SetDefault(prev)
+		log.SetOutput(prevOutput)
+		log.SetFlags(prevFlags)
+	}()
+
+	// the documented pattern: the wrapped default handler must not write back into itself
+	slog.SetDefault(slog.New(WrapHandler(slog.Default().Handler(), &Options{StaticFields: map[string]any{"service": "api"}})))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		slog.Info("started")
+		log.Print("from log")
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("logging through the wrapped default handler deadlocked")
+	}
+
+	lines := buf.lines()
+	if len(lines) != 2 || !strings.Contains(lines[0], "msg=started service=api") || !strings.Contains(lines[1], `msg="from log" service=api`) {
+		t.Errorf("expected both records enriched on the log package's writer, got %q", lines)
+	}
+}